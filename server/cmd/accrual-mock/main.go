@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/accrualmock"
+)
+
+func main() {
+	var (
+		address         string
+		rules           string
+		latency         time.Duration
+		rateLimit       int
+		rateWindow      time.Duration
+		processingSteps int
+	)
+
+	flag.StringVar(&address, "a", ":8081", "Address and port to run mock accrual system")
+	flag.StringVar(&rules, "rules", "=100", "Reward rules: prefix=accrual or prefix=invalid, comma separated")
+	flag.DurationVar(&latency, "latency", 0, "Delay before each response")
+	flag.IntVar(&rateLimit, "rate-limit", 0, "Requests per window before 429, 0 disables limiting")
+	flag.DurationVar(&rateWindow, "rate-window", time.Minute, "Rate limit window")
+	flag.IntVar(&processingSteps, "processing-steps", 1, "PROCESSING responses before the final status")
+	flag.Parse()
+
+	if addr := os.Getenv("RUN_ADDRESS"); addr != "" && !isFlagSet("a") {
+		address = addr
+	}
+
+	parsed, err := accrualmock.ParseRules(rules)
+	if err != nil {
+		log.Fatalf("Invalid rules: %v", err)
+	}
+
+	server := &http.Server{
+		Addr: address,
+		Handler: accrualmock.New(accrualmock.Config{
+			Latency:         latency,
+			RateLimit:       rateLimit,
+			RateWindow:      rateWindow,
+			ProcessingSteps: processingSteps,
+			Rules:           parsed,
+		}),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		log.Printf("Mock accrual system listening on %s", address)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Shutdown error: %v", err)
+	}
+}
+
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+	"github.com/RoGogDBD/loyalty_service/server/internal/worker"
+)
+
+var errUsage = errors.New("invalid command, run gophermart-admin -h for usage")
+
+// admin выполняет команды оператора поверх сервисов и репозиториев.
+type admin struct {
+	users       repository.UserRepository
+	auth        service.AuthService
+	orders      service.OrderService
+	orderRepo   repository.OrderRepository
+	balances    service.BalanceService
+	withdrawals service.WithdrawalService
+	transfers   service.TransferService
+	rewards     service.RewardService
+	campaigns   service.CampaignService
+	// accrual — nil, если адрес системы начислений не задан.
+	accrual *worker.AccrualWorker
+
+	in  io.Reader
+	out io.Writer
+}
+
+// userExport — данные пользователя в выгрузке.
+type userExport struct {
+	*models.User
+	Balance     *models.Balance             `json:"balance"`
+	Orders      []*models.Order             `json:"orders"`
+	Withdrawals []*models.Withdrawal        `json:"withdrawals"`
+	Adjustments []*models.BalanceAdjustment `json:"adjustments"`
+	Bonuses     []*models.CampaignBonus     `json:"bonuses"`
+	Transfers   []*models.Transfer          `json:"transfers"`
+}
+
+type export struct {
+	ExportedAt time.Time     `json:"exported_at"`
+	Users      []*userExport `json:"users"`
+}
+
+func (a *admin) run(ctx context.Context, args []string) error {
+	switch {
+	case match(args, "user", "create") && (len(args) == 3 || len(args) == 4):
+		password := "-"
+		if len(args) == 4 {
+			password = args[3]
+		}
+		return a.createUser(ctx, args[2], password)
+	case match(args, "user", "list") && len(args) == 2:
+		return a.listUsers(ctx)
+	case match(args, "user", "show") && len(args) == 3:
+		return a.showUser(ctx, args[2])
+	case match(args, "order", "repoll") && len(args) == 3:
+		return a.repollOrder(ctx, args[2])
+	case match(args, "balance", "adjust") && len(args) >= 5:
+		return a.adjustBalance(ctx, args[2], args[3], strings.Join(args[4:], " "))
+	case match(args, "rule", "add") && len(args) == 5:
+		return a.addRule(ctx, args[2], args[3], args[4])
+	case match(args, "rule", "list") && len(args) == 2:
+		return a.listRules(ctx)
+	case match(args, "rule", "delete") && len(args) == 3:
+		return a.deleteRule(ctx, args[2])
+	case match(args, "campaign", "add") && len(args) >= 6 && len(args) <= 8:
+		return a.addCampaign(ctx, args[2], args[3], args[4], args[5], args[6:])
+	case match(args, "campaign", "list") && len(args) == 2:
+		return a.listCampaigns(ctx)
+	case match(args, "campaign", "end") && len(args) == 3:
+		return a.endCampaign(ctx, args[2])
+	case match(args, "bonus", "reverse") && len(args) == 3:
+		return a.reverseBonus(ctx, args[2])
+	case match(args, "export") && len(args) <= 2:
+		var login string
+		if len(args) == 2 {
+			login = args[1]
+		}
+		return a.export(ctx, login)
+	default:
+		return errUsage
+	}
+}
+
+func match(args []string, words ...string) bool {
+	if len(args) < len(words) {
+		return false
+	}
+	for i, w := range words {
+		if args[i] != w {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *admin) createUser(ctx context.Context, login, password string) error {
+	if password == "-" {
+		line, err := bufio.NewReader(a.in).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if login == "" || password == "" {
+		return errors.New("login and password are required")
+	}
+
+	user, err := a.auth.Register(ctx, &models.UserCredentials{Login: login, Password: password})
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	_, err = fmt.Fprintf(a.out, "created user %s (id %d)\n", user.Login, user.ID)
+	return err
+}
+
+func (a *admin) listUsers(ctx context.Context) error {
+	users, err := a.users.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tLOGIN\tCREATED")
+	for _, u := range users {
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\n", u.ID, u.Login, u.CreatedAt.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+func (a *admin) showUser(ctx context.Context, login string) error {
+	data, err := a.collect(ctx, login)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "user %s (id %d), registered %s\n", data.Login, data.ID, data.CreatedAt.Format(time.RFC3339))
+	_, _ = fmt.Fprintf(tw, "balance: current %.2f, withdrawn %.2f\n", data.Balance.Current, data.Balance.Withdrawn)
+
+	_, _ = fmt.Fprintf(tw, "\norders (%d):\n", len(data.Orders))
+	for _, o := range data.Orders {
+		accrual := "-"
+		if o.Accrual != nil {
+			accrual = strconv.FormatFloat(*o.Accrual, 'f', 2, 64)
+		}
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", o.Number, o.Status, accrual, o.UploadedAt.Format(time.RFC3339))
+	}
+
+	_, _ = fmt.Fprintf(tw, "\nwithdrawals (%d):\n", len(data.Withdrawals))
+	for _, w := range data.Withdrawals {
+		_, _ = fmt.Fprintf(tw, "  %s\t%.2f\t%s\n", w.OrderNumber, w.Sum, w.ProcessedAt.Format(time.RFC3339))
+	}
+
+	_, _ = fmt.Fprintf(tw, "\nadjustments (%d):\n", len(data.Adjustments))
+	for _, adj := range data.Adjustments {
+		_, _ = fmt.Fprintf(tw, "  %+.2f\t%s\t%s\n", adj.Amount, adj.Reason, adj.CreatedAt.Format(time.RFC3339))
+	}
+
+	_, _ = fmt.Fprintf(tw, "\ncampaign bonuses (%d):\n", len(data.Bonuses))
+	for _, b := range data.Bonuses {
+		state := "active"
+		if b.ReversedAt != nil {
+			state = "reversed " + b.ReversedAt.Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(tw, "  %d\t%s\t%s\t%.2f\t%s\t%s\n", b.ID, b.Campaign, b.OrderNumber, b.Amount, b.CreatedAt.Format(time.RFC3339), state)
+	}
+
+	_, _ = fmt.Fprintf(tw, "\ntransfers (%d):\n", len(data.Transfers))
+	for _, t := range data.Transfers {
+		peer, sign := t.To, "-"
+		if t.Direction == models.TransferIn {
+			peer, sign = t.From, "+"
+		}
+		_, _ = fmt.Fprintf(tw, "  %d\t%s%.2f\t%s %s\t%s\n", t.ID, sign, t.Sum, t.Direction, peer, t.CreatedAt.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+// repollOrder возвращает заказ в очередь воркера и, если задан адрес системы
+// начислений, сразу опрашивает её.
+func (a *admin) repollOrder(ctx context.Context, number string) error {
+	order, err := a.orders.RequeueOrder(ctx, number)
+	if err != nil {
+		return fmt.Errorf("failed to requeue order: %w", err)
+	}
+
+	if a.accrual == nil {
+		_, err = fmt.Fprintf(a.out, "order %s queued for accrual polling\n", order.Number)
+		return err
+	}
+
+	if err := a.accrual.ProcessOrder(ctx, order); err != nil {
+		return fmt.Errorf("failed to poll accrual system: %w", err)
+	}
+
+	order, err = a.orderRepo.GetByNumber(ctx, number)
+	if err != nil {
+		return err
+	}
+	accrual := "-"
+	if order.Accrual != nil {
+		accrual = strconv.FormatFloat(*order.Accrual, 'f', 2, 64)
+	}
+	_, err = fmt.Fprintf(a.out, "order %s: %s, accrual %s\n", order.Number, order.Status, accrual)
+	return err
+}
+
+func (a *admin) adjustBalance(ctx context.Context, login, amount, reason string) error {
+	user, err := a.lookupUser(ctx, login)
+	if err != nil {
+		return err
+	}
+
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return fmt.Errorf("invalid amount %q", amount)
+	}
+
+	if _, err := a.balances.Adjust(ctx, user.ID, value, reason); err != nil {
+		return fmt.Errorf("failed to adjust balance: %w", err)
+	}
+
+	balance, err := a.balances.GetBalance(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(a.out, "balance of %s: %.2f\n", user.Login, balance.Current)
+	return err
+}
+
+func (a *admin) addRule(ctx context.Context, matchText, reward, rewardType string) error {
+	value, err := strconv.ParseFloat(reward, 64)
+	if err != nil {
+		return fmt.Errorf("invalid reward %q", reward)
+	}
+
+	rule, err := a.rewards.CreateRule(ctx, matchText, value, rewardType)
+	if err != nil {
+		return fmt.Errorf("failed to add rule: %w", err)
+	}
+
+	_, err = fmt.Fprintf(a.out, "added rule %d: %q -> %s%s\n", rule.ID, rule.Match, formatAmount(rule.Reward), rule.RewardType)
+	return err
+}
+
+func (a *admin) listRules(ctx context.Context) error {
+	rules, err := a.rewards.ListRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tMATCH\tREWARD\tTYPE\tCREATED")
+	for _, r := range rules {
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", r.ID, r.Match, formatAmount(r.Reward), r.RewardType, r.CreatedAt.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+func (a *admin) deleteRule(ctx context.Context, id string) error {
+	value, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid rule id %q", id)
+	}
+
+	if err := a.rewards.DeleteRule(ctx, value); err != nil {
+		return fmt.Errorf("failed to delete rule: %w", err)
+	}
+
+	_, err = fmt.Fprintf(a.out, "deleted rule %d\n", value)
+	return err
+}
+
+// addCampaign создаёт кампанию. reward — "x2" (множитель начисления) или
+// "+50" (фиксированный бонус за заказ); options — "min=AMOUNT" и "tier=TIER".
+func (a *admin) addCampaign(ctx context.Context, name, from, to, reward string, options []string) error {
+	campaign := &models.Campaign{Name: name}
+
+	var err error
+	if campaign.StartsAt, err = parseCampaignTime(from, false); err != nil {
+		return err
+	}
+	if campaign.EndsAt, err = parseCampaignTime(to, true); err != nil {
+		return err
+	}
+
+	switch {
+	case strings.HasPrefix(reward, "x"):
+		campaign.Multiplier, err = strconv.ParseFloat(reward[1:], 64)
+	case strings.HasPrefix(reward, "+"):
+		campaign.Bonus, err = strconv.ParseFloat(reward[1:], 64)
+	default:
+		err = errors.New("missing x or +")
+	}
+	if err != nil {
+		return fmt.Errorf("invalid reward %q, want xN or +N", reward)
+	}
+
+	for _, option := range options {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "min":
+			if campaign.MinAccrual, err = strconv.ParseFloat(value, 64); err != nil {
+				return fmt.Errorf("invalid minimum accrual %q", value)
+			}
+		case "tier":
+			campaign.Tier = value
+		default:
+			return fmt.Errorf("unknown campaign option %q", option)
+		}
+	}
+
+	created, err := a.campaigns.CreateCampaign(ctx, campaign)
+	if err != nil {
+		return fmt.Errorf("failed to add campaign: %w", err)
+	}
+
+	_, err = fmt.Fprintf(a.out, "added campaign %d: %s, %s, %s - %s\n", created.ID, created.Name,
+		formatCampaignReward(created), created.StartsAt.Format(time.RFC3339), created.EndsAt.Format(time.RFC3339))
+	return err
+}
+
+// parseCampaignTime принимает время в RFC 3339 или дату: дата начала — это
+// начало суток по UTC, дата окончания включается в кампанию целиком.
+func parseCampaignTime(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, want YYYY-MM-DD or RFC 3339", value)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func formatCampaignReward(c *models.Campaign) string {
+	if c.Multiplier > 0 {
+		return "x" + formatAmount(c.Multiplier)
+	}
+	return "+" + formatAmount(c.Bonus)
+}
+
+func (a *admin) listCampaigns(ctx context.Context) error {
+	campaigns, err := a.campaigns.ListCampaigns(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tNAME\tREWARD\tMIN\tTIER\tSTARTS\tENDS")
+	for _, c := range campaigns {
+		tier := c.Tier
+		if tier == "" {
+			tier = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", c.ID, c.Name, formatCampaignReward(c), formatAmount(c.MinAccrual),
+			tier, c.StartsAt.Format(time.RFC3339), c.EndsAt.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+func (a *admin) endCampaign(ctx context.Context, id string) error {
+	value, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid campaign id %q", id)
+	}
+
+	if err := a.campaigns.EndCampaign(ctx, value); err != nil {
+		return fmt.Errorf("failed to end campaign: %w", err)
+	}
+
+	_, err = fmt.Fprintf(a.out, "ended campaign %d\n", value)
+	return err
+}
+
+func (a *admin) reverseBonus(ctx context.Context, id string) error {
+	value, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid bonus id %q", id)
+	}
+
+	bonus, err := a.campaigns.ReverseBonus(ctx, value)
+	if err != nil {
+		return fmt.Errorf("failed to reverse bonus: %w", err)
+	}
+
+	_, err = fmt.Fprintf(a.out, "reversed bonus %d: %.2f for order %s (%s)\n", bonus.ID, bonus.Amount, bonus.OrderNumber, bonus.Campaign)
+	return err
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func (a *admin) export(ctx context.Context, login string) error {
+	doc := export{ExportedAt: time.Now().UTC(), Users: []*userExport{}}
+
+	if login != "" {
+		data, err := a.collect(ctx, login)
+		if err != nil {
+			return err
+		}
+		doc.Users = append(doc.Users, data)
+	} else {
+		users, err := a.users.List(ctx)
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			data, err := a.collectUser(ctx, u)
+			if err != nil {
+				return err
+			}
+			doc.Users = append(doc.Users, data)
+		}
+	}
+
+	enc := json.NewEncoder(a.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func (a *admin) lookupUser(ctx context.Context, login string) (*models.User, error) {
+	user, err := a.users.GetByLogin(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %q not found", login)
+	}
+	return user, nil
+}
+
+func (a *admin) collect(ctx context.Context, login string) (*userExport, error) {
+	user, err := a.lookupUser(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	return a.collectUser(ctx, user)
+}
+
+func (a *admin) collectUser(ctx context.Context, user *models.User) (*userExport, error) {
+	data := &userExport{User: user}
+
+	var err error
+	if data.Balance, err = a.balances.GetBalance(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if data.Orders, err = a.orders.GetUserOrders(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if data.Withdrawals, err = a.withdrawals.GetWithdrawals(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if data.Adjustments, err = a.balances.GetAdjustments(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if data.Bonuses, err = a.campaigns.GetBonuses(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if data.Transfers, err = a.transfers.GetTransfers(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	// В выгрузке пустые списки выводятся как [], а не null.
+	data.Orders = nonNil(data.Orders)
+	data.Withdrawals = nonNil(data.Withdrawals)
+	data.Adjustments = nonNil(data.Adjustments)
+	data.Bonuses = nonNil(data.Bonuses)
+	data.Transfers = nonNil(data.Transfers)
+	return data, nil
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/accrualmock"
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+	"github.com/RoGogDBD/loyalty_service/server/internal/worker"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestAdmin(t *testing.T, accrual *accrualmock.Server) (*admin, *bytes.Buffer) {
+	t.Helper()
+
+	store := repository.NewMemoryStore()
+	users := repository.NewMemoryUserRepository(store)
+	orderRepo := repository.NewMemoryOrderRepository(store)
+	balanceRepo := repository.NewMemoryBalanceRepository(store)
+
+	out := &bytes.Buffer{}
+	a := &admin{
+		users:       users,
+		auth:        service.NewAuthService(users, models.TiersConfig{}),
+		orders:      service.NewOrderService(orderRepo),
+		orderRepo:   orderRepo,
+		balances:    service.NewBalanceService(balanceRepo, models.PointsConfig{}),
+		withdrawals: service.NewWithdrawalService(models.WithdrawalsConfig{}, repository.NewMemoryWithdrawalRepository(store), nil),
+		transfers:   service.NewTransferService(models.TransfersConfig{}, repository.NewMemoryTransferRepository(store), users),
+		rewards:     service.NewRewardService(repository.NewMemoryRewardRepository(store)),
+		campaigns:   service.NewCampaignService(repository.NewMemoryCampaignRepository(store), users, models.PointsConfig{}),
+		in:          strings.NewReader("secret\n"),
+		out:         out,
+	}
+
+	if accrual != nil {
+		srv := httptest.NewServer(accrual)
+		t.Cleanup(srv.Close)
+
+		logger := logrus.New()
+		logger.SetOutput(io.Discard)
+		a.accrual = worker.NewAccrualWorker(models.AccrualConfig{RequestTimeout: time.Second}, worker.NewHTTPAccrualClient(srv.URL), a.orders, a.balances, nil, nil, nil, logger)
+	}
+	return a, out
+}
+
+func runAdmin(t *testing.T, a *admin, args ...string) {
+	t.Helper()
+	if err := a.run(context.Background(), args); err != nil {
+		t.Fatalf("%s: %v", strings.Join(args, " "), err)
+	}
+}
+
+func TestAdmin_UserAndBalance(t *testing.T) {
+	a, out := newTestAdmin(t, nil)
+	ctx := context.Background()
+
+	runAdmin(t, a, "user", "create", "alice")
+	if _, err := a.auth.Login(ctx, &models.UserCredentials{Login: "alice", Password: "secret"}); err != nil {
+		t.Fatalf("password from stdin not applied: %v", err)
+	}
+	if err := a.run(ctx, []string{"user", "create", "alice", "other"}); err == nil {
+		t.Error("duplicate user was created")
+	}
+
+	runAdmin(t, a, "balance", "adjust", "alice", "25.5", "support", "ticket", "#12")
+	if err := a.run(ctx, []string{"balance", "adjust", "alice", "-100", "chargeback"}); err == nil {
+		t.Error("adjustment below zero was accepted")
+	}
+	if err := a.run(ctx, []string{"balance", "adjust", "bob", "10", "typo"}); err == nil {
+		t.Error("adjustment for unknown user was accepted")
+	}
+
+	runAdmin(t, a, "user", "create", "bob", "secret")
+	alice, _ := a.users.GetByLogin(ctx, "alice")
+	if _, err := a.transfers.Transfer(ctx, alice.ID, &models.TransferRequest{To: "bob", Sum: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	runAdmin(t, a, "user", "show", "alice")
+	for _, want := range []string{"user alice", "current 20.50", "+25.50", "support ticket #12", "transfers (1)", "-5.00", "out bob"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("user show output misses %q:\n%s", want, out.String())
+		}
+	}
+
+	if err := a.run(ctx, []string{"user"}); err != errUsage {
+		t.Errorf("incomplete command: error = %v, want usage", err)
+	}
+}
+
+func TestAdmin_RepollOrder(t *testing.T) {
+	const number = "12345678903"
+
+	mock := accrualmock.New(accrualmock.Config{})
+	mock.Script(number, accrualmock.Processed(300))
+	a, out := newTestAdmin(t, mock)
+	ctx := context.Background()
+
+	runAdmin(t, a, "user", "create", "alice", "secret")
+	user, _ := a.users.GetByLogin(ctx, "alice")
+	if _, err := a.orders.UploadOrder(ctx, number, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	runAdmin(t, a, "order", "repoll", number)
+	if got := out.String(); got != "order 12345678903: PROCESSED, accrual 300.00\n" {
+		t.Errorf("output = %q", got)
+	}
+	if balance, _ := a.balances.GetBalance(ctx, user.ID); balance.Current != 300 {
+		t.Errorf("balance = %v, want 300", balance.Current)
+	}
+
+	// Повторный опрос обработанного заказа зачислил бы баллы дважды.
+	if err := a.run(ctx, []string{"order", "repoll", number}); err == nil {
+		t.Error("processed order was polled again")
+	}
+}
+
+func TestAdmin_Export(t *testing.T) {
+	a, out := newTestAdmin(t, nil)
+
+	runAdmin(t, a, "user", "create", "alice", "secret")
+	runAdmin(t, a, "user", "create", "bob", "secret")
+	runAdmin(t, a, "balance", "adjust", "bob", "10", "welcome")
+
+	out.Reset()
+	runAdmin(t, a, "export")
+
+	var doc struct {
+		Users []struct {
+			Login       string          `json:"login"`
+			Balance     models.Balance  `json:"balance"`
+			Orders      json.RawMessage `json:"orders"`
+			Adjustments []struct {
+				Amount float64 `json:"amount"`
+				Reason string  `json:"reason"`
+			} `json:"adjustments"`
+		} `json:"users"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("decode export: %v\n%s", err, out.String())
+	}
+
+	if len(doc.Users) != 2 || doc.Users[0].Login != "alice" || doc.Users[1].Login != "bob" {
+		t.Fatalf("users = %+v", doc.Users)
+	}
+	if string(doc.Users[0].Orders) != "[]" {
+		t.Errorf("empty orders exported as %s, want []", doc.Users[0].Orders)
+	}
+	if doc.Users[1].Balance.Current != 10 || len(doc.Users[1].Adjustments) != 1 || doc.Users[1].Adjustments[0].Reason != "welcome" {
+		t.Errorf("bob = %+v", doc.Users[1])
+	}
+	if strings.Contains(out.String(), "password") {
+		t.Error("export contains password hashes")
+	}
+
+	out.Reset()
+	runAdmin(t, a, "export", "bob")
+	if strings.Contains(out.String(), "alice") {
+		t.Errorf("single user export contains other users:\n%s", out.String())
+	}
+}
+
+func TestAdmin_Rules(t *testing.T) {
+	a, out := newTestAdmin(t, nil)
+	ctx := context.Background()
+
+	runAdmin(t, a, "rule", "add", "Bork", "10", "%")
+	if got := out.String(); got != "added rule 1: \"Bork\" -> 10%\n" {
+		t.Errorf("output = %q", got)
+	}
+	runAdmin(t, a, "rule", "add", "LG", "12.5", "pt")
+	if err := a.run(ctx, []string{"rule", "add", "Bork", "5", "pt"}); err == nil {
+		t.Error("duplicate rule was added")
+	}
+	if err := a.run(ctx, []string{"rule", "add", "Tefal", "ten", "%"}); err == nil {
+		t.Error("non-numeric reward was accepted")
+	}
+
+	runAdmin(t, a, "rule", "delete", "1")
+	if err := a.run(ctx, []string{"rule", "delete", "1"}); err == nil {
+		t.Error("deleted rule was deleted again")
+	}
+
+	out.Reset()
+	runAdmin(t, a, "rule", "list")
+	if got := out.String(); strings.Contains(got, "Bork") || !strings.Contains(got, "LG") || !strings.Contains(got, "12.5") {
+		t.Errorf("rule list output:\n%s", got)
+	}
+}
+
+func TestAdmin_Campaigns(t *testing.T) {
+	const number = "12345678903"
+
+	mock := accrualmock.New(accrualmock.Config{})
+	mock.Script(number, accrualmock.Processed(100))
+	a, out := newTestAdmin(t, mock)
+	ctx := context.Background()
+
+	from := time.Now().UTC().Format(time.DateOnly)
+	runAdmin(t, a, "campaign", "add", "weekend", from, from, "x2", "min=50")
+	if got := out.String(); !strings.HasPrefix(got, "added campaign 1: weekend, x2, ") {
+		t.Errorf("output = %q", got)
+	}
+	for _, args := range [][]string{
+		{"campaign", "add", "typo", from, from, "2"},
+		{"campaign", "add", "typo", from, "tomorrow", "+10"},
+		{"campaign", "add", "typo", from, from, "+10", "level=gold"},
+	} {
+		if err := a.run(ctx, args); err == nil {
+			t.Errorf("%s was accepted", strings.Join(args, " "))
+		}
+	}
+
+	runAdmin(t, a, "user", "create", "alice", "secret")
+	user, _ := a.users.GetByLogin(ctx, "alice")
+	if _, err := a.orders.UploadOrder(ctx, number, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	a.accrual = worker.NewAccrualWorker(models.AccrualConfig{RequestTimeout: time.Second}, nil, a.orders, a.balances, nil, a.campaigns, nil, logrus.New())
+	if err := a.accrual.ApplyAccrual(ctx, &models.AccrualResult{Order: number, Status: models.AccrualStatusProcessed, Accrual: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if balance, _ := a.balances.GetBalance(ctx, user.ID); balance.Current != 200 {
+		t.Errorf("balance = %v, want 200 with the double points bonus", balance.Current)
+	}
+
+	out.Reset()
+	runAdmin(t, a, "user", "show", "alice")
+	if !strings.Contains(out.String(), "campaign bonuses (1)") || !strings.Contains(out.String(), "weekend") {
+		t.Errorf("user show output misses the bonus:\n%s", out.String())
+	}
+
+	out.Reset()
+	runAdmin(t, a, "bonus", "reverse", "1")
+	if got := out.String(); got != "reversed bonus 1: 100.00 for order 12345678903 (weekend)\n" {
+		t.Errorf("output = %q", got)
+	}
+	if err := a.run(ctx, []string{"bonus", "reverse", "1"}); err == nil {
+		t.Error("bonus was reversed twice")
+	}
+	if balance, _ := a.balances.GetBalance(ctx, user.ID); balance.Current != 100 {
+		t.Errorf("balance after reversal = %v, want 100", balance.Current)
+	}
+
+	runAdmin(t, a, "campaign", "end", "1")
+	out.Reset()
+	runAdmin(t, a, "campaign", "list")
+	if got := out.String(); !strings.Contains(got, "weekend") || !strings.Contains(got, "x2") {
+		t.Errorf("campaign list output:\n%s", got)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/app"
+	"github.com/RoGogDBD/loyalty_service/server/internal/config"
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+	"github.com/RoGogDBD/loyalty_service/server/internal/worker"
+	"github.com/joho/godotenv"
+)
+
+const usage = `Usage: gophermart-admin [-d DATABASE_URI] [-r ACCRUAL_SYSTEM_ADDRESS] <command>
+
+Commands:
+  user create LOGIN [PASSWORD]        create a user; without PASSWORD or with "-" it is read from stdin
+  user list                           list users
+  user show LOGIN                     show balance, orders, withdrawals and adjustments
+  order repoll NUMBER                 return an unprocessed order to NEW; with -r poll the accrual system now
+  balance adjust LOGIN AMOUNT REASON  credit (positive) or debit (negative) the balance
+  rule add MATCH REWARD %|pt          add a reward rule for goods whose description contains MATCH
+  rule list                           list reward rules of the built-in accrual engine
+  rule delete ID                      delete a reward rule
+  campaign add NAME FROM TO xN|+N [min=AMOUNT] [tier=TIER]
+                                      add a campaign multiplying accruals by N or adding a fixed bonus N
+                                      to orders processed from FROM to TO (dates are whole UTC days)
+  campaign list                       list campaigns
+  campaign end ID                     end a campaign now
+  bonus reverse ID                    cancel a campaign bonus and debit it from the balance
+  export [LOGIN]                      write all data or one user's data as JSON to stdout
+
+Flags:
+`
+
+func main() {
+	_ = godotenv.Load()
+
+	fs := flag.NewFlagSet("gophermart-admin", flag.ExitOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	cfg, err := config.Load(fs, os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	args := fs.Args()
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	// stdout занят результатами команд, журнал пишется в stderr.
+	logger := config.NewLogger(cfg.Logger().Level, cfg.Env)
+	logger.SetOutput(os.Stderr)
+
+	if cfg.Database().Storage != models.StoragePostgres {
+		logger.Fatalf("gophermart-admin requires %s storage", models.StoragePostgres)
+	}
+
+	db, err := app.OpenDatabase(cfg, logger)
+	if err != nil {
+		logger.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if _, err := db.CheckMigrations(); err != nil {
+		logger.Fatalf("Database schema is not supported: %v", err)
+	}
+
+	users := repository.NewUserRepository(db)
+	var tiers service.TierService
+	if len(cfg.Tiers().Levels) > 0 {
+		tiers = service.NewTierService(cfg.Tiers(), users, repository.NewOrderRepository(db), repository.NewWithdrawalRepository(db))
+	}
+	orders := service.NewOrderService(repository.NewOrderRepository(db))
+	balances := service.NewBalanceService(repository.NewBalanceRepository(db), cfg.Points())
+	withdrawals := service.NewWithdrawalService(cfg.Withdrawals(), repository.NewWithdrawalRepository(db), tiers)
+	transfers := service.NewTransferService(cfg.Transfers(), repository.NewTransferRepository(db), users)
+	rewards := service.NewRewardService(repository.NewRewardRepository(db))
+	campaigns := service.NewCampaignService(repository.NewCampaignRepository(db), users, cfg.Points())
+	referrals := service.NewReferralService(cfg.Referral(), repository.NewReferralRepository(db), users, cfg.Points())
+
+	a := &admin{
+		users:       users,
+		auth:        service.NewAuthService(users, cfg.Tiers()),
+		orders:      orders,
+		orderRepo:   repository.NewOrderRepository(db),
+		balances:    balances,
+		withdrawals: withdrawals,
+		transfers:   transfers,
+		rewards:     rewards,
+		campaigns:   campaigns,
+		in:          os.Stdin,
+		out:         os.Stdout,
+	}
+	if client := worker.NewAccrualClient(cfg.Accrual(), rewards); client != nil {
+		a.accrual = worker.NewAccrualWorker(cfg.Accrual(), client, orders, balances, tiers, campaigns, referrals, logger)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := a.run(ctx, args); err != nil {
+		stop()
+		_ = db.Close()
+		logger.Fatalf("%v", err)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
@@ -18,14 +19,29 @@ func main() {
 		log.Print("No .env file found")
 	}
 
-	cfg := config.NewConfig()
-	cfg.ParseFlags()
-	logger := config.NewLogger(cfg.Logger().Level, cfg.Env)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
 
-	if cfg.Database().Password == "" && cfg.Database().URL == "" {
-		logger.Fatal("DB credentials not set: задайте DB_PASSWORD или DATABASE_URI")
+	cfg, printConfig, err := loadConfig(flag.CommandLine)
+	if printConfig {
+		if perr := cfg.Print(os.Stdout); perr != nil {
+			log.Fatalf("Failed to print configuration: %v", perr)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
 	}
 
+	logger := config.NewLogger(cfg.Logger().Level, cfg.Env)
+
 	config.LogConfig(cfg, logger)
 
 	application := app.New(cfg, logger)
@@ -37,13 +53,29 @@ func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+
 	go func() {
 		if err := application.Run(); err != nil {
 			logger.Fatalf("Server failed: %v", err)
 		}
 	}()
 
-	<-stop
+	for waiting := true; waiting; {
+		select {
+		case <-reload:
+			logger.Info("Received reload signal")
+			next, _, err := loadConfig(flag.NewFlagSet(os.Args[0], flag.ContinueOnError))
+			if err != nil {
+				logger.WithError(err).Error("Configuration reload rejected, keeping current configuration")
+				continue
+			}
+			application.Reload(next)
+		case <-stop:
+			waiting = false
+		}
+	}
 	logger.Info("Received shutdown signal")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
@@ -55,3 +87,12 @@ func main() {
 
 	logger.Info("Server stopped gracefully")
 }
+
+// loadConfig читает конфигурацию из файла, окружения и аргументов командной
+// строки, регистрируя флаги в fs. Повторный вызов с новым fs перечитывает
+// файл конфигурации при перезагрузке.
+func loadConfig(fs *flag.FlagSet) (*config.Config, bool, error) {
+	printConfig := fs.Bool("print-config", false, "Print effective configuration with secrets masked and exit")
+	cfg, err := config.Load(fs, os.Args[1:])
+	return cfg, *printConfig, err
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/app"
+	"github.com/RoGogDBD/loyalty_service/server/internal/config"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+)
+
+const migrateUsage = `Usage: gophermart migrate [-d DATABASE_URI] <command>
+
+Commands:
+  up          apply all pending migrations
+  down [N]    roll back N migrations (default 1, "all" rolls back everything)
+  status      print the current schema version
+  force V     set schema version to V without running migrations and clear the dirty flag
+
+Flags:
+`
+
+// runMigrate выполняет подкоманду migrate.
+func runMigrate(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(fs.Output(), migrateUsage)
+		fs.PrintDefaults()
+	}
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return err
+	}
+	args = fs.Args()
+	logger := config.NewLogger(cfg.Logger().Level, cfg.Env)
+
+	if len(args) == 0 {
+		fs.Usage()
+		return errors.New("migrate command is required")
+	}
+
+	db, err := app.OpenDatabase(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	m, err := db.NewMigrator(context.Background())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = m.Close() }()
+
+	switch cmd, rest := args[0], args[1:]; cmd {
+	case "up":
+		if _, err := m.Check(); err != nil {
+			return err
+		}
+		if err := m.Up(); err != nil {
+			return err
+		}
+	case "down":
+		steps, err := parseSteps(rest)
+		if err != nil {
+			return err
+		}
+		if err := m.Down(steps); err != nil {
+			return err
+		}
+	case "status":
+	case "force":
+		if len(rest) != 1 {
+			return errors.New("force requires a version")
+		}
+		version, err := strconv.Atoi(rest[0])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", rest[0])
+		}
+		if err := m.Force(version); err != nil {
+			return err
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown migrate command %q", cmd)
+	}
+
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+	latest, err := repository.LatestMigrationVersion()
+	if err != nil {
+		return err
+	}
+	printStatus(stdout, status, latest)
+	return nil
+}
+
+// parseSteps разбирает аргумент down: число шагов или all (0).
+func parseSteps(args []string) (int, error) {
+	if len(args) == 0 {
+		return 1, nil
+	}
+	if args[0] == "all" {
+		return 0, nil
+	}
+
+	steps, err := strconv.Atoi(args[0])
+	if err != nil || steps <= 0 {
+		return 0, fmt.Errorf("invalid number of steps %q", args[0])
+	}
+	return steps, nil
+}
+
+func printStatus(w io.Writer, status repository.MigrationStatus, latest uint) {
+	state := "clean"
+	if status.Dirty {
+		state = "dirty"
+	}
+	_, _ = fmt.Fprintf(w, "version: %d (%s), latest: %d\n", status.Version, state, latest)
+}
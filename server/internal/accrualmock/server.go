@@ -0,0 +1,261 @@
+// Package accrualmock реализует имитацию системы расчёта начислений для
+// локальной разработки и тестов. Server реализует http.Handler, поэтому его
+// можно поднять через httptest.NewServer.
+package accrualmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// Статусы расчёта в протоколе системы начислений.
+const (
+	StatusRegistered = "REGISTERED"
+	StatusProcessing = "PROCESSING"
+	StatusInvalid    = "INVALID"
+	StatusProcessed  = "PROCESSED"
+)
+
+type (
+	// Step — ответ на один запрос информации о заказе. NoContent означает,
+	// что заказ ещё не зарегистрирован в системе расчёта (204). Ненулевой
+	// HTTPStatus отдаётся как есть, без тела, например для имитации 500.
+	Step struct {
+		Status     string
+		Accrual    *float64
+		NoContent  bool
+		HTTPStatus int
+	}
+
+	// RewardRule задаёт начисление для заказов, номер которых начинается с
+	// Prefix. Пустой префикс подходит под любой номер. Invalid означает, что
+	// заказ будет отклонён.
+	RewardRule struct {
+		Prefix  string
+		Accrual float64
+		Invalid bool
+	}
+
+	Config struct {
+		// Latency — задержка перед каждым ответом.
+		Latency time.Duration
+		// RateLimit — число запросов за RateWindow, после которого сервер
+		// отвечает 429. Ноль отключает ограничение.
+		RateLimit  int
+		RateWindow time.Duration
+		RetryAfter time.Duration
+		// ProcessingSteps — сколько раз заказ по правилу отдаётся в статусе
+		// PROCESSING перед окончательным ответом.
+		ProcessingSteps int
+		// Rules применяются по первому совпадению, если для заказа нет
+		// сценария. Заказы без сценария и подходящего правила получают 204.
+		Rules []RewardRule
+	}
+
+	Server struct {
+		cfg    Config
+		router chi.Router
+
+		mu          sync.Mutex
+		scripts     map[string][]Step
+		requests    map[string]int
+		windowStart time.Time
+		windowCount int
+	}
+
+	orderResponse struct {
+		Order   string   `json:"order"`
+		Status  string   `json:"status"`
+		Accrual *float64 `json:"accrual,omitempty"`
+	}
+)
+
+func New(cfg Config) *Server {
+	if cfg.RateWindow <= 0 {
+		cfg.RateWindow = time.Minute
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = cfg.RateWindow
+	}
+
+	s := &Server{
+		cfg:      cfg,
+		scripts:  make(map[string][]Step),
+		requests: make(map[string]int),
+	}
+
+	r := chi.NewRouter()
+	r.Get("/api/orders/{number}", s.getOrder)
+	s.router = r
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+// Script задаёт последовательность ответов для заказа. Каждый запрос
+// переходит к следующему шагу, последний шаг повторяется. Сценарий без шагов
+// означает незарегистрированный заказ: сервер отвечает 204.
+func (s *Server) Script(number string, steps ...Step) {
+	if len(steps) == 0 {
+		steps = []Step{{NoContent: true}}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[number] = steps
+}
+
+// Requests возвращает число запросов информации о заказе, не считая
+// отклонённых ограничением частоты.
+func (s *Server) Requests(number string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[number]
+}
+
+// Reset удаляет сценарии, счётчики запросов и окно ограничения частоты.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts = make(map[string][]Step)
+	s.requests = make(map[string]int)
+	s.windowStart = time.Time{}
+	s.windowCount = 0
+}
+
+func (s *Server) getOrder(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Latency > 0 {
+		select {
+		case <-time.After(s.cfg.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	number := chi.URLParam(r, "number")
+
+	step, limited := s.next(number, time.Now())
+	if limited {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Retry-After", strconv.Itoa(int(s.cfg.RetryAfter.Seconds())))
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = fmt.Fprintf(w, "No more than %d requests per %s allowed", s.cfg.RateLimit, s.cfg.RateWindow)
+		return
+	}
+
+	if step.NoContent {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if step.HTTPStatus != 0 {
+		http.Error(w, http.StatusText(step.HTTPStatus), step.HTTPStatus)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(orderResponse{
+		Order:   number,
+		Status:  step.Status,
+		Accrual: step.Accrual,
+	})
+}
+
+func (s *Server) next(number string, now time.Time) (Step, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cfg.RateLimit > 0 {
+		if now.Sub(s.windowStart) >= s.cfg.RateWindow {
+			s.windowStart = now
+			s.windowCount = 0
+		}
+		if s.windowCount >= s.cfg.RateLimit {
+			return Step{}, true
+		}
+		s.windowCount++
+	}
+
+	steps, ok := s.scripts[number]
+	if !ok {
+		steps = s.stepsByRule(number)
+		s.scripts[number] = steps
+	}
+
+	n := s.requests[number]
+	s.requests[number] = n + 1
+
+	if n >= len(steps) {
+		n = len(steps) - 1
+	}
+	return steps[n], false
+}
+
+func (s *Server) stepsByRule(number string) []Step {
+	for _, rule := range s.cfg.Rules {
+		if !strings.HasPrefix(number, rule.Prefix) {
+			continue
+		}
+
+		steps := []Step{{Status: StatusRegistered}}
+		for i := 0; i < s.cfg.ProcessingSteps; i++ {
+			steps = append(steps, Step{Status: StatusProcessing})
+		}
+		if rule.Invalid {
+			return append(steps, Step{Status: StatusInvalid})
+		}
+		if rule.Accrual == 0 {
+			// Без начисления поле accrual в ответе отсутствует.
+			return append(steps, Step{Status: StatusProcessed})
+		}
+		return append(steps, Processed(rule.Accrual))
+	}
+
+	return []Step{{NoContent: true}}
+}
+
+// Processed возвращает окончательный шаг с начислением.
+func Processed(accrual float64) Step {
+	return Step{Status: StatusProcessed, Accrual: &accrual}
+}
+
+// ParseRules разбирает правила вида "prefix=accrual,prefix=invalid". Пустой
+// префикс ("=10") подходит под любой номер.
+func ParseRules(spec string) ([]RewardRule, error) {
+	var rules []RewardRule
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		prefix, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("rule %q: expected prefix=accrual", part)
+		}
+
+		rule := RewardRule{Prefix: strings.TrimSpace(prefix)}
+		value = strings.TrimSpace(value)
+		if strings.EqualFold(value, "invalid") {
+			rule.Invalid = true
+		} else {
+			accrual, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: invalid accrual: %w", part, err)
+			}
+			rule.Accrual = accrual
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
@@ -0,0 +1,130 @@
+package accrualmock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func get(t *testing.T, srv *httptest.Server, number string) (*http.Response, map[string]any) {
+	t.Helper()
+
+	resp, err := http.Get(srv.URL + "/api/orders/" + number)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]any
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return resp, body
+}
+
+func TestServer_Rules(t *testing.T) {
+	srv := httptest.NewServer(New(Config{
+		ProcessingSteps: 1,
+		Rules:           []RewardRule{{Prefix: "9", Invalid: true}, {Prefix: "1", Accrual: 25}},
+	}))
+	defer srv.Close()
+
+	var statuses []any
+	for i := 0; i < 4; i++ {
+		_, body := get(t, srv, "12345678903")
+		statuses = append(statuses, body["status"])
+		if i == 3 && body["accrual"] != 25.0 {
+			t.Errorf("accrual = %v, want 25", body["accrual"])
+		}
+	}
+	want := []any{StatusRegistered, StatusProcessing, StatusProcessed, StatusProcessed}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+
+	var body map[string]any
+	for i := 0; i < 3; i++ {
+		_, body = get(t, srv, "9278923470")
+	}
+	if body["status"] != StatusInvalid {
+		t.Errorf("status = %v, want INVALID", body["status"])
+	}
+
+	if resp, _ := get(t, srv, "5555"); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("unknown order status = %d, want 204", resp.StatusCode)
+	}
+}
+
+func TestServer_Script(t *testing.T) {
+	mock := New(Config{})
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	mock.Script("12345678903", Step{Status: StatusProcessing}, Step{HTTPStatus: http.StatusInternalServerError}, Processed(500))
+	mock.Script("9278923470")
+
+	var got []any
+	for i := 0; i < 4; i++ {
+		resp, body := get(t, srv, "12345678903")
+		if resp.StatusCode != http.StatusOK {
+			got = append(got, resp.StatusCode)
+			continue
+		}
+		got = append(got, body["status"])
+	}
+	want := []any{StatusProcessing, http.StatusInternalServerError, StatusProcessed, StatusProcessed}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("responses = %v, want %v", got, want)
+	}
+	if n := mock.Requests("12345678903"); n != 4 {
+		t.Errorf("Requests = %d, want 4", n)
+	}
+
+	// Пустой сценарий не должен ронять сервер.
+	for i := 0; i < 2; i++ {
+		if resp, _ := get(t, srv, "9278923470"); resp.StatusCode != http.StatusNoContent {
+			t.Errorf("empty script: status = %d, want 204", resp.StatusCode)
+		}
+	}
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	srv := httptest.NewServer(New(Config{RateLimit: 2, RateWindow: time.Hour, RetryAfter: 60 * time.Second}))
+	defer srv.Close()
+
+	for i := 0; i < 2; i++ {
+		if resp, _ := get(t, srv, "1"); resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("request %d status = %d, want 204", i, resp.StatusCode)
+		}
+	}
+
+	resp, _ := get(t, srv, "1")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want 60", got)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("9=invalid, 12=50.5,=10")
+	if err != nil {
+		t.Fatalf("ParseRules: %v", err)
+	}
+	want := []RewardRule{{Prefix: "9", Invalid: true}, {Prefix: "12", Accrual: 50.5}, {Accrual: 10}}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules = %+v, want %+v", rules, want)
+	}
+
+	for _, spec := range []string{"9", "1=abc"} {
+		if _, err := ParseRules(spec); err == nil {
+			t.Errorf("ParseRules(%q) succeeded, want error", spec)
+		}
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 
 	"github.com/RoGogDBD/loyalty_service/server/internal/config"
 	"github.com/RoGogDBD/loyalty_service/server/internal/handler"
 	"github.com/RoGogDBD/loyalty_service/server/internal/middleware"
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
 	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
 	"github.com/RoGogDBD/loyalty_service/server/internal/service"
 	"github.com/RoGogDBD/loyalty_service/server/internal/worker"
@@ -19,9 +21,26 @@ type App struct {
 	cfg           *config.Config
 	logger        *logrus.Logger
 	db            *repository.DB
+	repos         repositories
 	server        *http.Server
+	jwtService    service.JWTService
+	withdrawals   service.WithdrawalService
+	transfers     service.TransferService
+	accrualWorker *worker.AccrualWorker
 	workerContext context.Context
 	workerCancel  context.CancelFunc
+	workers       sync.WaitGroup
+}
+
+type repositories struct {
+	users       repository.UserRepository
+	orders      repository.OrderRepository
+	balances    repository.BalanceRepository
+	withdrawals repository.WithdrawalRepository
+	rewards     repository.RewardRepository
+	campaigns   repository.CampaignRepository
+	referrals   repository.ReferralRepository
+	transfers   repository.TransferRepository
 }
 
 func New(cfg *config.Config, logger *logrus.Logger) *App {
@@ -32,8 +51,8 @@ func New(cfg *config.Config, logger *logrus.Logger) *App {
 }
 
 func (a *App) Initialize() error {
-	if err := a.initDatabase(); err != nil {
-		return fmt.Errorf("failed to initialize database: %w", err)
+	if err := a.initRepositories(); err != nil {
+		return fmt.Errorf("failed to initialize repositories: %w", err)
 	}
 
 	if err := a.initServer(); err != nil {
@@ -42,79 +61,200 @@ func (a *App) Initialize() error {
 
 	return nil
 }
-func (a *App) initDatabase() error {
-	dbCfg := &repository.DatabaseConfig{
-		Host:            a.cfg.Database().Host,
-		Port:            a.cfg.Database().Port,
-		User:            a.cfg.Database().User,
-		Password:        a.cfg.Database().Password,
-		Database:        a.cfg.Database().Name,
-		SSLMode:         "disable",
-		MaxOpenConns:    a.cfg.Database().MaxOpenConns,
-		MaxIdleConns:    a.cfg.Database().MaxIdleConns,
-		ConnMaxLifetime: a.cfg.Database().ConnMaxLifetime,
-		ConnMaxIdleTime: a.cfg.Database().ConnMaxIdleTime,
+func (a *App) initRepositories() error {
+	switch a.cfg.Database().Storage {
+	case models.StorageMemory:
+		store := repository.NewMemoryStore()
+		a.repos = repositories{
+			users:       repository.NewMemoryUserRepository(store),
+			orders:      repository.NewMemoryOrderRepository(store),
+			balances:    repository.NewMemoryBalanceRepository(store),
+			withdrawals: repository.NewMemoryWithdrawalRepository(store),
+			rewards:     repository.NewMemoryRewardRepository(store),
+			campaigns:   repository.NewMemoryCampaignRepository(store),
+			referrals:   repository.NewMemoryReferralRepository(store),
+			transfers:   repository.NewMemoryTransferRepository(store),
+		}
+		a.logger.Warn("Using in-memory storage, data will be lost on restart")
+		return nil
+	case models.StoragePostgres:
+		if err := a.initDatabase(); err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		a.repos = repositories{
+			users:       repository.NewUserRepository(a.db),
+			orders:      repository.NewOrderRepository(a.db),
+			balances:    repository.NewBalanceRepository(a.db),
+			withdrawals: repository.NewWithdrawalRepository(a.db),
+			rewards:     repository.NewRewardRepository(a.db),
+			campaigns:   repository.NewCampaignRepository(a.db),
+			referrals:   repository.NewReferralRepository(a.db),
+			transfers:   repository.NewTransferRepository(a.db),
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown storage %q", a.cfg.Database().Storage)
 	}
+}
 
-	db, err := repository.NewDB(dbCfg, a.logger)
+func (a *App) initDatabase() error {
+	db, err := OpenDatabase(a.cfg, a.logger)
 	if err != nil {
-		return fmt.Errorf("failed to connect: %w", err)
+		return err
 	}
 
-	if err := db.RunMigrations(); err != nil {
+	if err := a.prepareSchema(db); err != nil {
 		if cerr := db.Close(); cerr != nil {
 			return fmt.Errorf("failed to close db after migration error: %v; original error: %w", cerr, err)
 		}
-		return fmt.Errorf("failed to run migrations: %w", err)
+		return err
 	}
 
 	a.db = db
 	return nil
 }
-func (a *App) initServer() error {
-	// Repositories.
-	userRepo := repository.NewUserRepository(a.db)
-	orderRepo := repository.NewOrderRepository(a.db)
-	balanceRepo := repository.NewBalanceRepository(a.db)
-	withdrawalRepo := repository.NewWithdrawalRepository(a.db)
 
+// prepareSchema применяет миграции или, если это отключено, только проверяет,
+// что сервис совместим с текущей схемой.
+func (a *App) prepareSchema(db *repository.DB) error {
+	if a.cfg.Database().AutoMigrate {
+		if err := db.RunMigrations(); err != nil {
+			return fmt.Errorf("failed to run migrations: %w", err)
+		}
+		return nil
+	}
+
+	status, err := db.CheckMigrations()
+	if err != nil {
+		return fmt.Errorf("failed to check migrations: %w", err)
+	}
+
+	latest, err := repository.LatestMigrationVersion()
+	if err != nil {
+		return fmt.Errorf("failed to check migrations: %w", err)
+	}
+	if status.Version < latest {
+		a.logger.WithFields(logrus.Fields{
+			"version": status.Version,
+			"latest":  latest,
+		}).Warn("Automatic migrations disabled and database schema is behind, run migrate up")
+	}
+	return nil
+}
+
+// OpenDatabase подключается к PostgreSQL по настройкам cfg без применения
+// миграций.
+func OpenDatabase(cfg *config.Config, logger *logrus.Logger) (*repository.DB, error) {
+	dbCfg := &repository.DatabaseConfig{
+		URL:             cfg.Database().URL,
+		Host:            cfg.Database().Host,
+		Port:            cfg.Database().Port,
+		User:            cfg.Database().User,
+		Password:        cfg.Database().Password,
+		Database:        cfg.Database().Name,
+		SSLMode:         "disable",
+		MaxOpenConns:    cfg.Database().MaxOpenConns,
+		MaxIdleConns:    cfg.Database().MaxIdleConns,
+		ConnMaxLifetime: cfg.Database().ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Database().ConnMaxIdleTime,
+	}
+
+	db, err := repository.NewDB(dbCfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	return db, nil
+}
+func (a *App) initServer() error {
 	// JWT Service.
 	jwtService := service.NewJWTService(
 		a.cfg.JWT().SecretKey,
 		a.cfg.JWT().TokenDuration,
+		a.cfg.JWT().VerificationKeys...,
 	)
+	a.jwtService = jwtService
 
 	// Auth Service.
-	authService := service.NewAuthService(userRepo)
+	authService := service.NewAuthService(a.repos.users, a.cfg.Tiers())
 
 	// Other Services.
-	orderService := service.NewOrderService(orderRepo)
-	balanceService := service.NewBalanceService(balanceRepo)
-	withdrawalService := service.NewWithdrawalService(withdrawalRepo, balanceRepo)
-
-	// Запускаем воркер, если указан адрес системы начисления.
-	if a.cfg.Accrual().Address != "" {
-		a.workerContext, a.workerCancel = context.WithCancel(context.Background())
-		accrualWorker := worker.NewAccrualWorker(
-			a.cfg.Accrual().Address,
-			orderService,
-			balanceService,
-			a.logger,
-		)
-		go accrualWorker.Start(a.workerContext)
+	var tierService service.TierService
+	if tiers := a.cfg.Tiers(); len(tiers.Levels) > 0 {
+		tierService = service.NewTierService(tiers, a.repos.users, a.repos.orders, a.repos.withdrawals)
+		a.logger.WithField("basis", tiers.Basis).Info("Loyalty tiers enabled")
+	}
+
+	orderService := service.NewOrderService(a.repos.orders)
+	balanceService := service.NewBalanceService(a.repos.balances, a.cfg.Points())
+	withdrawalService := service.NewWithdrawalService(a.cfg.Withdrawals(), a.repos.withdrawals, tierService)
+	transferService := service.NewTransferService(a.cfg.Transfers(), a.repos.transfers, a.repos.users)
+	a.withdrawals = withdrawalService
+	a.transfers = transferService
+	campaignService := service.NewCampaignService(a.repos.campaigns, a.repos.users, a.cfg.Points())
+	referralService := service.NewReferralService(a.cfg.Referral(), a.repos.referrals, a.repos.users, a.cfg.Points())
+
+	// Встроенный расчёт начислений обслуживает заказы своего префикса
+	// наравне с внешними системами.
+	var rewardService service.RewardService
+	var engine worker.AccrualClient
+	if a.cfg.Accrual().EngineEnabled {
+		rewardService = service.NewRewardService(a.repos.rewards)
+		engine = rewardService
+	}
+
+	accrualClient := worker.NewAccrualClient(a.cfg.Accrual(), engine)
+	accrualWorker := worker.NewAccrualWorker(
+		a.cfg.Accrual(),
+		accrualClient,
+		orderService,
+		balanceService,
+		tierService,
+		campaignService,
+		referralService,
+		a.logger,
+	)
+	a.accrualWorker = accrualWorker
+
+	a.workerContext, a.workerCancel = context.WithCancel(context.Background())
+
+	// Запускаем опрос, если настроена хотя бы одна система начисления.
+	if accrualClient != nil {
+		a.workers.Go(func() { accrualWorker.Start(a.workerContext) })
 		a.logger.Info("Accrual worker initialized")
 	} else {
 		a.logger.Warn("Accrual system address not configured, worker not started")
 	}
 
+	if points := a.cfg.Points(); points.TTL > 0 {
+		expiryWorker := worker.NewExpiryWorker(points.ExpiryInterval, balanceService, a.logger)
+		a.workers.Go(func() { expiryWorker.Start(a.workerContext) })
+		a.logger.WithField("ttl", points.TTL).Info("Points expiry enabled")
+	}
+
+	// Уведомления от системы начислений обрабатываются тем же воркером.
+	var accrualHandler *handler.AccrualHandler
+	if secret := a.cfg.Accrual().WebhookSecret; secret != "" {
+		accrualHandler = handler.NewAccrualHandler(accrualWorker, secret, a.logger)
+		a.logger.Info("Accrual notifications enabled")
+	}
+
+	var partnerHandler *handler.PartnerHandler
+	if rewardService != nil {
+		partnerHandler = handler.NewPartnerHandler(rewardService, a.cfg.Accrual().PartnerSecret, a.logger)
+		a.logger.WithField("prefix", a.cfg.Accrual().EnginePrefix).Info("Built-in accrual engine enabled")
+	}
+
 	// Handlers.
 	authHandler := handler.NewAuthHandler(authService, jwtService, a.logger)
 	orderHandler := handler.NewOrderHandler(orderService, a.logger)
-	balanceHandler := handler.NewBalanceHandler(balanceService, a.logger)
+	balanceHandler := handler.NewBalanceHandler(balanceService, tierService, a.logger)
 	withdrawalHandler := handler.NewWithdrawalHandler(withdrawalService, a.logger)
+	transferHandler := handler.NewTransferHandler(transferService, a.logger)
+	bonusHandler := handler.NewBonusHandler(campaignService, a.logger)
+	referralHandler := handler.NewReferralHandler(referralService, a.logger)
 	authMiddleware := middleware.NewAuthMiddleware(jwtService)
 
-	router := handler.NewRouter(authHandler, orderHandler, balanceHandler, withdrawalHandler, authMiddleware)
+	router := handler.NewRouter(authHandler, orderHandler, balanceHandler, withdrawalHandler, transferHandler, bonusHandler, referralHandler, accrualHandler, partnerHandler, authMiddleware)
 
 	a.server = &http.Server{
 		Addr:         a.cfg.Server().Port,
@@ -125,6 +265,29 @@ func (a *App) initServer() error {
 
 	return nil
 }
+
+// Reload применяет из next настройки, которые меняются без перезапуска:
+// уровень логирования, параметры воркера начислений и ключи JWT. Соединения
+// и запущенный сервер не затрагиваются. Изменения остальных настроек
+// игнорируются с предупреждением.
+func (a *App) Reload(next *config.Config) {
+	cfg, restart := a.cfg.Reload(next)
+	if len(restart) > 0 {
+		a.logger.WithField("settings", restart).Warn("Configuration changes require restart and were not applied")
+	}
+
+	changed := a.cfg.Changes(cfg)
+
+	a.logger.SetLevel(cfg.Logger().Level)
+	a.accrualWorker.SetConfig(cfg.Accrual())
+	a.jwtService.Reload(cfg.JWT().SecretKey, cfg.JWT().TokenDuration, cfg.JWT().VerificationKeys...)
+	a.withdrawals.SetLimits(cfg.Withdrawals())
+	a.transfers.SetLimits(cfg.Transfers())
+	a.cfg = cfg
+
+	a.logger.WithField("changed", changed).Info("Configuration reloaded")
+}
+
 func (a *App) Run() error {
 	a.logger.Infof("Starting server on port %s", a.cfg.Server().Port)
 
@@ -145,9 +308,34 @@ func (a *App) Shutdown(ctx context.Context) error {
 		a.logger.Errorf("Server shutdown error: %v", err)
 	}
 
-	if err := a.db.Close(); err != nil {
-		a.logger.Errorf("Database close error: %v", err)
+	// База закрывается и при ошибке: процесс всё равно завершается.
+	err := a.waitWorkers(ctx)
+
+	if a.db != nil {
+		if err := a.db.Close(); err != nil {
+			a.logger.Errorf("Database close error: %v", err)
+		}
 	}
 
-	return nil
+	return err
+}
+
+// waitWorkers ждёт, пока фоновые воркеры доделают начатую работу, но не
+// дольше, чем позволяет ctx.
+func (a *App) waitWorkers(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		a.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		if order, ok := a.accrualWorker.InFlight(); ok {
+			return fmt.Errorf("accrual worker did not finish order %s (status %s): %w", order.Number, order.Status, ctx.Err())
+		}
+		return fmt.Errorf("background workers did not stop: %w", ctx.Err())
+	}
 }
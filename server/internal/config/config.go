@@ -3,8 +3,12 @@ package config
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/RoGogDBD/loyalty_service/server/internal/models"
@@ -13,101 +17,364 @@ import (
 
 type (
 	Config struct {
-		Env      string
-		server   models.ServerConfig
-		database models.DatabaseConfig
-		accrual  models.AccrualConfig
-		logger   models.LoggerConfig
-		jwt      models.JWTConfig
+		Env         string
+		server      models.ServerConfig
+		database    models.DatabaseConfig
+		accrual     models.AccrualConfig
+		points      models.PointsConfig
+		tiers       models.TiersConfig
+		referral    models.ReferralConfig
+		transfers   models.TransfersConfig
+		withdrawals models.WithdrawalsConfig
+		logger      models.LoggerConfig
+		jwt         models.JWTConfig
+
+		// generatedSecret — ключ JWT не задан и сгенерирован при загрузке.
+		generatedSecret bool
 	}
 )
 
+// NewConfig возвращает конфигурацию со значениями по умолчанию.
 func NewConfig() *Config {
 	return &Config{
+		Env: EnvDevelopment,
 		server: models.ServerConfig{
-			Port:         getEnv("SERVER_PORT", "8080"),
-			ReadTimeout:  getEnvAsDuration("READ_TIMEOUT", 10*time.Second),
-			WriteTimeout: getEnvAsDuration("WRITE_TIMEOUT", 10*time.Second),
+			Port:         ":8080",
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 10 * time.Second,
 		},
 		database: models.DatabaseConfig{
-			Host:            getEnv("DB_HOST", "localhost"),
-			Port:            getEnvAsInt("DB_PORT", 5432),
-			User:            getEnv("DB_USER", "postgres"),
-			Password:        getEnv("DB_PASSWORD", ""),
-			Name:            getEnv("DB_NAME", "gophermart"),
-			URL:             getEnv("DATABASE_URI", ""),
-			MaxOpenConns:    getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
-			MaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", 5),
-			ConnMaxLifetime: getEnvAsDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
-			ConnMaxIdleTime: getEnvAsDuration("DB_CONN_MAX_IDLE_TIME", 10*time.Minute),
+			Storage:         models.StoragePostgres,
+			Host:            "localhost",
+			Port:            5432,
+			User:            "postgres",
+			Name:            "gophermart",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 5 * time.Minute,
+			ConnMaxIdleTime: 10 * time.Minute,
+			AutoMigrate:     true,
 		},
 		accrual: models.AccrualConfig{
-			Address: getEnv("ACCRUAL_SYSTEM_ADDRESS", ""),
+			PollInterval:    5 * time.Second,
+			RequestTimeout:  10 * time.Second,
+			OrderDelay:      100 * time.Millisecond,
+			BatchSize:       100,
+			MinPollInterval: time.Second,
+			MaxPollInterval: time.Minute,
+
+			FallbackPollInterval: time.Minute,
+		},
+		points: models.PointsConfig{
+			ExpiryInterval: time.Hour,
+			ExpiringWindow: 30 * 24 * time.Hour,
+		},
+		tiers: models.TiersConfig{
+			Basis: models.TierBasisLifetimeAccrual,
 		},
 		logger: models.LoggerConfig{
-			Level: getLogLevel(getEnv("LOG_LEVEL", "info")),
+			Level: logrus.InfoLevel,
 		},
 		jwt: models.JWTConfig{
-			SecretKey:     getEnvOrGenerate("JWT_SECRET"),
-			TokenDuration: getEnvAsDuration("JWT_TOKEN_DURATION", 24*time.Hour),
+			TokenDuration: 24 * time.Hour,
 		},
 	}
 }
 
-func (c *Config) Server() models.ServerConfig     { return c.server }
-func (c *Config) Database() models.DatabaseConfig { return c.database }
-func (c *Config) Accrual() models.AccrualConfig   { return c.accrual }
-func (c *Config) Logger() models.LoggerConfig     { return c.logger }
-func (c *Config) JWT() models.JWTConfig           { return c.jwt }
+// Load собирает конфигурацию по слоям: значения по умолчанию < файл
+// конфигурации < переменные окружения < флаги из args. Флаги регистрируются
+// в fs, позиционные аргументы остаются в fs.Args().
+//
+// Ошибки разбора и проверки возвращаются одним списком вместе с
+// конфигурацией, чтобы её можно было вывести для диагностики.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	c := NewConfig()
+	f := registerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return c, err
+	}
+
+	var errs []error
+
+	path := f.configFile
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := c.loadFile(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	errs = append(errs, c.applyEnv()...)
+	f.apply(c, fs)
+
+	if c.jwt.SecretKey == "" {
+		c.jwt.SecretKey = generateSecret()
+		c.generatedSecret = true
+	}
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+	if err := c.Validate(); err != nil {
+		errs = append(errs, err)
 	}
-	return defaultValue
+
+	return c, errors.Join(errs...)
+}
+
+func (c *Config) Server() models.ServerConfig           { return c.server }
+func (c *Config) Database() models.DatabaseConfig       { return c.database }
+func (c *Config) Accrual() models.AccrualConfig         { return c.accrual }
+func (c *Config) Points() models.PointsConfig           { return c.points }
+func (c *Config) Tiers() models.TiersConfig             { return c.tiers }
+func (c *Config) Referral() models.ReferralConfig       { return c.referral }
+func (c *Config) Transfers() models.TransfersConfig     { return c.transfers }
+func (c *Config) Withdrawals() models.WithdrawalsConfig { return c.withdrawals }
+func (c *Config) Logger() models.LoggerConfig           { return c.logger }
+func (c *Config) JWT() models.JWTConfig                 { return c.jwt }
+
+// applyEnv переносит в конфигурацию заданные переменные окружения.
+func (c *Config) applyEnv() []error {
+	e := &envReader{}
+
+	e.string("ENV", &c.Env)
+
+	e.string("SERVER_PORT", &c.server.Port)
+	e.string("RUN_ADDRESS", &c.server.Port)
+	c.server.Port = normalizeAddress(c.server.Port)
+	e.duration("READ_TIMEOUT", &c.server.ReadTimeout)
+	e.duration("WRITE_TIMEOUT", &c.server.WriteTimeout)
+
+	e.string("STORAGE", &c.database.Storage)
+	e.string("DB_HOST", &c.database.Host)
+	e.int("DB_PORT", &c.database.Port)
+	e.string("DB_USER", &c.database.User)
+	e.string("DB_PASSWORD", &c.database.Password)
+	e.string("DB_NAME", &c.database.Name)
+	e.string("DATABASE_URI", &c.database.URL)
+	e.int("DB_MAX_OPEN_CONNS", &c.database.MaxOpenConns)
+	e.int("DB_MAX_IDLE_CONNS", &c.database.MaxIdleConns)
+	e.duration("DB_CONN_MAX_LIFETIME", &c.database.ConnMaxLifetime)
+	e.duration("DB_CONN_MAX_IDLE_TIME", &c.database.ConnMaxIdleTime)
+	e.bool("AUTO_MIGRATE", &c.database.AutoMigrate)
+
+	e.string("ACCRUAL_SYSTEM_ADDRESS", &c.accrual.Address)
+	e.providers("ACCRUAL_PROVIDERS", &c.accrual.Providers)
+	e.duration("ACCRUAL_POLL_INTERVAL", &c.accrual.PollInterval)
+	e.duration("ACCRUAL_REQUEST_TIMEOUT", &c.accrual.RequestTimeout)
+	e.duration("ACCRUAL_ORDER_DELAY", &c.accrual.OrderDelay)
+	e.int("ACCRUAL_BATCH_SIZE", &c.accrual.BatchSize)
+	e.bool("ACCRUAL_DYNAMIC_INTERVAL", &c.accrual.DynamicInterval)
+	e.duration("ACCRUAL_MIN_POLL_INTERVAL", &c.accrual.MinPollInterval)
+	e.duration("ACCRUAL_MAX_POLL_INTERVAL", &c.accrual.MaxPollInterval)
+	e.string("ACCRUAL_WEBHOOK_SECRET", &c.accrual.WebhookSecret)
+	e.duration("ACCRUAL_FALLBACK_POLL_INTERVAL", &c.accrual.FallbackPollInterval)
+	e.bool("ACCRUAL_ENGINE", &c.accrual.EngineEnabled)
+	e.string("ACCRUAL_ENGINE_PREFIX", &c.accrual.EnginePrefix)
+	e.string("ACCRUAL_PARTNER_SECRET", &c.accrual.PartnerSecret)
+
+	e.duration("POINTS_TTL", &c.points.TTL)
+	e.duration("POINTS_EXPIRY_INTERVAL", &c.points.ExpiryInterval)
+	e.duration("POINTS_EXPIRING_WINDOW", &c.points.ExpiringWindow)
+
+	e.string("TIERS_BASIS", &c.tiers.Basis)
+	e.tiers("TIERS", &c.tiers.Levels)
+
+	e.float("REFERRAL_REFERRER_BONUS", &c.referral.ReferrerBonus)
+	e.float("REFERRAL_REFEREE_BONUS", &c.referral.RefereeBonus)
+	e.int("REFERRAL_MAX_REWARDS", &c.referral.MaxRewards)
+	e.int("REFERRAL_MAX_DAILY_REWARDS", &c.referral.MaxDailyRewards)
+
+	e.float("TRANSFERS_MAX_DAILY_SUM", &c.transfers.MaxDailySum)
+	e.int("TRANSFERS_MAX_DAILY_COUNT", &c.transfers.MaxDailyCount)
+
+	e.float("WITHDRAWALS_MAX_SUM", &c.withdrawals.MaxSum)
+	e.float("WITHDRAWALS_MAX_DAILY_SUM", &c.withdrawals.MaxDailySum)
+	e.float("WITHDRAWALS_MIN_BALANCE", &c.withdrawals.MinBalance)
+	e.int("WITHDRAWALS_MAX_PER_HOUR", &c.withdrawals.MaxPerHour)
+
+	e.logLevel("LOG_LEVEL", &c.logger.Level)
+
+	e.string("JWT_SECRET", &c.jwt.SecretKey)
+	e.list("JWT_VERIFICATION_KEYS", &c.jwt.VerificationKeys)
+	e.duration("JWT_TOKEN_DURATION", &c.jwt.TokenDuration)
+
+	return e.errs
+}
+
+// envReader читает переменные окружения и накапливает ошибки разбора вместо
+// тихого возврата к значениям по умолчанию.
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) lookup(key string) (string, bool) {
+	value, ok := os.LookupEnv(key)
+	return value, ok && value != ""
+}
+
+func (e *envReader) fail(key, value string, err error) {
+	e.errs = append(e.errs, fmt.Errorf("%s=%q: %w", key, value, err))
 }
 
-func getEnvOrGenerate(key string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+func (e *envReader) string(key string, dst *string) {
+	if value, ok := e.lookup(key); ok {
+		*dst = value
 	}
-	// Генерируем случайный ключ, если не задан
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
-		return "default-jwt-secret-change-in-production"
+}
+
+// list читает список через запятую, пустые элементы отбрасываются.
+func (e *envReader) list(key string, dst *[]string) {
+	if value, ok := e.lookup(key); ok {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*dst = items
+	}
+}
+
+// providers читает список систем начислений вида "prefix=address,...".
+func (e *envReader) providers(key string, dst *[]models.AccrualProvider) {
+	var items []string
+	e.list(key, &items)
+	if items == nil {
+		return
+	}
+
+	providers := make([]models.AccrualProvider, 0, len(items))
+	for _, item := range items {
+		prefix, address, ok := strings.Cut(item, "=")
+		if !ok {
+			e.fail(key, item, errors.New(`must be a list of "prefix=address"`))
+			return
+		}
+		providers = append(providers, models.AccrualProvider{
+			Prefix:  strings.TrimSpace(prefix),
+			Address: strings.TrimSpace(address),
+		})
+	}
+	*dst = providers
+}
+
+// tiers читает уровни лояльности вида "name:threshold:multiplier,...".
+func (e *envReader) tiers(key string, dst *[]models.TierLevel) {
+	var items []string
+	e.list(key, &items)
+	if items == nil {
+		return
+	}
+
+	levels := make([]models.TierLevel, 0, len(items))
+	for _, item := range items {
+		parts := strings.Split(item, ":")
+		if len(parts) != 3 {
+			e.fail(key, item, errors.New(`must be a list of "name:threshold:multiplier"`))
+			return
+		}
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			e.fail(key, item, errors.New("threshold must be a number"))
+			return
+		}
+		multiplier, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+		if err != nil {
+			e.fail(key, item, errors.New("multiplier must be a number"))
+			return
+		}
+		levels = append(levels, models.TierLevel{
+			Name:       strings.TrimSpace(parts[0]),
+			Threshold:  threshold,
+			Multiplier: multiplier,
+		})
+	}
+	*dst = levels
+}
+
+func (e *envReader) int(key string, dst *int) {
+	if value, ok := e.lookup(key); ok {
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			e.fail(key, value, errors.New("must be an integer"))
+			return
+		}
+		*dst = v
 	}
-	return base64.URLEncoding.EncodeToString(b)
 }
 
-func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return time.Duration(intValue) * time.Second
+func (e *envReader) float(key string, dst *float64) {
+	if value, ok := e.lookup(key); ok {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			e.fail(key, value, errors.New("must be a number"))
+			return
 		}
+		*dst = v
 	}
-	return defaultValue
 }
 
-func getEnvAsInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+func (e *envReader) bool(key string, dst *bool) {
+	if value, ok := e.lookup(key); ok {
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			e.fail(key, value, errors.New("must be true or false"))
+			return
 		}
+		*dst = v
 	}
-	return defaultValue
 }
 
-func getLogLevel(level string) logrus.Level {
-	switch level {
-	case "debug":
-		return logrus.DebugLevel
-	case "info":
-		return logrus.InfoLevel
-	case "warn":
-		return logrus.WarnLevel
-	case "error":
-		return logrus.ErrorLevel
-	default:
-		return logrus.InfoLevel
+func (e *envReader) duration(key string, dst *time.Duration) {
+	if value, ok := e.lookup(key); ok {
+		v, err := parseDuration(value)
+		if err != nil {
+			e.fail(key, value, err)
+			return
+		}
+		*dst = v
+	}
+}
+
+func (e *envReader) logLevel(key string, dst *logrus.Level) {
+	if value, ok := e.lookup(key); ok {
+		v, err := logrus.ParseLevel(value)
+		if err != nil {
+			e.fail(key, value, errors.New("unknown log level"))
+			return
+		}
+		*dst = v
+	}
+}
+
+// parseDuration принимает длительность в формате Go ("10s", "1h30m") или
+// целое число секунд, как в прежних версиях конфигурации.
+func parseDuration(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.New(`must be a duration like "10s" or a number of seconds`)
 	}
+	return d, nil
+}
+
+// normalizeAddress дополняет адрес из одного номера порта двоеточием:
+// "8080" → ":8080".
+func normalizeAddress(addr string) string {
+	if _, err := strconv.Atoi(addr); err == nil && !strings.Contains(addr, ":") {
+		return ":" + addr
+	}
+	return addr
+}
+
+// generateSecret создаёт случайный ключ подписи JWT, если он не задан.
+func generateSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "default-jwt-secret-change-in-production"
+	}
+	return base64.URLEncoding.EncodeToString(b)
 }
@@ -0,0 +1,569 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// clearEnv сбрасывает переменные окружения, которые читает конфигурация.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{
+		"ENV", "CONFIG_FILE", "SERVER_PORT", "RUN_ADDRESS", "READ_TIMEOUT", "WRITE_TIMEOUT",
+		"STORAGE", "DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME", "DATABASE_URI",
+		"DB_MAX_OPEN_CONNS", "DB_MAX_IDLE_CONNS", "DB_CONN_MAX_LIFETIME", "DB_CONN_MAX_IDLE_TIME",
+		"AUTO_MIGRATE", "ACCRUAL_SYSTEM_ADDRESS", "ACCRUAL_POLL_INTERVAL", "ACCRUAL_REQUEST_TIMEOUT",
+		"ACCRUAL_ORDER_DELAY", "ACCRUAL_BATCH_SIZE", "ACCRUAL_DYNAMIC_INTERVAL", "ACCRUAL_MIN_POLL_INTERVAL",
+		"ACCRUAL_MAX_POLL_INTERVAL", "ACCRUAL_WEBHOOK_SECRET", "ACCRUAL_PROVIDERS", "ACCRUAL_FALLBACK_POLL_INTERVAL", "ACCRUAL_ENGINE", "ACCRUAL_ENGINE_PREFIX", "ACCRUAL_PARTNER_SECRET", "POINTS_TTL", "POINTS_EXPIRY_INTERVAL", "POINTS_EXPIRING_WINDOW", "TIERS_BASIS", "TIERS", "REFERRAL_REFERRER_BONUS", "REFERRAL_REFEREE_BONUS", "REFERRAL_MAX_REWARDS", "REFERRAL_MAX_DAILY_REWARDS", "TRANSFERS_MAX_DAILY_SUM", "TRANSFERS_MAX_DAILY_COUNT", "WITHDRAWALS_MAX_SUM", "WITHDRAWALS_MAX_DAILY_SUM", "WITHDRAWALS_MIN_BALANCE", "WITHDRAWALS_MAX_PER_HOUR", "LOG_LEVEL", "JWT_SECRET", "JWT_TOKEN_DURATION", "JWT_VERIFICATION_KEYS",
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func load(t *testing.T, args ...string) (*Config, error) {
+	t.Helper()
+	return Load(flag.NewFlagSet("test", flag.ContinueOnError), args)
+}
+
+func TestLoad_Layers(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, `
+env: production
+server:
+  address: ":9000"
+  read_timeout: 30s
+database:
+  uri: postgres://file
+  max_open_conns: 50
+accrual:
+  address: http://file:8081
+log:
+  level: debug
+jwt:
+  token_duration: 7200
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("RUN_ADDRESS", ":9100")
+	t.Setenv("DATABASE_URI", "postgres://env")
+	t.Setenv("WRITE_TIMEOUT", "45s")
+
+	cfg, err := load(t, "-d", "postgres://flag", "-migrate=false")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	checks := []struct {
+		name      string
+		got, want any
+	}{
+		{"env from file", cfg.Env, EnvProduction},
+		{"address from env over file", cfg.Server().Port, ":9100"},
+		{"read timeout from file", cfg.Server().ReadTimeout, 30 * time.Second},
+		{"write timeout from env", cfg.Server().WriteTimeout, 45 * time.Second},
+		{"database uri from flag over env", cfg.Database().URL, "postgres://flag"},
+		{"max open conns from file", cfg.Database().MaxOpenConns, 50},
+		{"max idle conns default", cfg.Database().MaxIdleConns, 5},
+		{"auto migrate from flag", cfg.Database().AutoMigrate, false},
+		{"accrual from file", cfg.Accrual().Address, "http://file:8081"},
+		{"log level from file", cfg.Logger().Level, logrus.DebugLevel},
+		{"token duration as seconds", cfg.JWT().TokenDuration, 2 * time.Hour},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if cfg.JWT().SecretKey == "" {
+		t.Error("JWT secret was not generated")
+	}
+}
+
+func TestLoad_ConfigFlagAndPortOnly(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("STORAGE", "memory")
+	t.Setenv("SERVER_PORT", "8081")
+
+	cfg, err := load(t, "-config", writeFile(t, "log:\n  level: warn\n"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Server().Port != ":8081" {
+		t.Errorf("port = %q, want :8081", cfg.Server().Port)
+	}
+	if cfg.Logger().Level != logrus.WarnLevel {
+		t.Errorf("level = %v, want warn", cfg.Logger().Level)
+	}
+}
+
+func TestLoad_ReportsAllProblems(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("READ_TIMEOUT", "soon")
+	t.Setenv("DB_PORT", "abc")
+	t.Setenv("LOG_LEVEL", "loud")
+	t.Setenv("ENV", "staging")
+	t.Setenv("ACCRUAL_SYSTEM_ADDRESS", "localhost:8081")
+	t.Setenv("ACCRUAL_WEBHOOK_SECRET", "short")
+
+	_, err := load(t, "-a", "nowhere")
+	if err == nil {
+		t.Fatal("Load succeeded, want error")
+	}
+
+	for _, want := range []string{"READ_TIMEOUT", "DB_PORT", "LOG_LEVEL", "env:", "server address", "DB_PASSWORD", "accrual system address", "webhook secret"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoad_FileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown key", "server:\n  adress: :8080\n", "adress"},
+		{"bad duration", "server:\n  read_timeout: later\n", "duration"},
+		{"bad level", "log:\n  level: loud\n", "log level"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("STORAGE", "memory")
+
+			_, err := load(t, "-config", writeFile(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want mention of %q", err, tt.want)
+			}
+		})
+	}
+
+	clearEnv(t)
+	if _, err := load(t, "-config", filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("missing config file was accepted")
+	}
+}
+
+func TestConfig_PrintMasksSecrets(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DB_PASSWORD", "db-secret")
+	t.Setenv("DATABASE_URI", "postgres://app:uri-secret@db:5432/gm")
+	t.Setenv("JWT_SECRET", "jwt-secret")
+
+	cfg, err := load(t)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := cfg.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"db-secret", "uri-secret", "jwt-secret"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("printed config contains %q:\n%s", secret, out.String())
+		}
+	}
+	if !strings.Contains(out.String(), "postgres://app:redacted@db:5432/gm") {
+		t.Errorf("database uri not masked as expected:\n%s", out.String())
+	}
+
+	// Выведенная конфигурация читается обратно как файл.
+	path := writeFile(t, out.String())
+	clearEnv(t)
+	if _, err := load(t, "-config", path); err != nil {
+		t.Errorf("printed config does not load back: %v", err)
+	}
+}
+
+func TestMaskURI(t *testing.T) {
+	tests := map[string]string{
+		"":                                 "",
+		"postgres://app@db/gm":             "postgres://app@db/gm",
+		"host=db password=secret user=app": "host=db password=redacted user=app",
+		"host=db password='a b' user=app":  "host=db password=redacted user=app",
+		"postgres://app:p%40ss@db:5432/gm": "postgres://app:redacted@db:5432/gm",
+	}
+	for in, want := range tests {
+		if got := maskURI(in); got != want {
+			t.Errorf("maskURI(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestConfig_Reload(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("STORAGE", "memory")
+	path := writeFile(t, "accrual:\n  address: http://accrual:8081\n")
+	t.Setenv("CONFIG_FILE", path)
+
+	current, err := load(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(`
+server:
+  address: ":9999"
+accrual:
+  address: http://other:8081
+  batch_size: 5
+log:
+  level: debug
+jwt:
+  verification_keys: [previous]
+withdrawals:
+  max_per_hour: 2
+transfers:
+  max_daily_count: 3
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	next, err := load(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merged, restart := current.Reload(next)
+
+	if want := []string{"server", "accrual.address"}; !slices.Equal(restart, want) {
+		t.Errorf("restart = %v, want %v", restart, want)
+	}
+	if merged.Server().Port != ":8080" || merged.Accrual().Address != "http://accrual:8081" {
+		t.Errorf("restart-only settings applied: port %q, accrual %q", merged.Server().Port, merged.Accrual().Address)
+	}
+	if merged.Logger().Level != logrus.DebugLevel || merged.Accrual().BatchSize != 5 {
+		t.Errorf("reloadable settings not applied: level %s, batch %d", merged.Logger().Level, merged.Accrual().BatchSize)
+	}
+	if merged.JWT().SecretKey != current.JWT().SecretKey {
+		t.Error("generated JWT secret replaced on reload")
+	}
+	if !slices.Equal(merged.JWT().VerificationKeys, []string{"previous"}) {
+		t.Errorf("verification keys = %v, want [previous]", merged.JWT().VerificationKeys)
+	}
+	if merged.Withdrawals().MaxPerHour != 2 || merged.Transfers().MaxDailyCount != 3 {
+		t.Errorf("limits not applied: withdrawals %+v, transfers %+v", merged.Withdrawals(), merged.Transfers())
+	}
+	if want := []string{"log", "accrual", "jwt", "withdrawals", "transfers"}; !slices.Equal(current.Changes(merged), want) {
+		t.Errorf("changes = %v, want %v", current.Changes(merged), want)
+	}
+}
+
+func TestLoad_AccrualProviders(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("STORAGE", "memory")
+	t.Setenv("ACCRUAL_SYSTEM_ADDRESS", "http://accrual:8080")
+
+	cfg, err := load(t, "-config", writeFile(t, `
+accrual:
+  providers:
+    - prefix: "9"
+      address: http://partner:8081
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.AccrualProvider{{Prefix: "9", Address: "http://partner:8081"}}
+	if !slices.Equal(cfg.Accrual().Providers, want) {
+		t.Errorf("file providers = %+v, want %+v", cfg.Accrual().Providers, want)
+	}
+
+	t.Setenv("ACCRUAL_PROVIDERS", "9=http://partner:8081, 42=https://other")
+	cfg, err = load(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = append(want, models.AccrualProvider{Prefix: "42", Address: "https://other"})
+	if !slices.Equal(cfg.Accrual().Providers, want) {
+		t.Errorf("env providers = %+v, want %+v", cfg.Accrual().Providers, want)
+	}
+
+	for value, problem := range map[string]string{
+		"http://partner":        "ACCRUAL_PROVIDERS",
+		"x9=http://partner":     "prefix",
+		"9=partner:8081":        "http(s) URL",
+		"9=http://a,9=http://b": "more than once",
+	} {
+		t.Setenv("ACCRUAL_PROVIDERS", value)
+		if _, err := load(t); err == nil || !strings.Contains(err.Error(), problem) {
+			t.Errorf("ACCRUAL_PROVIDERS=%q: error = %v, want mention of %q", value, err, problem)
+		}
+	}
+
+	// Без основного адреса префиксы должны покрывать любой номер заказа.
+	t.Setenv("ACCRUAL_SYSTEM_ADDRESS", "")
+	t.Setenv("ACCRUAL_PROVIDERS", "9=http://partner:8081")
+	if _, err := load(t); err == nil || !strings.Contains(err.Error(), `orders starting with "0" match no provider`) {
+		t.Errorf("uncovered prefixes: error = %v", err)
+	}
+	var all []string
+	for d := range 10 {
+		all = append(all, fmt.Sprintf("%d=http://partner%d", d, d))
+	}
+	all = append(all, "90=http://other")
+	t.Setenv("ACCRUAL_PROVIDERS", strings.Join(all, ","))
+	if _, err := load(t); err != nil {
+		t.Errorf("prefixes covering every digit: %v", err)
+	}
+}
+
+func TestLoad_AccrualEngine(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("STORAGE", "memory")
+	t.Setenv("ACCRUAL_PROVIDERS", "9=http://partner:8081")
+	t.Setenv("ACCRUAL_SYSTEM_ADDRESS", "http://accrual:8080")
+
+	cfg, err := load(t, "-config", writeFile(t, `
+accrual:
+  engine: true
+  engine_prefix: "7"
+  partner_secret: partner-secret-0123
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc := cfg.Accrual()
+	if !acc.EngineEnabled || acc.EnginePrefix != "7" || acc.PartnerSecret != "partner-secret-0123" {
+		t.Errorf("engine config = %+v", acc)
+	}
+
+	var printed strings.Builder
+	if err := cfg.Print(&printed); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(printed.String(), "partner-secret-0123") {
+		t.Errorf("Print leaked partner secret:\n%s", printed.String())
+	}
+
+	t.Setenv("ACCRUAL_ENGINE", "true")
+	t.Setenv("ACCRUAL_PARTNER_SECRET", "partner-secret-0123")
+	for prefix, problem := range map[string]string{
+		"x7": "prefix of order digits",
+		"9":  "already used",
+	} {
+		t.Setenv("ACCRUAL_ENGINE_PREFIX", prefix)
+		if _, err := load(t); err == nil || !strings.Contains(err.Error(), problem) {
+			t.Errorf("ACCRUAL_ENGINE_PREFIX=%q: error = %v, want mention of %q", prefix, err, problem)
+		}
+	}
+
+	t.Setenv("ACCRUAL_ENGINE_PREFIX", "")
+	t.Setenv("ACCRUAL_SYSTEM_ADDRESS", "http://accrual:8080")
+	if _, err := load(t); err == nil || !strings.Contains(err.Error(), "conflicts with accrual system address") {
+		t.Errorf("empty engine prefix with address: error = %v", err)
+	}
+
+	t.Setenv("ACCRUAL_SYSTEM_ADDRESS", "")
+	t.Setenv("ACCRUAL_PARTNER_SECRET", "short")
+	if _, err := load(t); err == nil || !strings.Contains(err.Error(), "partner secret") {
+		t.Errorf("short partner secret: error = %v", err)
+	}
+}
+
+func TestLoad_Points(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("STORAGE", "memory")
+
+	cfg, err := load(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if points := cfg.Points(); points.TTL != 0 || points.ExpiryInterval != time.Hour || points.ExpiringWindow != 30*24*time.Hour {
+		t.Errorf("default points = %+v", points)
+	}
+
+	cfg, err = load(t, "-config", writeFile(t, `
+points:
+  ttl: 8760h
+  expiry_interval: 10m
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if points := cfg.Points(); points.TTL != 365*24*time.Hour || points.ExpiryInterval != 10*time.Minute {
+		t.Errorf("file points = %+v", points)
+	}
+
+	t.Setenv("POINTS_TTL", "-1s")
+	if _, err := load(t); err == nil || !strings.Contains(err.Error(), "points ttl") {
+		t.Errorf("negative ttl: error = %v", err)
+	}
+}
+
+func TestLoad_Tiers(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("STORAGE", "memory")
+
+	cfg, err := load(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tiers := cfg.Tiers(); tiers.Basis != models.TierBasisLifetimeAccrual || len(tiers.Levels) != 0 {
+		t.Errorf("default tiers = %+v", tiers)
+	}
+
+	cfg, err = load(t, "-config", writeFile(t, `
+tiers:
+  basis: rolling_spend
+  levels:
+    - {name: bronze, threshold: 0, multiplier: 1}
+    - {name: silver, threshold: 1000, multiplier: 1.1}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.TiersConfig{
+		Basis: models.TierBasisRollingSpend,
+		Levels: []models.TierLevel{
+			{Name: "bronze", Threshold: 0, Multiplier: 1},
+			{Name: "silver", Threshold: 1000, Multiplier: 1.1},
+		},
+	}
+	if tiers := cfg.Tiers(); !reflect.DeepEqual(tiers, want) {
+		t.Errorf("file tiers = %+v, want %+v", tiers, want)
+	}
+
+	t.Setenv("TIERS", "bronze:0:1, gold:5000:1.5")
+	cfg, err = load(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if levels := cfg.Tiers().Levels; len(levels) != 2 || levels[1] != (models.TierLevel{Name: "gold", Threshold: 5000, Multiplier: 1.5}) {
+		t.Errorf("env tiers = %+v", levels)
+	}
+
+	for value, problem := range map[string]string{
+		"bronze:0":                   "name:threshold:multiplier",
+		"bronze:100:1":               "first tier must be 0",
+		"bronze:0:1,silver:0:2":      "greater than the previous",
+		"bronze:0:1,bronze:100:2":    "duplicate name",
+		"bronze:0:1,silver:100:0":    "multiplier must be positive",
+		"bronze:0:1,silver:many:1.1": "threshold must be a number",
+	} {
+		t.Setenv("TIERS", value)
+		if _, err := load(t); err == nil || !strings.Contains(err.Error(), problem) {
+			t.Errorf("TIERS=%q: error = %v, want mention of %q", value, err, problem)
+		}
+	}
+
+	t.Setenv("TIERS", "")
+	t.Setenv("TIERS_BASIS", "visits")
+	if _, err := load(t); err == nil || !strings.Contains(err.Error(), "tiers basis") {
+		t.Errorf("unknown basis: error = %v", err)
+	}
+}
+
+func TestLoad_Referral(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("STORAGE", "memory")
+
+	cfg, err := load(t, "-config", writeFile(t, `
+referral:
+  referrer_bonus: 100
+  referee_bonus: 50
+  max_rewards: 20
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.ReferralConfig{ReferrerBonus: 100, RefereeBonus: 50, MaxRewards: 20}
+	if referral := cfg.Referral(); referral != want {
+		t.Errorf("file referral = %+v, want %+v", referral, want)
+	}
+
+	t.Setenv("REFERRAL_REFEREE_BONUS", "25.5")
+	t.Setenv("REFERRAL_MAX_DAILY_REWARDS", "3")
+	cfg, err = load(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if referral := cfg.Referral(); referral.RefereeBonus != 25.5 || referral.MaxDailyRewards != 3 {
+		t.Errorf("env referral = %+v", referral)
+	}
+
+	for key, problem := range map[string]string{
+		"REFERRAL_REFERRER_BONUS":    "must be a number",
+		"REFERRAL_MAX_REWARDS":       "must be an integer",
+		"REFERRAL_MAX_DAILY_REWARDS": "must not be negative",
+	} {
+		value := "many"
+		if key == "REFERRAL_MAX_DAILY_REWARDS" {
+			value = "-1"
+		}
+		t.Setenv(key, value)
+		if _, err := load(t); err == nil || !strings.Contains(err.Error(), problem) {
+			t.Errorf("%s=%q: error = %v, want mention of %q", key, value, err, problem)
+		}
+		t.Setenv(key, "")
+	}
+}
+
+func TestLoad_Transfers(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("STORAGE", "memory")
+
+	cfg, err := load(t, "-config", writeFile(t, `
+transfers:
+  max_daily_sum: 5000
+  max_daily_count: 10
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transfers := cfg.Transfers(); transfers != (models.TransfersConfig{MaxDailySum: 5000, MaxDailyCount: 10}) {
+		t.Errorf("file transfers = %+v", transfers)
+	}
+
+	t.Setenv("TRANSFERS_MAX_DAILY_SUM", "-1")
+	if _, err := load(t); err == nil || !strings.Contains(err.Error(), "transfers max daily sum") {
+		t.Errorf("negative sum: error = %v", err)
+	}
+}
+
+func TestLoad_Withdrawals(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("STORAGE", "memory")
+	t.Setenv("WITHDRAWALS_MAX_PER_HOUR", "3")
+
+	cfg, err := load(t, "-config", writeFile(t, `
+withdrawals:
+  max_sum: 1000
+  max_daily_sum: 3000
+  min_balance: 10
+  max_per_hour: 5
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.WithdrawalsConfig{MaxSum: 1000, MaxDailySum: 3000, MinBalance: 10, MaxPerHour: 3}
+	if withdrawals := cfg.Withdrawals(); withdrawals != want {
+		t.Errorf("withdrawals = %+v, want %+v", withdrawals, want)
+	}
+
+	t.Setenv("WITHDRAWALS_MIN_BALANCE", "-1")
+	if _, err := load(t); err == nil || !strings.Contains(err.Error(), "withdrawals min balance") {
+		t.Errorf("negative min balance: error = %v", err)
+	}
+}
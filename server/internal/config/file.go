@@ -0,0 +1,348 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig — формат файла конфигурации. Отсутствующие ключи не меняют
+// значения нижележащего слоя, поэтому все поля — указатели.
+type fileConfig struct {
+	Env         *string            `yaml:"env,omitempty"`
+	Server      serverSection      `yaml:"server"`
+	Database    dbSection          `yaml:"database"`
+	Accrual     accrualSection     `yaml:"accrual"`
+	Points      pointsSection      `yaml:"points"`
+	Tiers       tiersSection       `yaml:"tiers"`
+	Referral    referralSection    `yaml:"referral"`
+	Transfers   transfersSection   `yaml:"transfers"`
+	Withdrawals withdrawalsSection `yaml:"withdrawals"`
+	Log         logSection         `yaml:"log"`
+	JWT         jwtSection         `yaml:"jwt"`
+}
+
+type (
+	serverSection struct {
+		Address      *string   `yaml:"address,omitempty"`
+		ReadTimeout  *duration `yaml:"read_timeout,omitempty"`
+		WriteTimeout *duration `yaml:"write_timeout,omitempty"`
+	}
+	dbSection struct {
+		Storage         *string   `yaml:"storage,omitempty"`
+		URI             *string   `yaml:"uri,omitempty"`
+		Host            *string   `yaml:"host,omitempty"`
+		Port            *int      `yaml:"port,omitempty"`
+		User            *string   `yaml:"user,omitempty"`
+		Password        *string   `yaml:"password,omitempty"`
+		Name            *string   `yaml:"name,omitempty"`
+		MaxOpenConns    *int      `yaml:"max_open_conns,omitempty"`
+		MaxIdleConns    *int      `yaml:"max_idle_conns,omitempty"`
+		ConnMaxLifetime *duration `yaml:"conn_max_lifetime,omitempty"`
+		ConnMaxIdleTime *duration `yaml:"conn_max_idle_time,omitempty"`
+		AutoMigrate     *bool     `yaml:"auto_migrate,omitempty"`
+	}
+	accrualSection struct {
+		Address         *string            `yaml:"address,omitempty"`
+		Providers       *[]providerSection `yaml:"providers,omitempty"`
+		PollInterval    *duration          `yaml:"poll_interval,omitempty"`
+		RequestTimeout  *duration          `yaml:"request_timeout,omitempty"`
+		OrderDelay      *duration          `yaml:"order_delay,omitempty"`
+		BatchSize       *int               `yaml:"batch_size,omitempty"`
+		DynamicInterval *bool              `yaml:"dynamic_interval,omitempty"`
+		MinPollInterval *duration          `yaml:"min_poll_interval,omitempty"`
+		MaxPollInterval *duration          `yaml:"max_poll_interval,omitempty"`
+
+		WebhookSecret        *string   `yaml:"webhook_secret,omitempty"`
+		FallbackPollInterval *duration `yaml:"fallback_poll_interval,omitempty"`
+
+		Engine        *bool   `yaml:"engine,omitempty"`
+		EnginePrefix  *string `yaml:"engine_prefix,omitempty"`
+		PartnerSecret *string `yaml:"partner_secret,omitempty"`
+	}
+	providerSection struct {
+		Prefix  string `yaml:"prefix"`
+		Address string `yaml:"address"`
+	}
+	pointsSection struct {
+		TTL            *duration `yaml:"ttl,omitempty"`
+		ExpiryInterval *duration `yaml:"expiry_interval,omitempty"`
+		ExpiringWindow *duration `yaml:"expiring_window,omitempty"`
+	}
+	tiersSection struct {
+		Basis  *string         `yaml:"basis,omitempty"`
+		Levels *[]levelSection `yaml:"levels,omitempty"`
+	}
+	levelSection struct {
+		Name       string  `yaml:"name"`
+		Threshold  float64 `yaml:"threshold"`
+		Multiplier float64 `yaml:"multiplier"`
+	}
+	referralSection struct {
+		ReferrerBonus   *float64 `yaml:"referrer_bonus,omitempty"`
+		RefereeBonus    *float64 `yaml:"referee_bonus,omitempty"`
+		MaxRewards      *int     `yaml:"max_rewards,omitempty"`
+		MaxDailyRewards *int     `yaml:"max_daily_rewards,omitempty"`
+	}
+	transfersSection struct {
+		MaxDailySum   *float64 `yaml:"max_daily_sum,omitempty"`
+		MaxDailyCount *int     `yaml:"max_daily_count,omitempty"`
+	}
+	withdrawalsSection struct {
+		MaxSum      *float64 `yaml:"max_sum,omitempty"`
+		MaxDailySum *float64 `yaml:"max_daily_sum,omitempty"`
+		MinBalance  *float64 `yaml:"min_balance,omitempty"`
+		MaxPerHour  *int     `yaml:"max_per_hour,omitempty"`
+	}
+	logSection struct {
+		Level *logLevel `yaml:"level,omitempty"`
+	}
+	jwtSection struct {
+		Secret           *string   `yaml:"secret,omitempty"`
+		VerificationKeys *[]string `yaml:"verification_keys,omitempty"`
+		TokenDuration    *duration `yaml:"token_duration,omitempty"`
+	}
+)
+
+// duration разбирается так же, как переменные окружения: "10s" или число секунд.
+type duration time.Duration
+
+func (d *duration) UnmarshalYAML(node *yaml.Node) error {
+	v, err := parseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*d = duration(v)
+	return nil
+}
+
+func (d duration) MarshalYAML() (any, error) {
+	return time.Duration(d).String(), nil
+}
+
+type logLevel logrus.Level
+
+func (l *logLevel) UnmarshalYAML(node *yaml.Node) error {
+	v, err := logrus.ParseLevel(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: unknown log level %q", node.Line, node.Value)
+	}
+	*l = logLevel(v)
+	return nil
+}
+
+func (l logLevel) MarshalYAML() (any, error) {
+	return logrus.Level(l).String(), nil
+}
+
+// loadFile накладывает значения из YAML-файла на конфигурацию. Неизвестные
+// ключи считаются ошибкой, чтобы опечатки не игнорировались.
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var fc fileConfig
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&fc); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	c.applyFile(&fc)
+	return nil
+}
+
+func (c *Config) applyFile(fc *fileConfig) {
+	set(&c.Env, fc.Env)
+
+	set(&c.server.Port, fc.Server.Address)
+	c.server.Port = normalizeAddress(c.server.Port)
+	setDuration(&c.server.ReadTimeout, fc.Server.ReadTimeout)
+	setDuration(&c.server.WriteTimeout, fc.Server.WriteTimeout)
+
+	db := fc.Database
+	set(&c.database.Storage, db.Storage)
+	set(&c.database.URL, db.URI)
+	set(&c.database.Host, db.Host)
+	set(&c.database.Port, db.Port)
+	set(&c.database.User, db.User)
+	set(&c.database.Password, db.Password)
+	set(&c.database.Name, db.Name)
+	set(&c.database.MaxOpenConns, db.MaxOpenConns)
+	set(&c.database.MaxIdleConns, db.MaxIdleConns)
+	setDuration(&c.database.ConnMaxLifetime, db.ConnMaxLifetime)
+	setDuration(&c.database.ConnMaxIdleTime, db.ConnMaxIdleTime)
+	set(&c.database.AutoMigrate, db.AutoMigrate)
+
+	acc := fc.Accrual
+	set(&c.accrual.Address, acc.Address)
+	if acc.Providers != nil {
+		c.accrual.Providers = make([]models.AccrualProvider, 0, len(*acc.Providers))
+		for _, p := range *acc.Providers {
+			c.accrual.Providers = append(c.accrual.Providers, models.AccrualProvider(p))
+		}
+	}
+	setDuration(&c.accrual.PollInterval, acc.PollInterval)
+	setDuration(&c.accrual.RequestTimeout, acc.RequestTimeout)
+	setDuration(&c.accrual.OrderDelay, acc.OrderDelay)
+	set(&c.accrual.BatchSize, acc.BatchSize)
+	set(&c.accrual.DynamicInterval, acc.DynamicInterval)
+	setDuration(&c.accrual.MinPollInterval, acc.MinPollInterval)
+	setDuration(&c.accrual.MaxPollInterval, acc.MaxPollInterval)
+	set(&c.accrual.WebhookSecret, acc.WebhookSecret)
+	setDuration(&c.accrual.FallbackPollInterval, acc.FallbackPollInterval)
+	set(&c.accrual.EngineEnabled, acc.Engine)
+	set(&c.accrual.EnginePrefix, acc.EnginePrefix)
+	set(&c.accrual.PartnerSecret, acc.PartnerSecret)
+
+	setDuration(&c.points.TTL, fc.Points.TTL)
+	setDuration(&c.points.ExpiryInterval, fc.Points.ExpiryInterval)
+	setDuration(&c.points.ExpiringWindow, fc.Points.ExpiringWindow)
+
+	set(&c.tiers.Basis, fc.Tiers.Basis)
+	if fc.Tiers.Levels != nil {
+		c.tiers.Levels = make([]models.TierLevel, 0, len(*fc.Tiers.Levels))
+		for _, l := range *fc.Tiers.Levels {
+			c.tiers.Levels = append(c.tiers.Levels, models.TierLevel(l))
+		}
+	}
+
+	set(&c.referral.ReferrerBonus, fc.Referral.ReferrerBonus)
+	set(&c.referral.RefereeBonus, fc.Referral.RefereeBonus)
+	set(&c.referral.MaxRewards, fc.Referral.MaxRewards)
+	set(&c.referral.MaxDailyRewards, fc.Referral.MaxDailyRewards)
+
+	set(&c.transfers.MaxDailySum, fc.Transfers.MaxDailySum)
+	set(&c.transfers.MaxDailyCount, fc.Transfers.MaxDailyCount)
+
+	set(&c.withdrawals.MaxSum, fc.Withdrawals.MaxSum)
+	set(&c.withdrawals.MaxDailySum, fc.Withdrawals.MaxDailySum)
+	set(&c.withdrawals.MinBalance, fc.Withdrawals.MinBalance)
+	set(&c.withdrawals.MaxPerHour, fc.Withdrawals.MaxPerHour)
+
+	if fc.Log.Level != nil {
+		c.logger.Level = logrus.Level(*fc.Log.Level)
+	}
+
+	set(&c.jwt.SecretKey, fc.JWT.Secret)
+	set(&c.jwt.VerificationKeys, fc.JWT.VerificationKeys)
+	setDuration(&c.jwt.TokenDuration, fc.JWT.TokenDuration)
+}
+
+// toFile описывает конфигурацию в формате файла.
+func (c *Config) toFile() *fileConfig {
+	return &fileConfig{
+		Env: ptr(c.Env),
+		Server: serverSection{
+			Address:      ptr(c.server.Port),
+			ReadTimeout:  ptr(duration(c.server.ReadTimeout)),
+			WriteTimeout: ptr(duration(c.server.WriteTimeout)),
+		},
+		Database: dbSection{
+			Storage:         ptr(c.database.Storage),
+			URI:             ptr(c.database.URL),
+			Host:            ptr(c.database.Host),
+			Port:            ptr(c.database.Port),
+			User:            ptr(c.database.User),
+			Password:        ptr(c.database.Password),
+			Name:            ptr(c.database.Name),
+			MaxOpenConns:    ptr(c.database.MaxOpenConns),
+			MaxIdleConns:    ptr(c.database.MaxIdleConns),
+			ConnMaxLifetime: ptr(duration(c.database.ConnMaxLifetime)),
+			ConnMaxIdleTime: ptr(duration(c.database.ConnMaxIdleTime)),
+			AutoMigrate:     ptr(c.database.AutoMigrate),
+		},
+		Accrual: accrualSection{
+			Address:         ptr(c.accrual.Address),
+			Providers:       ptr(providerSections(c.accrual.Providers)),
+			PollInterval:    ptr(duration(c.accrual.PollInterval)),
+			RequestTimeout:  ptr(duration(c.accrual.RequestTimeout)),
+			OrderDelay:      ptr(duration(c.accrual.OrderDelay)),
+			BatchSize:       ptr(c.accrual.BatchSize),
+			DynamicInterval: ptr(c.accrual.DynamicInterval),
+			MinPollInterval: ptr(duration(c.accrual.MinPollInterval)),
+			MaxPollInterval: ptr(duration(c.accrual.MaxPollInterval)),
+
+			WebhookSecret:        ptr(c.accrual.WebhookSecret),
+			FallbackPollInterval: ptr(duration(c.accrual.FallbackPollInterval)),
+
+			Engine:        ptr(c.accrual.EngineEnabled),
+			EnginePrefix:  ptr(c.accrual.EnginePrefix),
+			PartnerSecret: ptr(c.accrual.PartnerSecret),
+		},
+		Points: pointsSection{
+			TTL:            ptr(duration(c.points.TTL)),
+			ExpiryInterval: ptr(duration(c.points.ExpiryInterval)),
+			ExpiringWindow: ptr(duration(c.points.ExpiringWindow)),
+		},
+		Tiers: tiersSection{
+			Basis:  ptr(c.tiers.Basis),
+			Levels: ptr(levelSections(c.tiers.Levels)),
+		},
+		Referral: referralSection{
+			ReferrerBonus:   ptr(c.referral.ReferrerBonus),
+			RefereeBonus:    ptr(c.referral.RefereeBonus),
+			MaxRewards:      ptr(c.referral.MaxRewards),
+			MaxDailyRewards: ptr(c.referral.MaxDailyRewards),
+		},
+		Transfers: transfersSection{
+			MaxDailySum:   ptr(c.transfers.MaxDailySum),
+			MaxDailyCount: ptr(c.transfers.MaxDailyCount),
+		},
+		Withdrawals: withdrawalsSection{
+			MaxSum:      ptr(c.withdrawals.MaxSum),
+			MaxDailySum: ptr(c.withdrawals.MaxDailySum),
+			MinBalance:  ptr(c.withdrawals.MinBalance),
+			MaxPerHour:  ptr(c.withdrawals.MaxPerHour),
+		},
+		Log: logSection{
+			Level: ptr(logLevel(c.logger.Level)),
+		},
+		JWT: jwtSection{
+			Secret:           ptr(c.jwt.SecretKey),
+			VerificationKeys: ptr(c.jwt.VerificationKeys),
+			TokenDuration:    ptr(duration(c.jwt.TokenDuration)),
+		},
+	}
+}
+
+func set[T any](dst *T, src *T) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func setDuration(dst *time.Duration, src *duration) {
+	if src != nil {
+		*dst = time.Duration(*src)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func providerSections(providers []models.AccrualProvider) []providerSection {
+	sections := make([]providerSection, 0, len(providers))
+	for _, p := range providers {
+		sections = append(sections, providerSection(p))
+	}
+	return sections
+}
+
+func levelSections(levels []models.TierLevel) []levelSection {
+	sections := make([]levelSection, 0, len(levels))
+	for _, l := range levels {
+		sections = append(sections, levelSection(l))
+	}
+	return sections
+}
@@ -2,33 +2,81 @@ package config
 
 import (
 	"flag"
-	"os"
+	"time"
 )
 
-func (c *Config) ParseFlags() {
-	var runAddress, databaseURI, accrualAddress string
-
-	flag.StringVar(&runAddress, "a", "", "Address and port to run server")
-	flag.StringVar(&databaseURI, "d", "", "Database connection URI")
-	flag.StringVar(&accrualAddress, "r", "", "Accrual system address")
-	flag.Parse()
-
-	// Флаги > переменные окружения > значения по умолчанию.
-	if runAddress != "" {
-		c.server.Port = runAddress
-	} else if addr := os.Getenv("RUN_ADDRESS"); addr != "" {
-		c.server.Port = addr
-	}
-
-	if databaseURI != "" {
-		c.database.URL = databaseURI
-	} else if uri := os.Getenv("DATABASE_URI"); uri != "" {
-		c.database.URL = uri
-	}
-
-	if accrualAddress != "" {
-		c.accrual.Address = accrualAddress
-	} else if addr := os.Getenv("ACCRUAL_SYSTEM_ADDRESS"); addr != "" {
-		c.accrual.Address = addr
-	}
+// flagValues — значения флагов командной строки.
+type flagValues struct {
+	configFile     string
+	runAddress     string
+	databaseURI    string
+	accrualAddress string
+	storage        string
+	autoMigrate    bool
+
+	accrualPollInterval    time.Duration
+	accrualRequestTimeout  time.Duration
+	accrualOrderDelay      time.Duration
+	accrualBatchSize       int
+	accrualDynamicInterval bool
+	accrualMinPollInterval time.Duration
+	accrualMaxPollInterval time.Duration
+
+	accrualFallbackPollInterval time.Duration
+}
+
+func registerFlags(fs *flag.FlagSet) *flagValues {
+	f := &flagValues{}
+
+	fs.StringVar(&f.configFile, "config", "", "Path to YAML configuration file")
+	fs.StringVar(&f.runAddress, "a", "", "Address and port to run server")
+	fs.StringVar(&f.databaseURI, "d", "", "Database connection URI")
+	fs.StringVar(&f.accrualAddress, "r", "", "Accrual system address")
+	fs.StringVar(&f.storage, "storage", "", "Storage backend: postgres or memory")
+	fs.BoolVar(&f.autoMigrate, "migrate", true, "Apply database migrations on startup")
+	fs.DurationVar(&f.accrualPollInterval, "accrual-poll-interval", 0, "Pause between accrual polling passes")
+	fs.DurationVar(&f.accrualRequestTimeout, "accrual-timeout", 0, "Accrual system request timeout")
+	fs.DurationVar(&f.accrualOrderDelay, "accrual-order-delay", 0, "Pause between accrual requests for consecutive orders")
+	fs.IntVar(&f.accrualBatchSize, "accrual-batch-size", 0, "Orders taken from the queue per polling pass")
+	fs.BoolVar(&f.accrualDynamicInterval, "accrual-dynamic-interval", false, "Adapt polling interval to the queue size")
+	fs.DurationVar(&f.accrualMinPollInterval, "accrual-min-poll-interval", 0, "Lower bound of the dynamic polling interval")
+	fs.DurationVar(&f.accrualMaxPollInterval, "accrual-max-poll-interval", 0, "Upper bound of the dynamic polling interval")
+	fs.DurationVar(&f.accrualFallbackPollInterval, "accrual-fallback-poll-interval", 0, "Polling interval when accrual webhook is enabled")
+
+	return f
+}
+
+// apply переносит в конфигурацию только явно заданные флаги: они
+// приоритетнее переменных окружения и файла.
+func (f *flagValues) apply(c *Config, fs *flag.FlagSet) {
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "a":
+			c.server.Port = normalizeAddress(f.runAddress)
+		case "d":
+			c.database.URL = f.databaseURI
+		case "r":
+			c.accrual.Address = f.accrualAddress
+		case "storage":
+			c.database.Storage = f.storage
+		case "migrate":
+			c.database.AutoMigrate = f.autoMigrate
+		case "accrual-poll-interval":
+			c.accrual.PollInterval = f.accrualPollInterval
+		case "accrual-timeout":
+			c.accrual.RequestTimeout = f.accrualRequestTimeout
+		case "accrual-order-delay":
+			c.accrual.OrderDelay = f.accrualOrderDelay
+		case "accrual-batch-size":
+			c.accrual.BatchSize = f.accrualBatchSize
+		case "accrual-dynamic-interval":
+			c.accrual.DynamicInterval = f.accrualDynamicInterval
+		case "accrual-min-poll-interval":
+			c.accrual.MinPollInterval = f.accrualMinPollInterval
+		case "accrual-max-poll-interval":
+			c.accrual.MaxPollInterval = f.accrualMaxPollInterval
+		case "accrual-fallback-poll-interval":
+			c.accrual.FallbackPollInterval = f.accrualFallbackPollInterval
+		}
+	})
 }
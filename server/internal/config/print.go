@@ -0,0 +1,56 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+const maskedSecret = "redacted"
+
+// dsnPassword находит пароль в строке подключения вида "key=value".
+var dsnPassword = regexp.MustCompile(`(password=)('[^']*'|\S+)`)
+
+// Print выводит итоговую конфигурацию в формате файла конфигурации, заменяя
+// пароли и ключи на маску.
+func (c *Config) Print(w io.Writer) error {
+	fc := c.toFile()
+	fc.Database.Password = mask(c.database.Password)
+	fc.Database.URI = ptr(maskURI(c.database.URL))
+	fc.Accrual.WebhookSecret = mask(c.accrual.WebhookSecret)
+	fc.Accrual.PartnerSecret = mask(c.accrual.PartnerSecret)
+	fc.JWT.Secret = mask(c.jwt.SecretKey)
+	keys := make([]string, len(c.jwt.VerificationKeys))
+	for i := range keys {
+		keys[i] = maskedSecret
+	}
+	fc.JWT.VerificationKeys = &keys
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(fc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func mask(secret string) *string {
+	if secret == "" {
+		return ptr("")
+	}
+	return ptr(maskedSecret)
+}
+
+// maskURI скрывает пароль в DATABASE_URI в URL- и key=value-формате.
+func maskURI(uri string) string {
+	if u, err := url.Parse(uri); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), maskedSecret)
+			return u.String()
+		}
+		return uri
+	}
+	return dsnPassword.ReplaceAllString(uri, "${1}"+maskedSecret)
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+)
+
+// Reload возвращает конфигурацию, в которой из next взяты только части,
+// применимые без перезапуска: уровень логирования, настройки воркера
+// начислений (кроме источников начислений и ключей подписи), ключи JWT и
+// лимиты списаний и переводов. Остальное остаётся от c.
+//
+// Второй результат — имена изменённых настроек, которые вступят в силу
+// только после перезапуска.
+func (c *Config) Reload(next *Config) (*Config, []string) {
+	merged := *c
+
+	merged.logger = next.logger
+	merged.withdrawals = next.withdrawals
+	merged.transfers = next.transfers
+
+	accrual := next.accrual
+	accrual.Address = c.accrual.Address
+	accrual.Providers = c.accrual.Providers
+	accrual.WebhookSecret = c.accrual.WebhookSecret
+	accrual.EngineEnabled = c.accrual.EngineEnabled
+	accrual.EnginePrefix = c.accrual.EnginePrefix
+	accrual.PartnerSecret = c.accrual.PartnerSecret
+	merged.accrual = accrual
+
+	// Сгенерированный ключ при каждой загрузке новый: подхватив его,
+	// сервис разлогинил бы всех пользователей.
+	if !next.generatedSecret {
+		merged.jwt = next.jwt
+		merged.generatedSecret = false
+	} else {
+		merged.jwt.VerificationKeys = next.jwt.VerificationKeys
+		merged.jwt.TokenDuration = next.jwt.TokenDuration
+	}
+
+	var restart []string
+	if c.Env != next.Env {
+		restart = append(restart, "env")
+	}
+	if c.server != next.server {
+		restart = append(restart, "server")
+	}
+	if c.database != next.database {
+		restart = append(restart, "database")
+	}
+	if c.accrual.Address != next.accrual.Address {
+		restart = append(restart, "accrual.address")
+	}
+	if !slices.Equal(c.accrual.Providers, next.accrual.Providers) {
+		restart = append(restart, "accrual.providers")
+	}
+	if c.accrual.WebhookSecret != next.accrual.WebhookSecret {
+		restart = append(restart, "accrual.webhook_secret")
+	}
+	if c.accrual.EngineEnabled != next.accrual.EngineEnabled || c.accrual.EnginePrefix != next.accrual.EnginePrefix {
+		restart = append(restart, "accrual.engine")
+	}
+	if c.accrual.PartnerSecret != next.accrual.PartnerSecret {
+		restart = append(restart, "accrual.partner_secret")
+	}
+	if c.points != next.points {
+		restart = append(restart, "points")
+	}
+	if !reflect.DeepEqual(c.tiers, next.tiers) {
+		restart = append(restart, "tiers")
+	}
+	if c.referral != next.referral {
+		restart = append(restart, "referral")
+	}
+
+	return &merged, restart
+}
+
+// Changes перечисляет разделы, отличающиеся в next.
+func (c *Config) Changes(next *Config) []string {
+	var changed []string
+	if c.logger != next.logger {
+		changed = append(changed, "log")
+	}
+	if !reflect.DeepEqual(c.accrual, next.accrual) {
+		changed = append(changed, "accrual")
+	}
+	if c.jwt.SecretKey != next.jwt.SecretKey || c.jwt.TokenDuration != next.jwt.TokenDuration ||
+		!slices.Equal(c.jwt.VerificationKeys, next.jwt.VerificationKeys) {
+		changed = append(changed, "jwt")
+	}
+	if c.withdrawals != next.withdrawals {
+		changed = append(changed, "withdrawals")
+	}
+	if c.transfers != next.transfers {
+		changed = append(changed, "transfers")
+	}
+	return changed
+}
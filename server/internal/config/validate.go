@@ -0,0 +1,175 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+// Validate проверяет конфигурацию целиком и возвращает все найденные
+// проблемы одной ошибкой.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Env == EnvDevelopment || c.Env == EnvProduction,
+		"env: must be %s or %s, got %q", EnvDevelopment, EnvProduction, c.Env)
+
+	if _, _, err := net.SplitHostPort(c.server.Port); err != nil {
+		errs = append(errs, fmt.Errorf("server address %q: must be host:port or :port", c.server.Port))
+	}
+	check(c.server.ReadTimeout > 0, "server read timeout: must be positive")
+	check(c.server.WriteTimeout > 0, "server write timeout: must be positive")
+
+	db := c.database
+	switch db.Storage {
+	case models.StorageMemory:
+	case models.StoragePostgres:
+		if db.URL == "" {
+			check(db.Password != "", "database: set DATABASE_URI or DB_PASSWORD")
+			check(db.Host != "", "database host: must not be empty")
+			check(db.Port > 0 && db.Port <= 65535, "database port: must be between 1 and 65535, got %d", db.Port)
+			check(db.Name != "", "database name: must not be empty")
+		}
+		check(db.MaxOpenConns >= 0, "database max open connections: must not be negative")
+		check(db.MaxIdleConns >= 0, "database max idle connections: must not be negative")
+		check(db.ConnMaxLifetime >= 0, "database connection max lifetime: must not be negative")
+		check(db.ConnMaxIdleTime >= 0, "database connection max idle time: must not be negative")
+	default:
+		errs = append(errs, fmt.Errorf("storage: must be %s or %s, got %q", models.StoragePostgres, models.StorageMemory, db.Storage))
+	}
+
+	acc := c.accrual
+	if acc.Address != "" {
+		check(isHTTPURL(acc.Address), "accrual system address %q: must be an http(s) URL", acc.Address)
+	}
+	prefixes := make(map[string]bool, len(acc.Providers))
+	for _, p := range acc.Providers {
+		check(p.Prefix != "" && strings.Trim(p.Prefix, "0123456789") == "",
+			"accrual provider prefix %q: must be a non-empty prefix of order digits", p.Prefix)
+		check(!prefixes[p.Prefix], "accrual provider prefix %q: configured more than once", p.Prefix)
+		check(isHTTPURL(p.Address), "accrual provider %q address %q: must be an http(s) URL", p.Prefix, p.Address)
+		prefixes[p.Prefix] = true
+	}
+	check(acc.PollInterval > 0, "accrual poll interval: must be positive")
+	check(acc.RequestTimeout > 0, "accrual request timeout: must be positive")
+	check(acc.OrderDelay >= 0, "accrual order delay: must not be negative")
+	check(acc.BatchSize > 0, "accrual batch size: must be positive, got %d", acc.BatchSize)
+	if acc.DynamicInterval {
+		check(acc.MinPollInterval > 0 && acc.MinPollInterval <= acc.PollInterval && acc.PollInterval <= acc.MaxPollInterval,
+			"accrual dynamic interval: need 0 < min (%s) <= poll interval (%s) <= max (%s)",
+			acc.MinPollInterval, acc.PollInterval, acc.MaxPollInterval)
+	}
+	if acc.WebhookSecret != "" {
+		check(len(acc.WebhookSecret) >= 16, "accrual webhook secret: must be at least 16 characters")
+		check(acc.FallbackPollInterval > 0, "accrual fallback poll interval: must be positive")
+	}
+	if acc.EngineEnabled {
+		check(strings.Trim(acc.EnginePrefix, "0123456789") == "",
+			"accrual engine prefix %q: must be a prefix of order digits", acc.EnginePrefix)
+		check(acc.EnginePrefix != "" || acc.Address == "",
+			"accrual engine: empty engine prefix conflicts with accrual system address")
+		check(!prefixes[acc.EnginePrefix] || acc.EnginePrefix == "",
+			"accrual engine prefix %q: already used by an accrual provider", acc.EnginePrefix)
+		check(len(acc.PartnerSecret) >= 16, "accrual partner secret: must be at least 16 characters")
+	}
+	if len(acc.Providers) > 0 || acc.EngineEnabled {
+		// Заказ без подходящего источника начислений остался бы в очереди
+		// навсегда, поэтому источники должны покрывать любой номер.
+		routes := map[string]bool{"": acc.Address != ""}
+		for _, p := range acc.Providers {
+			routes[p.Prefix] = true
+		}
+		if acc.EngineEnabled {
+			routes[acc.EnginePrefix] = true
+		}
+		if prefix, ok := uncoveredPrefix(routes, ""); ok {
+			errs = append(errs, fmt.Errorf("accrual providers: orders starting with %q match no provider, set accrual system address or add a provider for them", prefix))
+		}
+	}
+
+	check(c.points.TTL >= 0, "points ttl: must not be negative")
+	if c.points.TTL > 0 {
+		check(c.points.ExpiryInterval > 0, "points expiry interval: must be positive")
+	}
+	check(c.points.ExpiringWindow >= 0, "points expiring window: must not be negative")
+
+	tiers := c.tiers
+	check(tiers.Basis == models.TierBasisLifetimeAccrual || tiers.Basis == models.TierBasisRollingSpend,
+		"tiers basis %q: must be %q or %q", tiers.Basis, models.TierBasisLifetimeAccrual, models.TierBasisRollingSpend)
+	names := make(map[string]bool, len(tiers.Levels))
+	for i, level := range tiers.Levels {
+		check(level.Name != "", "tier %d: name must not be empty", i+1)
+		check(!names[level.Name], "tier %q: duplicate name", level.Name)
+		names[level.Name] = true
+		if i == 0 {
+			check(level.Threshold == 0, "tier %q: threshold of the first tier must be 0", level.Name)
+		} else {
+			check(level.Threshold > tiers.Levels[i-1].Threshold,
+				"tier %q: threshold must be greater than the previous tier's", level.Name)
+		}
+		check(level.Multiplier > 0, "tier %q: multiplier must be positive", level.Name)
+	}
+
+	check(c.referral.ReferrerBonus >= 0, "referral referrer bonus: must not be negative")
+	check(c.referral.RefereeBonus >= 0, "referral referee bonus: must not be negative")
+	check(c.referral.MaxRewards >= 0, "referral max rewards: must not be negative")
+	check(c.referral.MaxDailyRewards >= 0, "referral max daily rewards: must not be negative")
+
+	check(c.transfers.MaxDailySum >= 0, "transfers max daily sum: must not be negative")
+	check(c.transfers.MaxDailyCount >= 0, "transfers max daily count: must not be negative")
+
+	check(c.withdrawals.MaxSum >= 0, "withdrawals max sum: must not be negative")
+	check(c.withdrawals.MaxDailySum >= 0, "withdrawals max daily sum: must not be negative")
+	check(c.withdrawals.MinBalance >= 0, "withdrawals min balance: must not be negative")
+	check(c.withdrawals.MaxPerHour >= 0, "withdrawals max per hour: must not be negative")
+
+	check(strings.TrimSpace(c.jwt.SecretKey) != "", "jwt secret: must not be empty")
+	for i, key := range c.jwt.VerificationKeys {
+		check(strings.TrimSpace(key) != "", "jwt verification key %d: must not be empty", i+1)
+	}
+	check(c.jwt.TokenDuration > 0, "jwt token duration: must be positive")
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+}
+
+func isHTTPURL(address string) bool {
+	u, err := url.Parse(address)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// uncoveredPrefix ищет префикс номеров, начинающийся с prefix, для которого
+// нет источника начислений. routes — префиксы источников; пустой префикс
+// подходит под любой номер.
+func uncoveredPrefix(routes map[string]bool, prefix string) (string, bool) {
+	if routes[prefix] {
+		return "", false
+	}
+	deeper := false
+	for route, ok := range routes {
+		if ok && len(route) > len(prefix) && strings.HasPrefix(route, prefix) {
+			deeper = true
+			break
+		}
+	}
+	if !deeper {
+		return prefix, true
+	}
+	for d := '0'; d <= '9'; d++ {
+		if uncovered, ok := uncoveredPrefix(routes, prefix+string(d)); ok {
+			return uncovered, true
+		}
+	}
+	return "", false
+}
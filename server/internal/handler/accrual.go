@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/problem"
+	"github.com/sirupsen/logrus"
+)
+
+// Заголовки подписи уведомлений от системы начислений.
+const (
+	HeaderAccrualTimestamp = "X-Accrual-Timestamp"
+	HeaderAccrualSignature = "X-Accrual-Signature"
+)
+
+// AccrualProcessor применяет результат расчёта начисления к заказу.
+type AccrualProcessor interface {
+	ApplyAccrual(ctx context.Context, result *models.AccrualResult) error
+}
+
+type AccrualHandler struct {
+	processor AccrualProcessor
+	verifier  *signatureVerifier
+	logger    *logrus.Logger
+}
+
+func NewAccrualHandler(processor AccrualProcessor, secret string, logger *logrus.Logger) *AccrualHandler {
+	return &AccrualHandler{
+		processor: processor,
+		verifier: &signatureVerifier{
+			secret:          secret,
+			timestampHeader: HeaderAccrualTimestamp,
+			signatureHeader: HeaderAccrualSignature,
+			now:             time.Now,
+		},
+		logger: logger,
+	}
+}
+
+// SignAccrualNotification вычисляет значение заголовка X-Accrual-Signature:
+// HMAC-SHA256 от строки "<timestamp>.<body>" в hex с префиксом "sha256=".
+func SignAccrualNotification(secret string, timestamp int64, body []byte) string {
+	return signBody(secret, timestamp, body)
+}
+
+// Notify принимает от системы начислений изменение статуса заказа.
+func (h *AccrualHandler) Notify(w http.ResponseWriter, r *http.Request) {
+	body, _, err := readBody(w, r, maxJSONBodySize, contentTypeJSON)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.verifier.verify(r.Header, body); err != nil {
+		h.logger.WithError(err).Warn("Rejected accrual notification")
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidSignature, "Invalid notification signature")
+		return
+	}
+
+	var result models.AccrualResult
+	if err := unmarshalStrict(body, &result); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := validateAccrualResult(&result); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.processor.ApplyAccrual(r.Context(), &result); err != nil {
+		logError(h.logger.WithField("orderNumber", result.Order), err, "Failed to apply accrual notification")
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func validateAccrualResult(result *models.AccrualResult) error {
+	result.Order = strings.TrimSpace(result.Order)
+	if result.Order == "" {
+		return badRequest("Order number is required", nil)
+	}
+
+	switch result.Status {
+	case models.AccrualStatusRegistered, models.AccrualStatusProcessing,
+		models.AccrualStatusInvalid, models.AccrualStatusProcessed:
+	default:
+		return badRequest(fmt.Sprintf("Unknown accrual status %q", result.Status), nil)
+	}
+
+	if result.Accrual < 0 {
+		return badRequest("Accrual must not be negative", nil)
+	}
+
+	return nil
+}
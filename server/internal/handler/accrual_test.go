@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/problem"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+)
+
+// signedNotification собирает запрос уведомления, подписанный secret в момент at.
+func signedNotification(secret string, at time.Time, body string) testRequest {
+	timestamp := at.Unix()
+	return testRequest{
+		method:      http.MethodPost,
+		path:        "/api/accrual/notifications",
+		contentType: "application/json",
+		body:        body,
+		anonymous:   true,
+		headers: map[string]string{
+			HeaderAccrualTimestamp: strconv.FormatInt(timestamp, 10),
+			HeaderAccrualSignature: SignAccrualNotification(secret, timestamp, []byte(body)),
+		},
+	}
+}
+
+func TestAccrualHandler_Notify(t *testing.T) {
+	const body = `{"order":"12345678903","status":"PROCESSED","accrual":500}`
+	now := time.Now()
+
+	tampered := signedNotification(testWebhookSecret, now, body)
+	tampered.body = `{"order":"12345678903","status":"PROCESSED","accrual":5000}`
+
+	unsigned := signedNotification(testWebhookSecret, now, body)
+	delete(unsigned.headers, HeaderAccrualSignature)
+
+	tests := []struct {
+		name       string
+		req        testRequest
+		result     error
+		wantCalled bool
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "ok", req: signedNotification(testWebhookSecret, now, body), wantCalled: true, wantStatus: http.StatusOK},
+		{name: "unknown order", req: signedNotification(testWebhookSecret, now, body), result: service.ErrOrderNotFound,
+			wantCalled: true, wantStatus: http.StatusNotFound, wantCode: problem.CodeOrderNotFound},
+		{name: "wrong secret", req: signedNotification("other-webhook-secret", now, body),
+			wantStatus: http.StatusUnauthorized, wantCode: problem.CodeInvalidSignature},
+		{name: "tampered body", req: tampered, wantStatus: http.StatusUnauthorized, wantCode: problem.CodeInvalidSignature},
+		{name: "unsigned", req: unsigned, wantStatus: http.StatusUnauthorized, wantCode: problem.CodeInvalidSignature},
+		{name: "stale", req: signedNotification(testWebhookSecret, now.Add(-10*time.Minute), body),
+			wantStatus: http.StatusUnauthorized, wantCode: problem.CodeInvalidSignature},
+		{name: "unknown status", req: signedNotification(testWebhookSecret, now, `{"order":"12345678903","status":"DONE"}`),
+			wantStatus: http.StatusBadRequest, wantCode: problem.CodeInvalidRequest},
+		{name: "negative accrual", req: signedNotification(testWebhookSecret, now, `{"order":"12345678903","status":"PROCESSED","accrual":-1}`),
+			wantStatus: http.StatusBadRequest, wantCode: problem.CodeInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			var got *models.AccrualResult
+			env.accrual.apply = func(_ context.Context, result *models.AccrualResult) error {
+				got = result
+				return tt.result
+			}
+
+			rec := env.do(t, tt.req)
+
+			if tt.wantCode != "" {
+				assertProblem(t, rec, tt.wantStatus, tt.wantCode)
+			} else if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if (got != nil) != tt.wantCalled {
+				t.Fatalf("processor called = %v, want %v", got != nil, tt.wantCalled)
+			}
+			if got != nil && (got.Order != "12345678903" || got.Status != models.AccrualStatusProcessed || got.Accrual != 500) {
+				t.Errorf("result = %+v", got)
+			}
+		})
+	}
+}
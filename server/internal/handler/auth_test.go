@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/problem"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+)
+
+func TestAuthHandler(t *testing.T) {
+	user := &models.User{ID: 7, Login: "alice", ReferralCode: "K7MXQ2PA"}
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		result      error
+		wantStatus  int
+		wantCode    string
+	}{
+		{
+			name:        "register ok",
+			path:        "/api/user/register",
+			contentType: "application/json",
+			body:        `{"login":"alice","password":"secret"}`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "register with referrer",
+			path:        "/api/user/register",
+			contentType: "application/json",
+			body:        `{"login":"alice","password":"secret","referrer":"AB3DEF7H"}`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "register unknown referrer",
+			path:        "/api/user/register",
+			contentType: "application/json",
+			body:        `{"login":"alice","password":"secret","referrer":"NOBODY"}`,
+			result:      service.ErrUnknownReferrer,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    problem.CodeUnknownReferrer,
+		},
+		{
+			name:        "register login taken",
+			path:        "/api/user/register",
+			contentType: "application/json",
+			body:        `{"login":"alice","password":"secret"}`,
+			result:      service.ErrUserExists,
+			wantStatus:  http.StatusConflict,
+			wantCode:    problem.CodeUserExists,
+		},
+		{
+			name:        "register internal error",
+			path:        "/api/user/register",
+			contentType: "application/json",
+			body:        `{"login":"alice","password":"secret"}`,
+			result:      errors.New("db down"),
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    problem.CodeInternal,
+		},
+		{
+			name:        "register malformed json",
+			path:        "/api/user/register",
+			contentType: "application/json",
+			body:        `{"login":`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    problem.CodeInvalidRequest,
+		},
+		{
+			name:        "register unknown field",
+			path:        "/api/user/register",
+			contentType: "application/json",
+			body:        `{"login":"alice","password":"secret","admin":true}`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    problem.CodeInvalidRequest,
+		},
+		{
+			name:        "register trailing data",
+			path:        "/api/user/register",
+			contentType: "application/json",
+			body:        `{"login":"alice","password":"secret"}{}`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    problem.CodeInvalidRequest,
+		},
+		{
+			name:        "register wrong content type",
+			path:        "/api/user/register",
+			contentType: "text/plain",
+			body:        `{"login":"alice","password":"secret"}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantCode:    problem.CodeUnsupportedMediaType,
+		},
+		{
+			name:        "register body too large",
+			path:        "/api/user/register",
+			contentType: "application/json",
+			body:        `{"login":"` + strings.Repeat("a", maxJSONBodySize) + `"}`,
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantCode:    problem.CodePayloadTooLarge,
+		},
+		{
+			name:        "login ok",
+			path:        "/api/user/login",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"login":"alice","password":"secret"}`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "login bad credentials",
+			path:        "/api/user/login",
+			contentType: "application/json",
+			body:        `{"login":"alice","password":"wrong"}`,
+			result:      service.ErrInvalidCredentials,
+			wantStatus:  http.StatusUnauthorized,
+			wantCode:    problem.CodeInvalidCredentials,
+		},
+		{
+			name:       "login missing content type",
+			path:       "/api/user/login",
+			body:       `{"login":"alice","password":"secret"}`,
+			wantStatus: http.StatusUnsupportedMediaType,
+			wantCode:   problem.CodeUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			respond := func(_ context.Context, creds *models.UserCredentials) (*models.User, error) {
+				if creds.Login != "alice" {
+					t.Errorf("login = %q, want alice", creds.Login)
+				}
+				if strings.Contains(tt.body, "AB3DEF7H") && creds.Referrer != "AB3DEF7H" {
+					t.Errorf("referrer = %q, want AB3DEF7H", creds.Referrer)
+				}
+				if tt.result != nil {
+					return nil, tt.result
+				}
+				return user, nil
+			}
+			env.auth.register = respond
+			env.auth.login = respond
+
+			rec := env.do(t, testRequest{
+				method:      http.MethodPost,
+				path:        tt.path,
+				contentType: tt.contentType,
+				body:        tt.body,
+				anonymous:   true,
+			})
+
+			if tt.wantCode != "" {
+				assertProblem(t, rec, tt.wantStatus, tt.wantCode)
+				return
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			token := strings.TrimPrefix(rec.Header().Get("Authorization"), "Bearer ")
+			userID, err := env.jwt.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("issued token is invalid: %v", err)
+			}
+			if userID != user.ID {
+				t.Errorf("token user = %d, want %d", userID, user.ID)
+			}
+			body := decodeBody[map[string]string](t, rec)
+			if body["token"] != token {
+				t.Errorf("body token = %q, want header token", body["token"])
+			}
+			if register := tt.path == "/api/user/register"; register != (body["referral_code"] == user.ReferralCode) {
+				t.Errorf("body referral code = %q", body["referral_code"])
+			}
+		})
+	}
+}
@@ -11,31 +11,42 @@ import (
 
 type BalanceHandler struct {
 	balanceService service.BalanceService
+	tierService    service.TierService
 	logger         *logrus.Logger
 }
 
-func NewBalanceHandler(balanceService service.BalanceService, logger *logrus.Logger) *BalanceHandler {
-	return &BalanceHandler{balanceService: balanceService, logger: logger}
+// NewBalanceHandler создаёт обработчик баланса. Если задан tierService,
+// в ответ добавляется уровень лояльности пользователя.
+func NewBalanceHandler(balanceService service.BalanceService, tierService service.TierService, logger *logrus.Logger) *BalanceHandler {
+	return &BalanceHandler{balanceService: balanceService, tierService: tierService, logger: logger}
 }
 
 func (h *BalanceHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeUnauthorized(w, r)
 		return
 	}
 
 	balance, err := h.balanceService.GetBalance(r.Context(), userID)
 	if err != nil {
 		h.logger.WithError(err).WithField("userID", userID).Error("Failed to get balance")
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
+	if h.tierService != nil {
+		balance.Tier, err = h.tierService.GetStatus(r.Context(), userID)
+		if err != nil {
+			h.logger.WithError(err).WithField("userID", userID).Error("Failed to get loyalty tier")
+			writeError(w, r, err)
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(balance); err != nil {
-		http.Error(w, "failed to encode response", http.StatusInternalServerError)
-		return
+		h.logger.WithError(err).Error("Failed to encode response")
 	}
 }
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/problem"
+)
+
+func TestBalanceHandler_GetBalance(t *testing.T) {
+	tests := []struct {
+		name       string
+		anonymous  bool
+		balance    *models.Balance
+		tier       *models.TierStatus
+		result     error
+		wantStatus int
+		wantCode   string
+		wantBody   string
+	}{
+		{
+			name:       "balance",
+			balance:    &models.Balance{UserID: testUserID, Current: 500.5, Withdrawn: 42},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"current":500.5,"withdrawn":42}`,
+		},
+		{
+			name:       "with tier",
+			balance:    &models.Balance{UserID: testUserID, Current: 500.5, Withdrawn: 42},
+			tier:       &models.TierStatus{Name: "silver", Multiplier: 1.1, Amount: 1200, Next: &models.TierNext{Name: "gold", Threshold: 5000}},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"current":500.5,"withdrawn":42,"tier":{"name":"silver","multiplier":1.1,"amount":1200,"next":{"name":"gold","threshold":5000}}}`,
+		},
+		{
+			name:       "internal error",
+			result:     errors.New("db down"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   problem.CodeInternal,
+		},
+		{
+			name:       "unauthenticated",
+			anonymous:  true,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   problem.CodeUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.balance.get = func(_ context.Context, userID int) (*models.Balance, error) {
+				if userID != testUserID {
+					t.Errorf("userID = %d, want %d", userID, testUserID)
+				}
+				return tt.balance, tt.result
+			}
+			env.tiers.status = func(context.Context, int) (*models.TierStatus, error) {
+				return tt.tier, nil
+			}
+
+			rec := env.do(t, testRequest{method: http.MethodGet, path: "/api/user/balance", anonymous: tt.anonymous})
+
+			if tt.wantCode != "" {
+				assertProblem(t, rec, tt.wantStatus, tt.wantCode)
+				return
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
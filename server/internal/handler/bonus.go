@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/middleware"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+	"github.com/sirupsen/logrus"
+)
+
+type BonusHandler struct {
+	campaignService service.CampaignService
+	logger          *logrus.Logger
+}
+
+func NewBonusHandler(campaignService service.CampaignService, logger *logrus.Logger) *BonusHandler {
+	return &BonusHandler{campaignService: campaignService, logger: logger}
+}
+
+// GetBonuses возвращает бонусы промоакций, начисленные пользователю,
+// включая отменённые.
+func (h *BonusHandler) GetBonuses(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeUnauthorized(w, r)
+		return
+	}
+
+	bonuses, err := h.campaignService.GetBonuses(r.Context(), userID)
+	if err != nil {
+		h.logger.WithError(err).WithField("userID", userID).Error("Failed to get campaign bonuses")
+		writeError(w, r, err)
+		return
+	}
+
+	if len(bonuses) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(bonuses); err != nil {
+		h.logger.WithError(err).Error("Failed to encode response")
+	}
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/problem"
+)
+
+func TestBonusHandler_GetBonuses(t *testing.T) {
+	created := time.Date(2026, 10, 24, 12, 0, 0, 0, time.UTC)
+	reversed := created.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		anonymous  bool
+		bonuses    []*models.CampaignBonus
+		result     error
+		wantStatus int
+		wantCode   string
+		wantBody   string
+	}{
+		{
+			name: "bonuses",
+			bonuses: []*models.CampaignBonus{
+				{ID: 2, Campaign: "weekend", OrderNumber: "12345678903", Amount: 120.5, CreatedAt: created},
+				{ID: 1, Campaign: "welcome", OrderNumber: "2377225624", Amount: 50, CreatedAt: created, ReversedAt: &reversed},
+			},
+			wantStatus: http.StatusOK,
+			wantBody: `[{"id":2,"campaign":"weekend","order":"12345678903","amount":120.5,"created_at":"2026-10-24T12:00:00Z"},` +
+				`{"id":1,"campaign":"welcome","order":"2377225624","amount":50,"created_at":"2026-10-24T12:00:00Z","reversed_at":"2026-10-24T13:00:00Z"}]`,
+		},
+		{
+			name:       "no bonuses",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "internal error",
+			result:     errors.New("db down"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   problem.CodeInternal,
+		},
+		{
+			name:       "unauthenticated",
+			anonymous:  true,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   problem.CodeUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.campaigns.bonuses = func(_ context.Context, userID int) ([]*models.CampaignBonus, error) {
+				if userID != testUserID {
+					t.Errorf("userID = %d, want %d", userID, testUserID)
+				}
+				return tt.bonuses, tt.result
+			}
+
+			rec := env.do(t, testRequest{method: http.MethodGet, path: "/api/user/bonuses", anonymous: tt.anonymous})
+
+			if tt.wantCode != "" {
+				assertProblem(t, rec, tt.wantStatus, tt.wantCode)
+				return
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/problem"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+	"github.com/sirupsen/logrus"
+)
+
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+// serviceErrors сопоставляет ошибки сервисного слоя ответам API.
+var serviceErrors = []struct {
+	target error
+	apiError
+}{
+	{service.ErrUserExists, apiError{http.StatusConflict, problem.CodeUserExists, "Login already exists"}},
+	{service.ErrInvalidCredentials, apiError{http.StatusUnauthorized, problem.CodeInvalidCredentials, "Invalid login or password"}},
+	{service.ErrUnknownReferrer, apiError{http.StatusUnprocessableEntity, problem.CodeUnknownReferrer, "Unknown referral code"}},
+	{service.ErrInvalidOrderNumber, apiError{http.StatusUnprocessableEntity, problem.CodeInvalidOrderNumber, "Invalid order number"}},
+	{service.ErrOrderConflict, apiError{http.StatusConflict, problem.CodeOrderConflict, "Order already uploaded by another user"}},
+	{service.ErrEmptyOrderBatch, apiError{http.StatusBadRequest, problem.CodeEmptyOrderBatch, "Order numbers are required"}},
+	{service.ErrOrderBatchTooLarge, apiError{http.StatusRequestEntityTooLarge, problem.CodeOrderBatchTooLarge, "Too many order numbers"}},
+	{service.ErrInvalidWithdrawalOrder, apiError{http.StatusUnprocessableEntity, problem.CodeInvalidWithdrawalOrder, "Invalid order number"}},
+	{service.ErrInvalidWithdrawalSum, apiError{http.StatusUnprocessableEntity, problem.CodeInvalidWithdrawalSum, "Withdrawal sum must be positive"}},
+	{service.ErrWithdrawalSumLimit, apiError{http.StatusUnprocessableEntity, problem.CodeWithdrawalSumLimit, "Withdrawal sum exceeds the limit"}},
+	{service.ErrWithdrawalDailyLimit, apiError{http.StatusTooManyRequests, problem.CodeWithdrawalDailyLimit, "Daily withdrawal limit exceeded"}},
+	{service.ErrWithdrawalMinBalance, apiError{http.StatusPaymentRequired, problem.CodeWithdrawalMinBalance, "Withdrawal would leave less than the minimum balance"}},
+	{service.ErrWithdrawalRateExceeded, apiError{http.StatusTooManyRequests, problem.CodeWithdrawalRateExceeded, "Too many withdrawals, try again later"}},
+	{service.ErrInvalidTransferSum, apiError{http.StatusUnprocessableEntity, problem.CodeInvalidTransferSum, "Transfer sum must be positive"}},
+	{service.ErrUnknownRecipient, apiError{http.StatusUnprocessableEntity, problem.CodeUnknownRecipient, "Unknown recipient"}},
+	{service.ErrSelfTransfer, apiError{http.StatusUnprocessableEntity, problem.CodeSelfTransfer, "Cannot transfer points to yourself"}},
+	{service.ErrTransferLimitExceeded, apiError{http.StatusTooManyRequests, problem.CodeTransferLimitExceeded, "Daily transfer limit exceeded"}},
+	{service.ErrInsufficientFunds, apiError{http.StatusPaymentRequired, problem.CodeInsufficientFunds, "Insufficient funds"}},
+	{service.ErrOrderNotFound, apiError{http.StatusNotFound, problem.CodeOrderNotFound, "Order not found"}},
+	{service.ErrInvalidOrderGoods, apiError{http.StatusBadRequest, problem.CodeInvalidOrderGoods, "Invalid order goods"}},
+	{service.ErrOrderAlreadyRegistered, apiError{http.StatusConflict, problem.CodeOrderRegistered, "Order already registered"}},
+}
+
+var errInternal = apiError{http.StatusInternalServerError, problem.CodeInternal, "Internal server error"}
+
+// lookupError возвращает ответ API для известной ошибки.
+func lookupError(err error) (apiError, bool) {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		return apiError{reqErr.status, reqErr.code, reqErr.message}, true
+	}
+
+	for _, e := range serviceErrors {
+		if errors.Is(err, e.target) {
+			return e.apiError, true
+		}
+	}
+
+	return apiError{}, false
+}
+
+// logError логирует ошибку обработки запроса перед ответом. Ошибки клиента
+// (4xx) пишутся на уровне Debug, чтобы некорректные запросы не засоряли
+// оповещения; уровень Error остаётся за ошибками сервера.
+func logError(logger logrus.FieldLogger, err error, msg string) {
+	if e, ok := lookupError(err); ok && e.status < http.StatusInternalServerError {
+		logger.WithError(err).Debug(msg)
+		return
+	}
+	logger.WithError(err).Error(msg)
+}
+
+// writeError отвечает клиенту problem+json, соответствующим ошибке.
+// Неизвестные ошибки превращаются в 500 без раскрытия деталей.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	e, ok := lookupError(err)
+	if !ok {
+		e = errInternal
+	}
+	problem.Write(w, r, e.status, e.code, e.message)
+}
+
+func writeUnauthorized(w http.ResponseWriter, r *http.Request) {
+	problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "Unauthorized")
+}
+
+func notFound(w http.ResponseWriter, r *http.Request) {
+	problem.Write(w, r, http.StatusNotFound, problem.CodeNotFound, "Resource not found")
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	problem.Write(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "Method not allowed")
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestLogError_Levels(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want logrus.Level
+	}{
+		{name: "bad request", err: badRequest("Invalid JSON", errors.New("unexpected EOF")), want: logrus.DebugLevel},
+		{name: "luhn failure", err: service.ErrInvalidOrderNumber, want: logrus.DebugLevel},
+		{name: "wrapped client error", err: fmt.Errorf("upload: %w", service.ErrOrderConflict), want: logrus.DebugLevel},
+		{name: "server error", err: errors.New("db down"), want: logrus.ErrorLevel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, hook := test.NewNullLogger()
+			logger.SetLevel(logrus.DebugLevel)
+
+			logError(logger, tt.err, "Failed")
+
+			if entry := hook.LastEntry(); entry == nil || entry.Level != tt.want {
+				t.Errorf("entry = %+v, want level %s", entry, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/middleware"
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+	"github.com/go-chi/chi"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeAuthService struct {
+	register func(ctx context.Context, creds *models.UserCredentials) (*models.User, error)
+	login    func(ctx context.Context, creds *models.UserCredentials) (*models.User, error)
+}
+
+func (f *fakeAuthService) Register(ctx context.Context, creds *models.UserCredentials) (*models.User, error) {
+	return f.register(ctx, creds)
+}
+
+func (f *fakeAuthService) Login(ctx context.Context, creds *models.UserCredentials) (*models.User, error) {
+	return f.login(ctx, creds)
+}
+
+type fakeOrderService struct {
+	service.OrderService
+	upload      func(ctx context.Context, number string, userID int) (*models.Order, error)
+	uploadBatch func(ctx context.Context, numbers []string, userID int) ([]*models.OrderUploadResult, error)
+	list        func(ctx context.Context, userID int) ([]*models.Order, error)
+}
+
+func (f *fakeOrderService) UploadOrder(ctx context.Context, number string, userID int) (*models.Order, error) {
+	return f.upload(ctx, number, userID)
+}
+
+func (f *fakeOrderService) UploadOrders(ctx context.Context, numbers []string, userID int) ([]*models.OrderUploadResult, error) {
+	return f.uploadBatch(ctx, numbers, userID)
+}
+
+func (f *fakeOrderService) GetUserOrders(ctx context.Context, userID int) ([]*models.Order, error) {
+	return f.list(ctx, userID)
+}
+
+type fakeBalanceService struct {
+	service.BalanceService
+	get func(ctx context.Context, userID int) (*models.Balance, error)
+}
+
+func (f *fakeBalanceService) GetBalance(ctx context.Context, userID int) (*models.Balance, error) {
+	return f.get(ctx, userID)
+}
+
+// fakeTierService без заданного status ведёт себя как пользователь без
+// уровня: в балансе уровень не выводится.
+type fakeTierService struct {
+	service.TierService
+	status func(ctx context.Context, userID int) (*models.TierStatus, error)
+}
+
+func (f *fakeTierService) GetStatus(ctx context.Context, userID int) (*models.TierStatus, error) {
+	if f.status == nil {
+		return nil, nil
+	}
+	return f.status(ctx, userID)
+}
+
+type fakeCampaignService struct {
+	service.CampaignService
+	bonuses func(ctx context.Context, userID int) ([]*models.CampaignBonus, error)
+}
+
+func (f *fakeCampaignService) GetBonuses(ctx context.Context, userID int) ([]*models.CampaignBonus, error) {
+	return f.bonuses(ctx, userID)
+}
+
+type fakeReferralService struct {
+	service.ReferralService
+	status func(ctx context.Context, userID int) (*models.ReferralStatus, error)
+}
+
+func (f *fakeReferralService) GetStatus(ctx context.Context, userID int) (*models.ReferralStatus, error) {
+	return f.status(ctx, userID)
+}
+
+type fakeWithdrawalService struct {
+	service.WithdrawalService
+	withdraw func(ctx context.Context, userID int, req *models.WithdrawalRequest) (*models.Withdrawal, error)
+	list     func(ctx context.Context, userID int) ([]*models.Withdrawal, error)
+}
+
+func (f *fakeWithdrawalService) Withdraw(ctx context.Context, userID int, req *models.WithdrawalRequest) (*models.Withdrawal, error) {
+	return f.withdraw(ctx, userID, req)
+}
+
+func (f *fakeWithdrawalService) GetWithdrawals(ctx context.Context, userID int) ([]*models.Withdrawal, error) {
+	return f.list(ctx, userID)
+}
+
+type fakeTransferService struct {
+	service.TransferService
+	transfer func(ctx context.Context, userID int, req *models.TransferRequest) (*models.Transfer, error)
+	list     func(ctx context.Context, userID int) ([]*models.Transfer, error)
+}
+
+func (f *fakeTransferService) Transfer(ctx context.Context, userID int, req *models.TransferRequest) (*models.Transfer, error) {
+	return f.transfer(ctx, userID, req)
+}
+
+func (f *fakeTransferService) GetTransfers(ctx context.Context, userID int) ([]*models.Transfer, error) {
+	return f.list(ctx, userID)
+}
+
+type fakeAccrualProcessor struct {
+	apply func(ctx context.Context, result *models.AccrualResult) error
+}
+
+func (f *fakeAccrualProcessor) ApplyAccrual(ctx context.Context, result *models.AccrualResult) error {
+	return f.apply(ctx, result)
+}
+
+type fakeRewardService struct {
+	service.RewardService
+	register func(ctx context.Context, number string, goods []models.OrderGood) (*models.RegisteredOrder, error)
+}
+
+func (f *fakeRewardService) RegisterOrder(ctx context.Context, number string, goods []models.OrderGood) (*models.RegisteredOrder, error) {
+	return f.register(ctx, number, goods)
+}
+
+const (
+	testUserID        = 42
+	testWebhookSecret = "test-webhook-secret"
+	testPartnerSecret = "test-partner-secret"
+)
+
+type testEnv struct {
+	auth        *fakeAuthService
+	orders      *fakeOrderService
+	balance     *fakeBalanceService
+	tiers       *fakeTierService
+	withdrawals *fakeWithdrawalService
+	transfers   *fakeTransferService
+	campaigns   *fakeCampaignService
+	referrals   *fakeReferralService
+	accrual     *fakeAccrualProcessor
+	rewards     *fakeRewardService
+	jwt         service.JWTService
+	router      *chi.Mux
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	env := &testEnv{
+		auth:        &fakeAuthService{},
+		orders:      &fakeOrderService{},
+		balance:     &fakeBalanceService{},
+		tiers:       &fakeTierService{},
+		withdrawals: &fakeWithdrawalService{},
+		transfers:   &fakeTransferService{},
+		campaigns:   &fakeCampaignService{},
+		referrals:   &fakeReferralService{},
+		accrual:     &fakeAccrualProcessor{},
+		rewards:     &fakeRewardService{},
+		jwt:         service.NewJWTService("test-secret", time.Hour),
+	}
+	env.router = NewRouter(
+		NewAuthHandler(env.auth, env.jwt, logger),
+		NewOrderHandler(env.orders, logger),
+		NewBalanceHandler(env.balance, env.tiers, logger),
+		NewWithdrawalHandler(env.withdrawals, logger),
+		NewTransferHandler(env.transfers, logger),
+		NewBonusHandler(env.campaigns, logger),
+		NewReferralHandler(env.referrals, logger),
+		NewAccrualHandler(env.accrual, testWebhookSecret, logger),
+		NewPartnerHandler(env.rewards, testPartnerSecret, logger),
+		middleware.NewAuthMiddleware(env.jwt),
+	)
+	return env
+}
+
+type testRequest struct {
+	method      string
+	path        string
+	contentType string
+	body        string
+	headers     map[string]string
+	anonymous   bool
+}
+
+func (e *testEnv) do(t *testing.T, req testRequest) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest(req.method, req.path, strings.NewReader(req.body))
+	if req.contentType != "" {
+		r.Header.Set("Content-Type", req.contentType)
+	}
+	for name, value := range req.headers {
+		r.Header.Set(name, value)
+	}
+	if !req.anonymous {
+		token, err := e.jwt.GenerateToken(testUserID)
+		if err != nil {
+			t.Fatalf("generate token: %v", err)
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	e.router.ServeHTTP(rec, r)
+	return rec
+}
+
+// assertProblem проверяет, что ответ — problem+json с ожидаемым кодом ошибки.
+func assertProblem(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, status, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("Content-Type = %q, want application/problem+json", ct)
+	}
+
+	p := decodeBody[models.Problem](t, rec)
+	if p.Code != code {
+		t.Errorf("problem code = %q, want %q", p.Code, code)
+	}
+	if p.Status != status {
+		t.Errorf("problem status = %d, want %d", p.Status, status)
+	}
+	if p.RequestID == "" {
+		t.Error("problem request_id is empty")
+	}
+}
+
+func decodeBody[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var v T
+	if err := unmarshalStrict(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode response body %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
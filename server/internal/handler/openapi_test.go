@@ -0,0 +1,314 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+	"github.com/go-chi/chi"
+)
+
+type openAPIDoc struct {
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components struct {
+		Responses map[string]openAPIResponse `json:"responses"`
+		Schemas   map[string]*schema         `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	Responses map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIResponse struct {
+	Ref     string `json:"$ref"`
+	Content map[string]struct {
+		Schema *schema `json:"schema"`
+	} `json:"content"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	Enum                 []string           `json:"enum"`
+}
+
+func loadOpenAPI(t *testing.T) *openAPIDoc {
+	t.Helper()
+
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("parse openapi.json: %v", err)
+	}
+	return &doc
+}
+
+func (d *openAPIDoc) response(path, method string, status int) (openAPIResponse, bool) {
+	op, ok := d.Paths[path][strings.ToLower(method)]
+	if !ok {
+		return openAPIResponse{}, false
+	}
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		return openAPIResponse{}, false
+	}
+	if resp.Ref != "" {
+		resp, ok = d.Components.Responses[strings.TrimPrefix(resp.Ref, "#/components/responses/")]
+	}
+	return resp, ok
+}
+
+func (d *openAPIDoc) validate(s *schema, v any, at string) error {
+	if s.Ref != "" {
+		ref, ok := d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema %s", at, s.Ref)
+		}
+		s = ref
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %T", at, v)
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", at, name)
+			}
+		}
+		for name, value := range obj {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s: unexpected property %q", at, name)
+				}
+				continue
+			}
+			if err := d.validate(prop, value, at+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %T", at, v)
+		}
+		for i, item := range arr {
+			if err := d.validate(s.Items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string, got %T", at, v)
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			return fmt.Errorf("%s: %q not in enum %v", at, str, s.Enum)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return fmt.Errorf("%s: invalid date-time: %w", at, err)
+			}
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s: expected number, got %T", at, v)
+		}
+	case "integer":
+		n, ok := v.(float64)
+		if !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%s: expected integer, got %v", at, v)
+		}
+	}
+
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// TestOpenAPI_CoversRoutes проверяет, что каждый маршрут роутера описан в
+// спецификации и в спецификации нет лишних маршрутов.
+func TestOpenAPI_CoversRoutes(t *testing.T) {
+	doc := loadOpenAPI(t)
+	env := newTestEnv(t)
+
+	registered := make(map[string]bool)
+	err := chi.Walk(env.router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		key := strings.ToLower(method) + " " + route
+		registered[key] = true
+		if _, ok := doc.Paths[route][strings.ToLower(method)]; !ok {
+			t.Errorf("route %s %s is not described in openapi.json", method, route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk routes: %v", err)
+	}
+
+	var described []string
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			described = append(described, method+" "+path)
+		}
+	}
+	sort.Strings(described)
+	for _, key := range described {
+		if !registered[key] {
+			t.Errorf("openapi.json describes %s, but it is not registered", key)
+		}
+	}
+}
+
+// TestOpenAPI_ResponsesMatchSchema прогоняет запросы через роутер и проверяет
+// коды, типы содержимого и тела ответов по спецификации для всех описанных
+// операций.
+func TestOpenAPI_ResponsesMatchSchema(t *testing.T) {
+	doc := loadOpenAPI(t)
+	env := newTestEnv(t)
+
+	accrual := 500.0
+	now := time.Now()
+	user := &models.User{ID: testUserID, Login: "alice"}
+	env.auth.register = func(context.Context, *models.UserCredentials) (*models.User, error) { return user, nil }
+	env.auth.login = func(context.Context, *models.UserCredentials) (*models.User, error) {
+		return nil, service.ErrInvalidCredentials
+	}
+	env.orders.upload = func(context.Context, string, int) (*models.Order, error) { return nil, service.ErrOrderConflict }
+	env.orders.uploadBatch = func(_ context.Context, numbers []string, _ int) ([]*models.OrderUploadResult, error) {
+		return []*models.OrderUploadResult{{Number: numbers[0], Result: models.OrderUploadAccepted}}, nil
+	}
+	env.orders.list = func(context.Context, int) ([]*models.Order, error) {
+		return []*models.Order{
+			{Number: "9278923470", Status: models.OrderStatusProcessed, Accrual: &accrual, UploadedAt: now},
+			{Number: "12345678903", Status: models.OrderStatusNew, UploadedAt: now},
+		}, nil
+	}
+	env.balance.get = func(context.Context, int) (*models.Balance, error) {
+		return &models.Balance{Current: 500.5, Withdrawn: 42, Expiring: []*models.PointsExpiry{{Amount: 100, ExpiresAt: now}}}, nil
+	}
+	env.tiers.status = func(context.Context, int) (*models.TierStatus, error) {
+		return &models.TierStatus{Name: "silver", Multiplier: 1.1, Amount: 1200, Next: &models.TierNext{Name: "gold", Threshold: 5000}}, nil
+	}
+	env.campaigns.bonuses = func(context.Context, int) ([]*models.CampaignBonus, error) {
+		return []*models.CampaignBonus{{ID: 1, Campaign: "weekend", OrderNumber: "9278923470", Amount: 100, CreatedAt: now, ReversedAt: &now}}, nil
+	}
+	env.referrals.status = func(context.Context, int) (*models.ReferralStatus, error) {
+		return &models.ReferralStatus{Code: "K7MXQ2PA", Invited: 3, Rewarded: 2, Earned: 200}, nil
+	}
+	env.withdrawals.withdraw = func(context.Context, int, *models.WithdrawalRequest) (*models.Withdrawal, error) {
+		return nil, service.ErrInsufficientFunds
+	}
+	env.withdrawals.list = func(context.Context, int) ([]*models.Withdrawal, error) {
+		return []*models.Withdrawal{{OrderNumber: "2377225624", Sum: 500, ProcessedAt: now}}, nil
+	}
+	env.transfers.transfer = func(context.Context, int, *models.TransferRequest) (*models.Transfer, error) {
+		return nil, service.ErrTransferLimitExceeded
+	}
+	env.transfers.list = func(context.Context, int) ([]*models.Transfer, error) {
+		return []*models.Transfer{{ID: 7, From: "bob", To: "alice", Sum: 150, Direction: models.TransferIn, CreatedAt: now}}, nil
+	}
+	env.accrual.apply = func(context.Context, *models.AccrualResult) error { return service.ErrOrderNotFound }
+	env.rewards.register = func(context.Context, string, []models.OrderGood) (*models.RegisteredOrder, error) {
+		return &models.RegisteredOrder{Number: "12345678903", Accrual: 700}, nil
+	}
+
+	requests := []testRequest{
+		{method: http.MethodGet, path: "/api/openapi.json", anonymous: true},
+		{method: http.MethodPost, path: "/api/user/register", contentType: "application/json", body: `{"login":"alice","password":"secret"}`, anonymous: true},
+		{method: http.MethodPost, path: "/api/user/register", contentType: "text/plain", body: "alice", anonymous: true},
+		{method: http.MethodPost, path: "/api/user/login", contentType: "application/json", body: `{"login":"alice","password":"wrong"}`, anonymous: true},
+		{method: http.MethodPost, path: "/api/user/orders", contentType: "text/plain", body: "12345678903"},
+		{method: http.MethodPost, path: "/api/user/orders", contentType: "text/plain", body: "12345678903", anonymous: true},
+		{method: http.MethodPost, path: "/api/user/orders/batch", contentType: "application/json", body: `["12345678903"]`},
+		{method: http.MethodGet, path: "/api/user/orders"},
+		{method: http.MethodGet, path: "/api/user/balance"},
+		{method: http.MethodGet, path: "/api/user/bonuses"},
+		{method: http.MethodGet, path: "/api/user/referral"},
+		{method: http.MethodPost, path: "/api/user/balance/withdraw", contentType: "application/json", body: `{"order":"2377225624","sum":751}`},
+		{method: http.MethodPost, path: "/api/user/balance/withdraw", contentType: "application/json", body: `{"order":1}`},
+		{method: http.MethodGet, path: "/api/user/withdrawals"},
+		{method: http.MethodPost, path: "/api/user/balance/transfer", contentType: "application/json", body: `{"to":"bob","sum":150}`},
+		{method: http.MethodGet, path: "/api/user/balance/transfers"},
+		signedNotification(testWebhookSecret, now, `{"order":"12345678903","status":"PROCESSED","accrual":500}`),
+		signedNotification("other-webhook-secret", now, `{"order":"12345678903","status":"PROCESSED"}`),
+		signedRegistration(testPartnerSecret, now, `{"order":"12345678903","goods":[{"description":"Чайник Bork","price":7000}]}`),
+		signedRegistration(testPartnerSecret, now, `{"order":"12345678903"`),
+	}
+
+	exercised := make(map[string]bool)
+	for _, req := range requests {
+		exercised[strings.ToLower(req.method)+" "+req.path] = true
+		t.Run(req.method+" "+req.path, func(t *testing.T) {
+			rec := env.do(t, req)
+			checkResponse(t, doc, req, rec)
+		})
+	}
+
+	// Каждая описанная операция должна проверяться хотя бы одним запросом,
+	// иначе её ответы могут разойтись со спецификацией незаметно.
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			if !exercised[method+" "+path] {
+				t.Errorf("%s %s has no request validated against the schema", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func checkResponse(t *testing.T, doc *openAPIDoc, req testRequest, rec *httptest.ResponseRecorder) {
+	t.Helper()
+
+	resp, ok := doc.response(req.path, req.method, rec.Code)
+	if !ok {
+		t.Fatalf("status %d is not described for %s %s", rec.Code, req.method, req.path)
+	}
+
+	if len(resp.Content) == 0 {
+		if rec.Body.Len() != 0 {
+			t.Errorf("unexpected body for status %d: %s", rec.Code, rec.Body.String())
+		}
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("parse Content-Type: %v", err)
+	}
+	content, ok := resp.Content[mediaType]
+	if !ok {
+		t.Fatalf("Content-Type %q is not described for status %d", mediaType, rec.Code)
+	}
+
+	var body any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if err := doc.validate(content.Schema, body, "body"); err != nil {
+		t.Error(err)
+	}
+}
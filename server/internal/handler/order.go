@@ -3,7 +3,6 @@ package handler
 import (
 	"encoding/json"
 	"errors"
-	"io"
 	"net/http"
 
 	"github.com/RoGogDBD/loyalty_service/server/internal/middleware"
@@ -25,58 +24,72 @@ func NewOrderHandler(orderService service.OrderService, logger *logrus.Logger) *
 func (h *OrderHandler) UploadOrder(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeUnauthorized(w, r)
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
+	orderNumber, err := readOrderNumber(w, r)
 	if err != nil {
-		h.logger.WithError(err).Error("Failed to read request body")
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
-		return
-	}
-	defer func() {
-		if err := r.Body.Close(); err != nil {
-			h.logger.WithError(err).Error("Failed to close request body")
-		}
-	}()
-
-	orderNumber := string(body)
-	if orderNumber == "" {
-		http.Error(w, "Order number is required", http.StatusBadRequest)
+		logError(h.logger, err, "Failed to read order number")
+		writeError(w, r, err)
 		return
 	}
 
 	_, err = h.orderService.UploadOrder(r.Context(), orderNumber, userID)
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrOrderExists):
+		if errors.Is(err, service.ErrOrderExists) {
 			w.WriteHeader(http.StatusOK)
-		case errors.Is(err, service.ErrOrderConflict):
-			w.WriteHeader(http.StatusConflict)
-		case errors.Is(err, service.ErrInvalidOrderNumber):
-			w.WriteHeader(http.StatusUnprocessableEntity) // 422
-		default:
-			h.logger.WithError(err).Error("Failed to upload order")
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
 		}
+		logError(h.logger, err, "Failed to upload order")
+		writeError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusAccepted)
 }
 
+// UploadOrdersBatch принимает пакет номеров заказов: JSON-массив строк
+// (Content-Type: application/json) или список номеров по одному в строке.
+func (h *OrderHandler) UploadOrdersBatch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeUnauthorized(w, r)
+		return
+	}
+
+	numbers, err := readOrderNumbers(w, r)
+	if err != nil {
+		logError(h.logger, err, "Failed to decode order batch")
+		writeError(w, r, err)
+		return
+	}
+
+	results, err := h.orderService.UploadOrders(r.Context(), numbers, userID)
+	if err != nil {
+		logError(h.logger, err, "Failed to upload orders")
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		h.logger.WithError(err).Error("Failed to encode response")
+	}
+}
+
 func (h *OrderHandler) GetOrders(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeUnauthorized(w, r)
 		return
 	}
 
 	orders, err := h.orderService.GetUserOrders(r.Context(), userID)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get orders")
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -88,7 +101,6 @@ func (h *OrderHandler) GetOrders(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(orders); err != nil {
-		http.Error(w, "failed to encode response", http.StatusInternalServerError)
-		return
+		h.logger.WithError(err).Error("Failed to encode response")
 	}
 }
@@ -0,0 +1,314 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/problem"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+)
+
+func TestOrderHandler_UploadOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		anonymous   bool
+		result      error
+		wantNumber  string
+		wantStatus  int
+		wantCode    string
+	}{
+		{
+			name:        "accepted",
+			contentType: "text/plain",
+			body:        "12345678903",
+			wantNumber:  "12345678903",
+			wantStatus:  http.StatusAccepted,
+		},
+		{
+			name:        "whitespace trimmed",
+			contentType: "text/plain; charset=utf-8",
+			body:        "  12345678903\n",
+			wantNumber:  "12345678903",
+			wantStatus:  http.StatusAccepted,
+		},
+		{
+			name:        "already uploaded by user",
+			contentType: "text/plain",
+			body:        "12345678903",
+			result:      service.ErrOrderExists,
+			wantNumber:  "12345678903",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "uploaded by another user",
+			contentType: "text/plain",
+			body:        "12345678903",
+			result:      service.ErrOrderConflict,
+			wantNumber:  "12345678903",
+			wantStatus:  http.StatusConflict,
+			wantCode:    problem.CodeOrderConflict,
+		},
+		{
+			name:        "invalid number",
+			contentType: "text/plain",
+			body:        "12345678900",
+			result:      service.ErrInvalidOrderNumber,
+			wantNumber:  "12345678900",
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    problem.CodeInvalidOrderNumber,
+		},
+		{
+			name:        "internal error",
+			contentType: "text/plain",
+			body:        "12345678903",
+			result:      errors.New("db down"),
+			wantNumber:  "12345678903",
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    problem.CodeInternal,
+		},
+		{
+			name:        "empty body",
+			contentType: "text/plain",
+			body:        " \n",
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    problem.CodeInvalidRequest,
+		},
+		{
+			name:        "json content type",
+			contentType: "application/json",
+			body:        `"12345678903"`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantCode:    problem.CodeUnsupportedMediaType,
+		},
+		{
+			name:        "body too large",
+			contentType: "text/plain",
+			body:        strings.Repeat("1", maxOrderBodySize+1),
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantCode:    problem.CodePayloadTooLarge,
+		},
+		{
+			name:        "unauthenticated",
+			contentType: "text/plain",
+			body:        "12345678903",
+			anonymous:   true,
+			wantStatus:  http.StatusUnauthorized,
+			wantCode:    problem.CodeUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			called := false
+			env.orders.upload = func(_ context.Context, number string, userID int) (*models.Order, error) {
+				called = true
+				if number != tt.wantNumber {
+					t.Errorf("number = %q, want %q", number, tt.wantNumber)
+				}
+				if userID != testUserID {
+					t.Errorf("userID = %d, want %d", userID, testUserID)
+				}
+				if tt.result != nil {
+					return nil, tt.result
+				}
+				return &models.Order{Number: number, UserID: userID, Status: models.OrderStatusNew}, nil
+			}
+
+			rec := env.do(t, testRequest{
+				method:      http.MethodPost,
+				path:        "/api/user/orders",
+				contentType: tt.contentType,
+				body:        tt.body,
+				anonymous:   tt.anonymous,
+			})
+
+			if called != (tt.wantNumber != "") {
+				t.Errorf("service called = %v, want %v", called, tt.wantNumber != "")
+			}
+			if tt.wantCode != "" {
+				assertProblem(t, rec, tt.wantStatus, tt.wantCode)
+				return
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestOrderHandler_UploadOrdersBatch(t *testing.T) {
+	results := []*models.OrderUploadResult{
+		{Number: "12345678903", Result: models.OrderUploadAccepted},
+		{Number: "9278923470", Result: models.OrderUploadConflict},
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		result      error
+		wantNumbers []string
+		wantStatus  int
+		wantCode    string
+	}{
+		{
+			name:        "json array",
+			contentType: "application/json",
+			body:        `["12345678903", " 9278923470 "]`,
+			wantNumbers: []string{"12345678903", "9278923470"},
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "newline separated",
+			contentType: "text/plain",
+			body:        "12345678903\r\n\n9278923470\n",
+			wantNumbers: []string{"12345678903", "9278923470"},
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "json object rejected",
+			contentType: "application/json",
+			body:        `{"orders":["12345678903"]}`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    problem.CodeInvalidRequest,
+		},
+		{
+			name:        "empty batch",
+			contentType: "text/plain",
+			body:        "\n",
+			result:      service.ErrEmptyOrderBatch,
+			wantNumbers: []string{},
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    problem.CodeEmptyOrderBatch,
+		},
+		{
+			name:        "batch too large",
+			contentType: "application/json",
+			body:        `["12345678903"]`,
+			result:      service.ErrOrderBatchTooLarge,
+			wantNumbers: []string{"12345678903"},
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantCode:    problem.CodeOrderBatchTooLarge,
+		},
+		{
+			name:        "unsupported content type",
+			contentType: "application/xml",
+			body:        "<orders/>",
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantCode:    problem.CodeUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			called := false
+			env.orders.uploadBatch = func(_ context.Context, numbers []string, userID int) ([]*models.OrderUploadResult, error) {
+				called = true
+				if len(numbers) != 0 || len(tt.wantNumbers) != 0 {
+					if !reflect.DeepEqual(numbers, tt.wantNumbers) {
+						t.Errorf("numbers = %q, want %q", numbers, tt.wantNumbers)
+					}
+				}
+				if userID != testUserID {
+					t.Errorf("userID = %d, want %d", userID, testUserID)
+				}
+				if tt.result != nil {
+					return nil, tt.result
+				}
+				return results, nil
+			}
+
+			rec := env.do(t, testRequest{
+				method:      http.MethodPost,
+				path:        "/api/user/orders/batch",
+				contentType: tt.contentType,
+				body:        tt.body,
+			})
+
+			if called != (tt.wantNumbers != nil) {
+				t.Errorf("service called = %v, want %v", called, tt.wantNumbers != nil)
+			}
+			if tt.wantCode != "" {
+				assertProblem(t, rec, tt.wantStatus, tt.wantCode)
+				return
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := decodeBody[[]*models.OrderUploadResult](t, rec); !reflect.DeepEqual(got, results) {
+				t.Errorf("results = %+v, want %+v", got, results)
+			}
+		})
+	}
+}
+
+func TestOrderHandler_GetOrders(t *testing.T) {
+	accrual := 500.0
+	uploaded := time.Date(2020, 12, 10, 15, 15, 45, 0, time.FixedZone("MSK", 3*60*60))
+	orders := []*models.Order{
+		{Number: "9278923470", Status: models.OrderStatusProcessed, Accrual: &accrual, UploadedAt: uploaded},
+		{Number: "12345678903", Status: models.OrderStatusProcessing, UploadedAt: uploaded},
+	}
+
+	tests := []struct {
+		name       string
+		orders     []*models.Order
+		result     error
+		wantStatus int
+		wantCode   string
+		wantBody   string
+	}{
+		{
+			name:       "orders",
+			orders:     orders,
+			wantStatus: http.StatusOK,
+			wantBody: `[{"number":"9278923470","status":"PROCESSED","accrual":500,"uploaded_at":"2020-12-10T15:15:45+03:00"},` +
+				`{"number":"12345678903","status":"PROCESSING","uploaded_at":"2020-12-10T15:15:45+03:00"}]`,
+		},
+		{
+			name:       "no orders",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "internal error",
+			result:     errors.New("db down"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   problem.CodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.orders.list = func(_ context.Context, userID int) ([]*models.Order, error) {
+				if userID != testUserID {
+					t.Errorf("userID = %d, want %d", userID, testUserID)
+				}
+				return tt.orders, tt.result
+			}
+
+			rec := env.do(t, testRequest{method: http.MethodGet, path: "/api/user/orders"})
+
+			if tt.wantCode != "" {
+				assertProblem(t, rec, tt.wantStatus, tt.wantCode)
+				return
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/problem"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+	"github.com/sirupsen/logrus"
+)
+
+// Заголовки подписи запросов партнёров.
+const (
+	HeaderPartnerTimestamp = "X-Partner-Timestamp"
+	HeaderPartnerSignature = "X-Partner-Signature"
+)
+
+type PartnerHandler struct {
+	rewardService service.RewardService
+	verifier      *signatureVerifier
+	logger        *logrus.Logger
+}
+
+func NewPartnerHandler(rewardService service.RewardService, secret string, logger *logrus.Logger) *PartnerHandler {
+	return &PartnerHandler{
+		rewardService: rewardService,
+		verifier: &signatureVerifier{
+			secret:          secret,
+			timestampHeader: HeaderPartnerTimestamp,
+			signatureHeader: HeaderPartnerSignature,
+			now:             time.Now,
+		},
+		logger: logger,
+	}
+}
+
+// SignPartnerRequest вычисляет значение заголовка X-Partner-Signature по той
+// же схеме, что и SignAccrualNotification.
+func SignPartnerRequest(secret string, timestamp int64, body []byte) string {
+	return signBody(secret, timestamp, body)
+}
+
+// RegisterOrder принимает от партнёра состав заказа для встроенного расчёта
+// начислений.
+func (h *PartnerHandler) RegisterOrder(w http.ResponseWriter, r *http.Request) {
+	body, _, err := readBody(w, r, maxJSONBodySize, contentTypeJSON)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.verifier.verify(r.Header, body); err != nil {
+		h.logger.WithError(err).Warn("Rejected partner request")
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidSignature, "Invalid request signature")
+		return
+	}
+
+	var req models.OrderRegistration
+	if err := unmarshalStrict(body, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	req.Order = strings.TrimSpace(req.Order)
+	if req.Order == "" {
+		writeError(w, r, badRequest("Order number is required", nil))
+		return
+	}
+
+	order, err := h.rewardService.RegisterOrder(r.Context(), req.Order, req.Goods)
+	if err != nil {
+		logError(h.logger.WithField("orderNumber", req.Order), err, "Failed to register order")
+		writeError(w, r, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"orderNumber": order.Number,
+		"accrual":     order.Accrual,
+	}).Info("Partner order registered")
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/problem"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+)
+
+// signedRegistration собирает запрос регистрации заказа, подписанный secret в момент at.
+func signedRegistration(secret string, at time.Time, body string) testRequest {
+	timestamp := at.Unix()
+	return testRequest{
+		method:      http.MethodPost,
+		path:        "/api/partner/orders",
+		contentType: "application/json",
+		body:        body,
+		anonymous:   true,
+		headers: map[string]string{
+			HeaderPartnerTimestamp: strconv.FormatInt(timestamp, 10),
+			HeaderPartnerSignature: SignPartnerRequest(secret, timestamp, []byte(body)),
+		},
+	}
+}
+
+func TestPartnerHandler_RegisterOrder(t *testing.T) {
+	const body = `{"order":"12345678903","goods":[{"description":"Чайник Bork","price":7000}]}`
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		req        testRequest
+		result     error
+		wantCalled bool
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "ok", req: signedRegistration(testPartnerSecret, now, body), wantCalled: true, wantStatus: http.StatusAccepted},
+		{name: "already registered", req: signedRegistration(testPartnerSecret, now, body), result: service.ErrOrderAlreadyRegistered,
+			wantCalled: true, wantStatus: http.StatusConflict, wantCode: problem.CodeOrderRegistered},
+		{name: "invalid number", req: signedRegistration(testPartnerSecret, now, body), result: service.ErrInvalidOrderNumber,
+			wantCalled: true, wantStatus: http.StatusUnprocessableEntity, wantCode: problem.CodeInvalidOrderNumber},
+		{name: "invalid goods", req: signedRegistration(testPartnerSecret, now, body), result: service.ErrInvalidOrderGoods,
+			wantCalled: true, wantStatus: http.StatusBadRequest, wantCode: problem.CodeInvalidOrderGoods},
+		{name: "webhook secret", req: signedRegistration(testWebhookSecret, now, body),
+			wantStatus: http.StatusUnauthorized, wantCode: problem.CodeInvalidSignature},
+		{name: "stale", req: signedRegistration(testPartnerSecret, now.Add(-10*time.Minute), body),
+			wantStatus: http.StatusUnauthorized, wantCode: problem.CodeInvalidSignature},
+		{name: "missing order", req: signedRegistration(testPartnerSecret, now, `{"goods":[]}`),
+			wantStatus: http.StatusBadRequest, wantCode: problem.CodeInvalidRequest},
+		{name: "accrual field", req: signedRegistration(testPartnerSecret, now, `{"order":"12345678903","goods":[],"accrual":100}`),
+			wantStatus: http.StatusBadRequest, wantCode: problem.CodeInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			var gotNumber string
+			var gotGoods []models.OrderGood
+			env.rewards.register = func(_ context.Context, number string, goods []models.OrderGood) (*models.RegisteredOrder, error) {
+				gotNumber, gotGoods = number, goods
+				if tt.result != nil {
+					return nil, tt.result
+				}
+				return &models.RegisteredOrder{Number: number, Goods: goods, Accrual: 700}, nil
+			}
+
+			rec := env.do(t, tt.req)
+
+			if tt.wantCode != "" {
+				assertProblem(t, rec, tt.wantStatus, tt.wantCode)
+			} else if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if (gotNumber != "") != tt.wantCalled {
+				t.Fatalf("service called = %v, want %v", gotNumber != "", tt.wantCalled)
+			}
+			if tt.wantCalled && (gotNumber != "12345678903" || len(gotGoods) != 1 || gotGoods[0].Price != 7000) {
+				t.Errorf("registered %q with %+v", gotNumber, gotGoods)
+			}
+		})
+	}
+}
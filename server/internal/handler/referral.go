@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/middleware"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+	"github.com/sirupsen/logrus"
+)
+
+type ReferralHandler struct {
+	referralService service.ReferralService
+	logger          *logrus.Logger
+}
+
+func NewReferralHandler(referralService service.ReferralService, logger *logrus.Logger) *ReferralHandler {
+	return &ReferralHandler{referralService: referralService, logger: logger}
+}
+
+// GetReferral возвращает реферальный код пользователя и итоги приглашений.
+func (h *ReferralHandler) GetReferral(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeUnauthorized(w, r)
+		return
+	}
+
+	status, err := h.referralService.GetStatus(r.Context(), userID)
+	if err != nil {
+		h.logger.WithError(err).WithField("userID", userID).Error("Failed to get referral status")
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		h.logger.WithError(err).Error("Failed to encode response")
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/problem"
+)
+
+func TestReferralHandler_GetReferral(t *testing.T) {
+	tests := []struct {
+		name       string
+		anonymous  bool
+		status     *models.ReferralStatus
+		result     error
+		wantStatus int
+		wantCode   string
+		wantBody   string
+	}{
+		{
+			name:       "status",
+			status:     &models.ReferralStatus{Code: "K7MXQ2PA", Invited: 3, Rewarded: 2, Earned: 200},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"code":"K7MXQ2PA","invited":3,"rewarded":2,"earned":200}`,
+		},
+		{
+			name:       "internal error",
+			result:     errors.New("db down"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   problem.CodeInternal,
+		},
+		{
+			name:       "unauthenticated",
+			anonymous:  true,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   problem.CodeUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.referrals.status = func(_ context.Context, userID int) (*models.ReferralStatus, error) {
+				if userID != testUserID {
+					t.Errorf("userID = %d, want %d", userID, testUserID)
+				}
+				return tt.status, tt.result
+			}
+
+			rec := env.do(t, testRequest{method: http.MethodGet, path: "/api/user/referral", anonymous: tt.anonymous})
+
+			if tt.wantCode != "" {
+				assertProblem(t, rec, tt.wantStatus, tt.wantCode)
+				return
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/problem"
+)
+
+// Ограничения размера тела запроса.
+const (
+	maxJSONBodySize       = 1 << 20
+	maxOrderBodySize      = 1 << 10
+	maxOrderBatchBodySize = 1 << 20
+)
+
+const (
+	contentTypeJSON = "application/json"
+	contentTypeText = "text/plain"
+)
+
+// requestError описывает ошибку разбора запроса и HTTP-код ответа на неё.
+type requestError struct {
+	status  int
+	code    string
+	message string
+	err     error
+}
+
+func (e *requestError) Error() string {
+	if e.err != nil {
+		return fmt.Sprintf("%s: %v", e.message, e.err)
+	}
+	return e.message
+}
+
+func (e *requestError) Unwrap() error { return e.err }
+
+func badRequest(message string, err error) *requestError {
+	return &requestError{status: http.StatusBadRequest, code: problem.CodeInvalidRequest, message: message, err: err}
+}
+
+// readBody проверяет Content-Type запроса и читает тело не длиннее maxBytes.
+// Возвращает совпавший тип содержимого.
+func readBody(w http.ResponseWriter, r *http.Request, maxBytes int64, allowed ...string) ([]byte, string, error) {
+	mediaType, err := requireContentType(r, allowed...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, "", &requestError{status: http.StatusRequestEntityTooLarge, code: problem.CodePayloadTooLarge, message: "Request body too large", err: err}
+		}
+		return nil, "", badRequest("Failed to read request body", err)
+	}
+
+	return body, mediaType, nil
+}
+
+func requireContentType(r *http.Request, allowed ...string) (string, error) {
+	header := r.Header.Get("Content-Type")
+	if header == "" {
+		return "", &requestError{status: http.StatusUnsupportedMediaType, code: problem.CodeUnsupportedMediaType, message: "Content-Type is required"}
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return "", &requestError{status: http.StatusUnsupportedMediaType, code: problem.CodeUnsupportedMediaType, message: "Invalid Content-Type", err: err}
+	}
+
+	for _, a := range allowed {
+		if mediaType == a {
+			return mediaType, nil
+		}
+	}
+
+	return "", &requestError{
+		status:  http.StatusUnsupportedMediaType,
+		code:    problem.CodeUnsupportedMediaType,
+		message: fmt.Sprintf("Unsupported Content-Type, expected %s", strings.Join(allowed, " or ")),
+	}
+}
+
+// decodeJSON разбирает тело application/json в dst. Неизвестные поля и данные
+// после JSON-значения считаются ошибкой формата.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	body, _, err := readBody(w, r, maxJSONBodySize, contentTypeJSON)
+	if err != nil {
+		return err
+	}
+	return unmarshalStrict(body, dst)
+}
+
+func unmarshalStrict(body []byte, dst any) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return badRequest("Invalid request format", err)
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return badRequest("Invalid request format", errors.New("unexpected data after JSON value"))
+	}
+
+	return nil
+}
+
+// readOrderNumber читает номер заказа из тела text/plain, обрезая пробелы.
+func readOrderNumber(w http.ResponseWriter, r *http.Request) (string, error) {
+	body, _, err := readBody(w, r, maxOrderBodySize, contentTypeText)
+	if err != nil {
+		return "", err
+	}
+
+	number := strings.TrimSpace(string(body))
+	if number == "" {
+		return "", badRequest("Order number is required", nil)
+	}
+
+	return number, nil
+}
+
+// readOrderNumbers читает пакет номеров: JSON-массив строк или список по
+// одному номеру в строке.
+func readOrderNumbers(w http.ResponseWriter, r *http.Request) ([]string, error) {
+	body, mediaType, err := readBody(w, r, maxOrderBatchBodySize, contentTypeJSON, contentTypeText)
+	if err != nil {
+		return nil, err
+	}
+
+	var numbers []string
+	if mediaType == contentTypeJSON {
+		var raw []string
+		if err := unmarshalStrict(body, &raw); err != nil {
+			return nil, err
+		}
+		for _, number := range raw {
+			numbers = append(numbers, strings.TrimSpace(number))
+		}
+		return numbers, nil
+	}
+
+	for _, line := range strings.Split(string(body), "\n") {
+		if number := strings.TrimSpace(line); number != "" {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers, nil
+}
@@ -11,14 +11,36 @@ func NewRouter(
 	orderHandler *OrderHandler,
 	balanceHandler *BalanceHandler,
 	withdrawalHandler *WithdrawalHandler,
+	transferHandler *TransferHandler,
+	bonusHandler *BonusHandler,
+	referralHandler *ReferralHandler,
+	accrualHandler *AccrualHandler,
+	partnerHandler *PartnerHandler,
 	authMiddleware *middleware.AuthMiddleware,
 ) *chi.Mux {
 	r := chi.NewRouter()
 
+	// Регистрируются до Use: иначе chi оборачивает их middleware повторно.
+	r.NotFound(notFound)
+	r.MethodNotAllowed(methodNotAllowed)
+
 	r.Use(chiMiddleware.RequestID)
 	r.Use(chiMiddleware.RealIP)
 	r.Use(chiMiddleware.Logger)
-	r.Use(chiMiddleware.Recoverer)
+	r.Use(middleware.Recoverer)
+
+	r.Get("/api/openapi.json", serveOpenAPI)
+
+	// Уведомления системы начислений аутентифицируются подписью, а не JWT.
+	// Без ключа подписи маршрут не регистрируется.
+	if accrualHandler != nil {
+		r.Post("/api/accrual/notifications", accrualHandler.Notify)
+	}
+	// Регистрация заказов партнёрами доступна при включённом встроенном
+	// расчёте начислений и тоже подписывается.
+	if partnerHandler != nil {
+		r.Post("/api/partner/orders", partnerHandler.RegisterOrder)
+	}
 
 	r.Route("/api/user", func(r chi.Router) {
 		r.Post("/register", authHandler.Register)
@@ -28,10 +50,15 @@ func NewRouter(
 			r.Use(authMiddleware.Authenticate)
 
 			r.Post("/orders", orderHandler.UploadOrder)
+			r.Post("/orders/batch", orderHandler.UploadOrdersBatch)
 			r.Get("/orders", orderHandler.GetOrders)
 			r.Get("/balance", balanceHandler.GetBalance)
 			r.Post("/balance/withdraw", withdrawalHandler.Withdraw)
 			r.Get("/withdrawals", withdrawalHandler.GetWithdrawals)
+			r.Post("/balance/transfer", transferHandler.Transfer)
+			r.Get("/balance/transfers", transferHandler.GetTransfers)
+			r.Get("/bonuses", bonusHandler.GetBonuses)
+			r.Get("/referral", referralHandler.GetReferral)
 		})
 	})
 
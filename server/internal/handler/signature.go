@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxSignatureAge ограничивает расхождение времени подписи с текущим, чтобы
+// перехваченный запрос нельзя было повторить позже.
+const maxSignatureAge = 5 * time.Minute
+
+// signBody вычисляет подпись HMAC-SHA256 от строки "<timestamp>.<body>" в hex
+// с префиксом "sha256=".
+func signBody(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// signatureVerifier проверяет подпись тела запроса из заголовков
+// timestampHeader и signatureHeader.
+type signatureVerifier struct {
+	secret          string
+	timestampHeader string
+	signatureHeader string
+	now             func() time.Time
+}
+
+func (v *signatureVerifier) verify(header http.Header, body []byte) error {
+	timestamp, err := strconv.ParseInt(header.Get(v.timestampHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s header: %w", v.timestampHeader, err)
+	}
+
+	age := v.now().Sub(time.Unix(timestamp, 0))
+	if age > maxSignatureAge || age < -maxSignatureAge {
+		return fmt.Errorf("signature timestamp is off by %s", age.Round(time.Second))
+	}
+
+	want := signBody(v.secret, timestamp, body)
+	if !hmac.Equal([]byte(header.Get(v.signatureHeader)), []byte(want)) {
+		return errors.New("signature mismatch")
+	}
+
+	return nil
+}
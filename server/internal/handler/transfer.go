@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/middleware"
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+	"github.com/sirupsen/logrus"
+)
+
+type TransferHandler struct {
+	transferService service.TransferService
+	logger          *logrus.Logger
+}
+
+func NewTransferHandler(transferService service.TransferService, logger *logrus.Logger) *TransferHandler {
+	return &TransferHandler{transferService: transferService, logger: logger}
+}
+
+func (h *TransferHandler) Transfer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeUnauthorized(w, r)
+		return
+	}
+
+	var req models.TransferRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		logError(h.logger, err, "Failed to decode transfer request")
+		writeError(w, r, err)
+		return
+	}
+
+	transfer, err := h.transferService.Transfer(r.Context(), userID, &req)
+	if err != nil {
+		logError(h.logger.WithField("userID", userID), err, "Failed to transfer points")
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(transfer); err != nil {
+		h.logger.WithError(err).Error("Failed to encode response")
+	}
+}
+
+func (h *TransferHandler) GetTransfers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeUnauthorized(w, r)
+		return
+	}
+
+	transfers, err := h.transferService.GetTransfers(r.Context(), userID)
+	if err != nil {
+		h.logger.WithError(err).WithField("userID", userID).Error("Failed to get transfers")
+		writeError(w, r, err)
+		return
+	}
+
+	if len(transfers) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(transfers); err != nil {
+		h.logger.WithError(err).Error("Failed to encode response")
+	}
+}
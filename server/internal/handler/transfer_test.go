@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/problem"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+)
+
+func TestTransferHandler_Transfer(t *testing.T) {
+	created := time.Date(2020, 12, 9, 16, 9, 57, 0, time.FixedZone("MSK", 3*60*60))
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		result      error
+		wantCalled  bool
+		wantStatus  int
+		wantCode    string
+		wantBody    string
+	}{
+		{
+			name:        "ok",
+			contentType: "application/json",
+			body:        `{"to":"bob","sum":150}`,
+			wantCalled:  true,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"id":7,"from":"alice","to":"bob","sum":150,"direction":"out","created_at":"2020-12-09T16:09:57+03:00"}`,
+		},
+		{
+			name:        "insufficient funds",
+			contentType: "application/json",
+			body:        `{"to":"bob","sum":150}`,
+			result:      service.ErrInsufficientFunds,
+			wantCalled:  true,
+			wantStatus:  http.StatusPaymentRequired,
+			wantCode:    problem.CodeInsufficientFunds,
+		},
+		{
+			name:        "limit exceeded",
+			contentType: "application/json",
+			body:        `{"to":"bob","sum":150}`,
+			result:      service.ErrTransferLimitExceeded,
+			wantCalled:  true,
+			wantStatus:  http.StatusTooManyRequests,
+			wantCode:    problem.CodeTransferLimitExceeded,
+		},
+		{
+			name:        "unknown recipient",
+			contentType: "application/json",
+			body:        `{"to":"bob","sum":150}`,
+			result:      service.ErrUnknownRecipient,
+			wantCalled:  true,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    problem.CodeUnknownRecipient,
+		},
+		{
+			name:        "self transfer",
+			contentType: "application/json",
+			body:        `{"to":"bob","sum":150}`,
+			result:      service.ErrSelfTransfer,
+			wantCalled:  true,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    problem.CodeSelfTransfer,
+		},
+		{
+			name:        "invalid sum",
+			contentType: "application/json",
+			body:        `{"to":"bob","sum":150}`,
+			result:      service.ErrInvalidTransferSum,
+			wantCalled:  true,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    problem.CodeInvalidTransferSum,
+		},
+		{
+			name:        "internal error",
+			contentType: "application/json",
+			body:        `{"to":"bob","sum":150}`,
+			result:      errors.New("db down"),
+			wantCalled:  true,
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    problem.CodeInternal,
+		},
+		{
+			name:        "sum as string",
+			contentType: "application/json",
+			body:        `{"to":"bob","sum":"150"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    problem.CodeInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			called := false
+			env.transfers.transfer = func(_ context.Context, userID int, req *models.TransferRequest) (*models.Transfer, error) {
+				called = true
+				if userID != testUserID {
+					t.Errorf("userID = %d, want %d", userID, testUserID)
+				}
+				if req.To != "bob" || req.Sum != 150 {
+					t.Errorf("request = %+v", req)
+				}
+				if tt.result != nil {
+					return nil, tt.result
+				}
+				return &models.Transfer{ID: 7, From: "alice", To: req.To, Sum: req.Sum, Direction: models.TransferOut, CreatedAt: created}, nil
+			}
+
+			rec := env.do(t, testRequest{
+				method:      http.MethodPost,
+				path:        "/api/user/balance/transfer",
+				contentType: tt.contentType,
+				body:        tt.body,
+			})
+
+			if called != tt.wantCalled {
+				t.Errorf("service called = %v, want %v", called, tt.wantCalled)
+			}
+			if tt.wantCode != "" {
+				assertProblem(t, rec, tt.wantStatus, tt.wantCode)
+				return
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestTransferHandler_GetTransfers(t *testing.T) {
+	created := time.Date(2020, 12, 9, 16, 9, 57, 0, time.FixedZone("MSK", 3*60*60))
+
+	tests := []struct {
+		name       string
+		transfers  []*models.Transfer
+		result     error
+		wantStatus int
+		wantCode   string
+		wantBody   string
+	}{
+		{
+			name:       "transfers",
+			transfers:  []*models.Transfer{{ID: 7, From: "bob", To: "alice", Sum: 150, Direction: models.TransferIn, CreatedAt: created}},
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"id":7,"from":"bob","to":"alice","sum":150,"direction":"in","created_at":"2020-12-09T16:09:57+03:00"}]`,
+		},
+		{
+			name:       "no transfers",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "internal error",
+			result:     errors.New("db down"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   problem.CodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.transfers.list = func(_ context.Context, userID int) ([]*models.Transfer, error) {
+				if userID != testUserID {
+					t.Errorf("userID = %d, want %d", userID, testUserID)
+				}
+				return tt.transfers, tt.result
+			}
+
+			rec := env.do(t, testRequest{method: http.MethodGet, path: "/api/user/balance/transfers"})
+
+			if tt.wantCode != "" {
+				assertProblem(t, rec, tt.wantStatus, tt.wantCode)
+				return
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
@@ -23,29 +23,25 @@ func NewWithdrawalHandler(withdrawalService service.WithdrawalService, logger *l
 func (h *WithdrawalHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeUnauthorized(w, r)
 		return
 	}
 
 	var req models.WithdrawalRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.WithError(err).Error("Failed to decode withdrawal request")
-		http.Error(w, "Invalid request format", http.StatusBadRequest)
+	if err := decodeJSON(w, r, &req); err != nil {
+		logError(h.logger, err, "Failed to decode withdrawal request")
+		writeError(w, r, err)
 		return
 	}
 
 	_, err := h.withdrawalService.Withdraw(r.Context(), userID, &req)
-	if err != nil {
-		if errors.Is(err, service.ErrInvalidWithdrawalOrder) {
-			http.Error(w, "Invalid order number", http.StatusUnprocessableEntity)
-			return
-		}
-		if errors.Is(err, service.ErrInsufficientFunds) {
-			http.Error(w, "Insufficient funds", http.StatusPaymentRequired)
-			return
-		}
-		h.logger.WithError(err).WithField("userID", userID).Error("Failed to withdraw")
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	switch {
+	case errors.Is(err, service.ErrTierNotRecalculated):
+		// Списание проведено, ответ успешный.
+		h.logger.WithError(err).WithField("userID", userID).Warn("Failed to recalculate loyalty tier")
+	case err != nil:
+		logError(h.logger.WithField("userID", userID), err, "Failed to withdraw")
+		writeError(w, r, err)
 		return
 	}
 
@@ -55,14 +51,14 @@ func (h *WithdrawalHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
 func (h *WithdrawalHandler) GetWithdrawals(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeUnauthorized(w, r)
 		return
 	}
 
 	withdrawals, err := h.withdrawalService.GetWithdrawals(r.Context(), userID)
 	if err != nil {
 		h.logger.WithError(err).WithField("userID", userID).Error("Failed to get withdrawals")
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -74,7 +70,6 @@ func (h *WithdrawalHandler) GetWithdrawals(w http.ResponseWriter, r *http.Reques
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(withdrawals); err != nil {
-		http.Error(w, "failed to encode response", http.StatusInternalServerError)
-		return
+		h.logger.WithError(err).Error("Failed to encode response")
 	}
 }
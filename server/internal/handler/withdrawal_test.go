@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/problem"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+)
+
+func TestWithdrawalHandler_Withdraw(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		result      error
+		wantCalled  bool
+		wantStatus  int
+		wantCode    string
+	}{
+		{
+			name:        "ok",
+			contentType: "application/json",
+			body:        `{"order":"2377225624","sum":751}`,
+			wantCalled:  true,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "tier not recalculated",
+			contentType: "application/json",
+			body:        `{"order":"2377225624","sum":751}`,
+			result:      fmt.Errorf("%w: db down", service.ErrTierNotRecalculated),
+			wantCalled:  true,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "insufficient funds",
+			contentType: "application/json",
+			body:        `{"order":"2377225624","sum":751}`,
+			result:      service.ErrInsufficientFunds,
+			wantCalled:  true,
+			wantStatus:  http.StatusPaymentRequired,
+			wantCode:    problem.CodeInsufficientFunds,
+		},
+		{
+			name:        "invalid order",
+			contentType: "application/json",
+			body:        `{"order":"2377225624","sum":751}`,
+			result:      service.ErrInvalidWithdrawalOrder,
+			wantCalled:  true,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    problem.CodeInvalidWithdrawalOrder,
+		},
+		{
+			name:        "invalid sum",
+			contentType: "application/json",
+			body:        `{"order":"2377225624","sum":751}`,
+			result:      service.ErrInvalidWithdrawalSum,
+			wantCalled:  true,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    problem.CodeInvalidWithdrawalSum,
+		},
+		{
+			name:        "sum limit",
+			contentType: "application/json",
+			body:        `{"order":"2377225624","sum":751}`,
+			result:      service.ErrWithdrawalSumLimit,
+			wantCalled:  true,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    problem.CodeWithdrawalSumLimit,
+		},
+		{
+			name:        "daily limit",
+			contentType: "application/json",
+			body:        `{"order":"2377225624","sum":751}`,
+			result:      service.ErrWithdrawalDailyLimit,
+			wantCalled:  true,
+			wantStatus:  http.StatusTooManyRequests,
+			wantCode:    problem.CodeWithdrawalDailyLimit,
+		},
+		{
+			name:        "min balance",
+			contentType: "application/json",
+			body:        `{"order":"2377225624","sum":751}`,
+			result:      service.ErrWithdrawalMinBalance,
+			wantCalled:  true,
+			wantStatus:  http.StatusPaymentRequired,
+			wantCode:    problem.CodeWithdrawalMinBalance,
+		},
+		{
+			name:        "rate exceeded",
+			contentType: "application/json",
+			body:        `{"order":"2377225624","sum":751}`,
+			result:      service.ErrWithdrawalRateExceeded,
+			wantCalled:  true,
+			wantStatus:  http.StatusTooManyRequests,
+			wantCode:    problem.CodeWithdrawalRateExceeded,
+		},
+		{
+			name:        "internal error",
+			contentType: "application/json",
+			body:        `{"order":"2377225624","sum":751}`,
+			result:      errors.New("db down"),
+			wantCalled:  true,
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    problem.CodeInternal,
+		},
+		{
+			name:        "sum as string",
+			contentType: "application/json",
+			body:        `{"order":"2377225624","sum":"751"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    problem.CodeInvalidRequest,
+		},
+		{
+			name:        "plain text",
+			contentType: "text/plain",
+			body:        `{"order":"2377225624","sum":751}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantCode:    problem.CodeUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			called := false
+			env.withdrawals.withdraw = func(_ context.Context, userID int, req *models.WithdrawalRequest) (*models.Withdrawal, error) {
+				called = true
+				if userID != testUserID {
+					t.Errorf("userID = %d, want %d", userID, testUserID)
+				}
+				if req.OrderNumber != "2377225624" || req.Sum != 751 {
+					t.Errorf("request = %+v", req)
+				}
+				if tt.result != nil {
+					return nil, tt.result
+				}
+				return &models.Withdrawal{UserID: userID, OrderNumber: req.OrderNumber, Sum: req.Sum}, nil
+			}
+
+			rec := env.do(t, testRequest{
+				method:      http.MethodPost,
+				path:        "/api/user/balance/withdraw",
+				contentType: tt.contentType,
+				body:        tt.body,
+			})
+
+			if called != tt.wantCalled {
+				t.Errorf("service called = %v, want %v", called, tt.wantCalled)
+			}
+			if tt.wantCode != "" {
+				assertProblem(t, rec, tt.wantStatus, tt.wantCode)
+				return
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestWithdrawalHandler_GetWithdrawals(t *testing.T) {
+	processed := time.Date(2020, 12, 9, 16, 9, 57, 0, time.FixedZone("MSK", 3*60*60))
+
+	tests := []struct {
+		name        string
+		withdrawals []*models.Withdrawal
+		result      error
+		wantStatus  int
+		wantCode    string
+		wantBody    string
+	}{
+		{
+			name:        "withdrawals",
+			withdrawals: []*models.Withdrawal{{OrderNumber: "2377225624", Sum: 500, ProcessedAt: processed}},
+			wantStatus:  http.StatusOK,
+			wantBody:    `[{"order":"2377225624","sum":500,"processed_at":"2020-12-09T16:09:57+03:00"}]`,
+		},
+		{
+			name:       "no withdrawals",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "internal error",
+			result:     errors.New("db down"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   problem.CodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.withdrawals.list = func(_ context.Context, userID int) ([]*models.Withdrawal, error) {
+				if userID != testUserID {
+					t.Errorf("userID = %d, want %d", userID, testUserID)
+				}
+				return tt.withdrawals, tt.result
+			}
+
+			rec := env.do(t, testRequest{method: http.MethodGet, path: "/api/user/withdrawals"})
+
+			if tt.wantCode != "" {
+				assertProblem(t, rec, tt.wantStatus, tt.wantCode)
+				return
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
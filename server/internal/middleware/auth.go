@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/RoGogDBD/loyalty_service/server/internal/problem"
 	"github.com/RoGogDBD/loyalty_service/server/internal/service"
 )
 
@@ -24,19 +25,19 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "Unauthorized")
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Invalid authorization header", http.StatusUnauthorized)
+			problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid authorization header")
 			return
 		}
 
 		userID, err := m.jwtService.ValidateToken(parts[1])
 		if err != nil {
-			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid or expired token")
 			return
 		}
 
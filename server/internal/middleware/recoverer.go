@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/problem"
+	chiMiddleware "github.com/go-chi/chi/middleware"
+)
+
+// Recoverer перехватывает панику в обработчике, печатает стек и отвечает
+// 500 в формате problem+json.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				if rvr == http.ErrAbortHandler {
+					panic(rvr)
+				}
+				chiMiddleware.PrintPrettyStack(rvr)
+				problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,7 +1,42 @@
 package models
 
-type Balance struct {
-	UserID    int     `json:"-"`
-	Current   float64 `json:"current"`
-	Withdrawn float64 `json:"withdrawn"`
-}
+import "time"
+
+type (
+	Balance struct {
+		UserID    int     `json:"-"`
+		Current   float64 `json:"current"`
+		Withdrawn float64 `json:"withdrawn"`
+		// Expiring — баллы из Current, которые скоро сгорят, по датам сгорания.
+		Expiring []*PointsExpiry `json:"expiring,omitempty"`
+		// Tier — уровень лояльности, если программа уровней включена.
+		Tier *TierStatus `json:"tier,omitempty"`
+	}
+
+	// PointLot — партия начисленных баллов. Списания уменьшают Remaining
+	// партий, начиная с тех, что сгорают раньше; по истечении ExpiresAt
+	// остаток переносится в Expired. Партии без ExpiresAt не сгорают.
+	PointLot struct {
+		ID        int
+		UserID    int
+		Amount    float64
+		Remaining float64
+		Expired   float64
+		CreatedAt time.Time
+		ExpiresAt *time.Time
+	}
+
+	PointsExpiry struct {
+		Amount    float64   `json:"amount"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+
+	// BalanceAdjustment — ручная корректировка баланса оператором.
+	BalanceAdjustment struct {
+		ID        int       `json:"-"`
+		UserID    int       `json:"-"`
+		Amount    float64   `json:"amount"`
+		Reason    string    `json:"reason"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+)
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type (
+	// Campaign — промоакция, добавляющая бонус к начислениям по заказам,
+	// обработанным в период [StartsAt, EndsAt). Бонус задаётся либо
+	// множителем (2 — «двойные баллы»: бонус равен начислению), либо
+	// фиксированной суммой за заказ.
+	Campaign struct {
+		ID         int       `json:"id"`
+		Name       string    `json:"name"`
+		StartsAt   time.Time `json:"starts_at"`
+		EndsAt     time.Time `json:"ends_at"`
+		Multiplier float64   `json:"multiplier,omitempty"`
+		Bonus      float64   `json:"bonus,omitempty"`
+		// MinAccrual — минимальное начисление по заказу для участия.
+		MinAccrual float64 `json:"min_accrual,omitempty"`
+		// Tier — только для пользователей этого уровня; пусто — для всех.
+		Tier      string    `json:"tier,omitempty"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	// CampaignBonus — бонус кампании по заказу. Хранится отдельно от
+	// начисления заказа, чтобы его можно было показать и отменить.
+	CampaignBonus struct {
+		ID          int        `json:"id"`
+		CampaignID  int        `json:"-"`
+		Campaign    string     `json:"campaign"`
+		UserID      int        `json:"-"`
+		OrderNumber string     `json:"order"`
+		Amount      float64    `json:"amount"`
+		CreatedAt   time.Time  `json:"created_at"`
+		ReversedAt  *time.Time `json:"reversed_at,omitempty"`
+	}
+)
@@ -6,6 +6,20 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// Хранилища данных.
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
+// Основа расчёта уровня лояльности.
+const (
+	// TierBasisLifetimeAccrual — сумма всех начислений за время участия.
+	TierBasisLifetimeAccrual = "lifetime_accrual"
+	// TierBasisRollingSpend — сумма списаний за последние 12 месяцев.
+	TierBasisRollingSpend = "rolling_spend"
+)
+
 type (
 	ServerConfig struct {
 		Port         string
@@ -13,6 +27,7 @@ type (
 		WriteTimeout time.Duration
 	}
 	DatabaseConfig struct {
+		Storage         string
 		Host            string
 		Port            int
 		User            string
@@ -23,15 +38,105 @@ type (
 		MaxIdleConns    int
 		ConnMaxLifetime time.Duration
 		ConnMaxIdleTime time.Duration
+		// AutoMigrate — применять миграции при запуске сервиса.
+		AutoMigrate bool
 	}
 	AccrualConfig struct {
+		// Address — система начислений по умолчанию.
+		Address string
+		// Providers — системы начислений партнёров для заказов с номерами
+		// на заданные префиксы. Выбирается самый длинный подходящий префикс.
+		Providers []AccrualProvider
+		// PollInterval — пауза между проходами по очереди заказов.
+		PollInterval time.Duration
+		// RequestTimeout — таймаут одного запроса к системе начислений.
+		RequestTimeout time.Duration
+		// OrderDelay — пауза между запросами по соседним заказам.
+		OrderDelay time.Duration
+		// BatchSize — сколько заказов забирается из очереди за один проход.
+		BatchSize int
+		// DynamicInterval включает подстройку интервала под размер очереди:
+		// при полной пачке интервал уменьшается до MinPollInterval, при пустой
+		// очереди увеличивается до MaxPollInterval.
+		DynamicInterval bool
+		MinPollInterval time.Duration
+		MaxPollInterval time.Duration
+		// WebhookSecret включает приём уведомлений от системы начислений,
+		// подписанных HMAC-SHA256 этим ключом. Опрос при этом остаётся
+		// запасным и идёт с интервалом FallbackPollInterval.
+		WebhookSecret        string
+		FallbackPollInterval time.Duration
+		// EngineEnabled включает встроенный расчёт начислений по правилам
+		// вознаграждения для заказов с префиксом EnginePrefix (пустой
+		// префикс — для всех заказов, не попавших к партнёрам). Состав
+		// заказов партнёры регистрируют запросами, подписанными PartnerSecret.
+		EngineEnabled bool
+		EnginePrefix  string
+		PartnerSecret string
+	}
+	AccrualProvider struct {
+		Prefix  string
 		Address string
 	}
+	// PointsConfig — срок действия начисленных баллов.
+	PointsConfig struct {
+		// TTL — через сколько сгорают начисленные баллы; 0 — не сгорают.
+		TTL time.Duration
+		// ExpiryInterval — как часто сжигаются просроченные баллы.
+		ExpiryInterval time.Duration
+		// ExpiringWindow — за сколько до сгорания баллы показываются в
+		// балансе как сгорающие; 0 — не показываются.
+		ExpiringWindow time.Duration
+	}
+	// TiersConfig — уровни лояльности; без уровней программа выключена.
+	TiersConfig struct {
+		Basis string
+		// Levels упорядочены по возрастанию порога, порог первого — 0.
+		Levels []TierLevel
+	}
+	// TierLevel получает пользователь, накопивший Threshold; его
+	// начисления умножаются на Multiplier.
+	TierLevel struct {
+		Name       string
+		Threshold  float64
+		Multiplier float64
+	}
+	// ReferralConfig — реферальная программа. После первого обработанного
+	// заказа приглашённого ReferrerBonus получает пригласивший, RefereeBonus —
+	// приглашённый. MaxRewards и MaxDailyRewards ограничивают число
+	// вознаграждений одного пригласившего всего и за последние сутки;
+	// 0 — без ограничения.
+	ReferralConfig struct {
+		ReferrerBonus   float64
+		RefereeBonus    float64
+		MaxRewards      int
+		MaxDailyRewards int
+	}
+	// TransfersConfig — лимиты переводов баллов между пользователями за
+	// последние сутки для одного отправителя; 0 — без ограничения.
+	TransfersConfig struct {
+		MaxDailySum   float64
+		MaxDailyCount int
+	}
+	// WithdrawalsConfig — ограничения списаний одного пользователя: сумма
+	// одного списания, сумма за последние сутки, остаток, который должен
+	// сохраниться на счёте, и число списаний за последний час. 0 — без
+	// ограничения.
+	WithdrawalsConfig struct {
+		MaxSum      float64
+		MaxDailySum float64
+		MinBalance  float64
+		MaxPerHour  int
+	}
 	LoggerConfig struct {
 		Level logrus.Level
 	}
 	JWTConfig struct {
-		SecretKey     string
-		TokenDuration time.Duration
+		// SecretKey подписывает новые токены и проверяет их.
+		SecretKey string
+		// VerificationKeys — прежние ключи, токены с которыми ещё
+		// принимаются. Нужны для смены SecretKey без разлогинивания.
+		VerificationKeys []string
+		TokenDuration    time.Duration
 	}
 )
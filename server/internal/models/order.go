@@ -9,6 +9,22 @@ const (
 	OrderStatusProcessed  = "PROCESSED"
 )
 
+// Результаты загрузки номера заказа в пакетном запросе.
+const (
+	OrderUploadAccepted = "accepted"
+	OrderUploadExists   = "already_uploaded"
+	OrderUploadConflict = "conflict"
+	OrderUploadInvalid  = "invalid"
+)
+
+// Статусы расчёта в системе начислений.
+const (
+	AccrualStatusRegistered = "REGISTERED"
+	AccrualStatusProcessing = "PROCESSING"
+	AccrualStatusInvalid    = "INVALID"
+	AccrualStatusProcessed  = "PROCESSED"
+)
+
 type (
 	Order struct {
 		ID         int       `json:"-"`
@@ -18,4 +34,17 @@ type (
 		Accrual    *float64  `json:"accrual,omitempty"`
 		UploadedAt time.Time `json:"uploaded_at"`
 	}
+
+	// AccrualResult — результат расчёта начисления по заказу: ответ системы
+	// начислений или присланное ею уведомление.
+	AccrualResult struct {
+		Order   string  `json:"order"`
+		Status  string  `json:"status"`
+		Accrual float64 `json:"accrual,omitempty"`
+	}
+
+	OrderUploadResult struct {
+		Number string `json:"number"`
+		Result string `json:"result"`
+	}
 )
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type (
+	// ReferralReward — вознаграждение за приглашённого пользователя,
+	// начисленное после его первого обработанного заказа. Limited — лимит
+	// пригласившего был исчерпан, и баллы не начислены никому.
+	ReferralReward struct {
+		RefereeID      int
+		ReferrerID     int
+		OrderNumber    string
+		ReferrerAmount float64
+		RefereeAmount  float64
+		Limited        bool
+		CreatedAt      time.Time
+	}
+
+	// ReferralStatus — реферальный код пользователя и итоги его приглашений.
+	ReferralStatus struct {
+		Code string `json:"code"`
+		// Invited — сколько пользователей зарегистрировалось по коду.
+		Invited int `json:"invited"`
+		// Rewarded — за скольких из них начислено вознаграждение.
+		Rewarded int     `json:"rewarded"`
+		Earned   float64 `json:"earned"`
+	}
+)
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Типы вознаграждения в правилах встроенного расчёта начислений.
+const (
+	RewardTypePercent = "%"
+	RewardTypePoints  = "pt"
+)
+
+type (
+	// RewardRule — правило начисления за товар, в описании которого
+	// встречается Match: процент от цены или фиксированное число баллов.
+	RewardRule struct {
+		ID         int       `json:"id"`
+		Match      string    `json:"match"`
+		Reward     float64   `json:"reward"`
+		RewardType string    `json:"reward_type"`
+		CreatedAt  time.Time `json:"created_at"`
+	}
+
+	OrderGood struct {
+		Description string  `json:"description"`
+		Price       float64 `json:"price"`
+	}
+
+	// OrderRegistration — запрос партнёра на регистрацию состава заказа.
+	OrderRegistration struct {
+		Order string      `json:"order"`
+		Goods []OrderGood `json:"goods"`
+	}
+
+	// RegisteredOrder — состав заказа, зарегистрированный партнёром, и
+	// рассчитанное по нему начисление.
+	RegisteredOrder struct {
+		Number       string      `json:"order"`
+		Goods        []OrderGood `json:"goods"`
+		Accrual      float64     `json:"accrual"`
+		RegisteredAt time.Time   `json:"registered_at"`
+	}
+)
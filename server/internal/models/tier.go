@@ -0,0 +1,16 @@
+package models
+
+type (
+	// TierStatus — уровень лояльности пользователя и прогресс до следующего.
+	TierStatus struct {
+		Name       string  `json:"name"`
+		Multiplier float64 `json:"multiplier"`
+		// Amount — накопленная сумма, по которой определяется уровень.
+		Amount float64   `json:"amount"`
+		Next   *TierNext `json:"next,omitempty"`
+	}
+	TierNext struct {
+		Name      string  `json:"name"`
+		Threshold float64 `json:"threshold"`
+	}
+)
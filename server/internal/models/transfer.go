@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+const (
+	TransferIn  = "in"
+	TransferOut = "out"
+)
+
+type (
+	// Transfer — перевод баллов между пользователями. From и To — логины
+	// отправителя и получателя, Direction — направление перевода для
+	// пользователя, запросившего историю.
+	Transfer struct {
+		ID        int       `json:"id"`
+		FromID    int       `json:"-"`
+		ToID      int       `json:"-"`
+		From      string    `json:"from"`
+		To        string    `json:"to"`
+		Sum       float64   `json:"sum"`
+		Direction string    `json:"direction"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	TransferRequest struct {
+		To  string  `json:"to"`
+		Sum float64 `json:"sum"`
+	}
+)
@@ -3,13 +3,21 @@ package models
 import "time"
 
 type User struct {
-	ID           int       `json:"id"`
-	Login        string    `json:"login"`
-	PasswordHash string    `json:"-"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID           int    `json:"id"`
+	Login        string `json:"login"`
+	PasswordHash string `json:"-"`
+	// Tier — уровень лояльности, пересчитывается при начислениях и списаниях.
+	Tier string `json:"tier,omitempty"`
+	// ReferralCode — код, по которому пользователь приглашает других.
+	ReferralCode string `json:"referral_code"`
+	// ReferredBy — ID пригласившего пользователя.
+	ReferredBy *int      `json:"referred_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 type UserCredentials struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
+	// Referrer — реферальный код пригласившего, учитывается при регистрации.
+	Referrer string `json:"referrer,omitempty"`
 }
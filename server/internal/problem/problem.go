@@ -0,0 +1,63 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	chiMiddleware "github.com/go-chi/chi/middleware"
+)
+
+const ContentType = "application/problem+json"
+
+// Стабильные коды ошибок API. Клиенты опираются на них, поэтому
+// существующие значения менять нельзя.
+const (
+	CodeInvalidRequest         = "invalid_request"
+	CodeUnsupportedMediaType   = "unsupported_media_type"
+	CodePayloadTooLarge        = "payload_too_large"
+	CodeUnauthorized           = "unauthorized"
+	CodeInvalidCredentials     = "invalid_credentials"
+	CodeUserExists             = "user_exists"
+	CodeUnknownReferrer        = "unknown_referrer"
+	CodeInvalidOrderNumber     = "invalid_order_number"
+	CodeOrderConflict          = "order_conflict"
+	CodeEmptyOrderBatch        = "empty_order_batch"
+	CodeOrderBatchTooLarge     = "order_batch_too_large"
+	CodeInvalidWithdrawalOrder = "invalid_withdrawal_order"
+	CodeInvalidWithdrawalSum   = "invalid_withdrawal_sum"
+	CodeWithdrawalSumLimit     = "withdrawal_sum_limit"
+	CodeWithdrawalDailyLimit   = "withdrawal_daily_limit"
+	CodeWithdrawalMinBalance   = "withdrawal_min_balance"
+	CodeWithdrawalRateExceeded = "withdrawal_rate_exceeded"
+	CodeInvalidTransferSum     = "invalid_transfer_sum"
+	CodeUnknownRecipient       = "unknown_recipient"
+	CodeSelfTransfer           = "self_transfer"
+	CodeTransferLimitExceeded  = "transfer_limit_exceeded"
+	CodeInsufficientFunds      = "insufficient_funds"
+	CodeOrderNotFound          = "order_not_found"
+	CodeInvalidSignature       = "invalid_signature"
+	CodeInvalidOrderGoods      = "invalid_order_goods"
+	CodeOrderRegistered        = "order_registered"
+	CodeNotFound               = "not_found"
+	CodeMethodNotAllowed       = "method_not_allowed"
+	CodeInternal               = "internal_error"
+)
+
+// Write отправляет ответ об ошибке в формате problem+json.
+func Write(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	p := models.Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Code:      code,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		RequestID: chiMiddleware.GetReqID(r.Context()),
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(p)
+}
@@ -5,15 +5,38 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log"
+	"math"
+	"time"
 
 	"github.com/RoGogDBD/loyalty_service/server/internal/models"
 )
 
+// ErrInsufficientFunds возвращается Withdraw, если на балансе не хватает баллов.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
 type BalanceRepository interface {
 	GetByUserID(ctx context.Context, userID int) (*models.Balance, error)
 	Create(ctx context.Context, userID int) error
-	AddAccrual(ctx context.Context, userID int, amount float64) error
+	// AddAccrual зачисляет amount партией баллов, сгорающей в expiresAt
+	// (nil — без срока).
+	AddAccrual(ctx context.Context, userID int, amount float64, expiresAt *time.Time) error
+	// Withdraw списывает amount с партий, начиная с тех, что сгорают раньше.
 	Withdraw(ctx context.Context, userID int, amount float64) error
+	// Adjust изменяет текущий баланс на amount (может быть отрицательным) и
+	// записывает корректировку с причиной. Положительная корректировка
+	// зачисляется партией со сроком expiresAt, отрицательная списывается
+	// как Withdraw. Если баланс стал бы отрицательным, возвращает
+	// ErrInsufficientFunds.
+	Adjust(ctx context.Context, userID int, amount float64, reason string, expiresAt *time.Time) (*models.BalanceAdjustment, error)
+	GetAdjustments(ctx context.Context, userID int) ([]*models.BalanceAdjustment, error)
+	// GetExpiring возвращает остатки партий, сгорающих не позже before,
+	// сгруппированные по сроку, от ранних к поздним.
+	GetExpiring(ctx context.Context, userID int, before time.Time) ([]*models.PointsExpiry, error)
+	// Expire сжигает остатки партий со сроком не позже now и возвращает
+	// сгоревшую сумму. Ошибка у одного пользователя не прерывает проход:
+	// ошибки всех пользователей возвращаются вместе в конце.
+	Expire(ctx context.Context, now time.Time) (float64, error)
 }
 
 type balanceRepository struct {
@@ -63,30 +86,45 @@ func (r *balanceRepository) Create(ctx context.Context, userID int) error {
 	return nil
 }
 
-func (r *balanceRepository) AddAccrual(ctx context.Context, userID int, amount float64) error {
-	query := `
+func (r *balanceRepository) AddAccrual(ctx context.Context, userID int, amount float64, expiresAt *time.Time) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx, &err)
+
+	if _, err = tx.ExecContext(ctx, `
         INSERT INTO balance (user_id, current, withdrawn)
         VALUES ($1, $2, 0)
         ON CONFLICT (user_id)
         DO UPDATE SET current = balance.current + EXCLUDED.current
-    `
-
-	_, err := r.db.ExecContext(ctx, query, userID, amount)
-	if err != nil {
+    `, userID, amount); err != nil {
 		return fmt.Errorf("failed to add accrual: %w", err)
 	}
 
+	if err = insertLot(ctx, tx, userID, amount, expiresAt); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit accrual: %w", err)
+	}
+
 	return nil
 }
 
-func (r *balanceRepository) Withdraw(ctx context.Context, userID int, amount float64) error {
-	query := `
+func (r *balanceRepository) Withdraw(ctx context.Context, userID int, amount float64) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx, &err)
+
+	result, err := tx.ExecContext(ctx, `
         UPDATE balance
         SET current = current - $1, withdrawn = withdrawn + $1
         WHERE user_id = $2 AND current >= $1
-    `
-
-	result, err := r.db.ExecContext(ctx, query, amount, userID)
+    `, amount, userID)
 	if err != nil {
 		return fmt.Errorf("failed to withdraw: %w", err)
 	}
@@ -97,8 +135,315 @@ func (r *balanceRepository) Withdraw(ctx context.Context, userID int, amount flo
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("insufficient funds")
+		return ErrInsufficientFunds
+	}
+
+	if _, err = consumeLots(ctx, tx, userID, amount); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit withdrawal: %w", err)
+	}
+
+	return nil
+}
+
+func (r *balanceRepository) Adjust(ctx context.Context, userID int, amount float64, reason string, expiresAt *time.Time) (_ *models.BalanceAdjustment, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx, &err)
+
+	if _, err = tx.ExecContext(ctx, `
+        INSERT INTO balance (user_id, current, withdrawn)
+        VALUES ($1, 0, 0)
+        ON CONFLICT (user_id) DO NOTHING
+    `, userID); err != nil {
+		return nil, fmt.Errorf("failed to create balance: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+        UPDATE balance
+        SET current = current + $1
+        WHERE user_id = $2 AND current + $1 >= 0
+    `, amount, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to adjust balance: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return nil, ErrInsufficientFunds
+	}
+
+	if amount > 0 {
+		err = insertLot(ctx, tx, userID, amount, expiresAt)
+	} else {
+		_, err = consumeLots(ctx, tx, userID, -amount)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	adjustment := &models.BalanceAdjustment{}
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO balance_adjustments (user_id, amount, reason, created_at)
+        VALUES ($1, $2, $3, NOW())
+        RETURNING id, user_id, amount, reason, created_at
+    `, userID, amount, reason).Scan(
+		&adjustment.ID,
+		&adjustment.UserID,
+		&adjustment.Amount,
+		&adjustment.Reason,
+		&adjustment.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record adjustment: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit adjustment: %w", err)
+	}
+
+	return adjustment, nil
+}
+
+func (r *balanceRepository) GetAdjustments(ctx context.Context, userID int) ([]*models.BalanceAdjustment, error) {
+	query := `
+        SELECT id, user_id, amount, reason, created_at
+        FROM balance_adjustments
+        WHERE user_id = $1
+        ORDER BY created_at DESC, id DESC
+    `
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get adjustments: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("failed to close rows: %v", err)
+		}
+	}()
+
+	var adjustments []*models.BalanceAdjustment
+	for rows.Next() {
+		adjustment := &models.BalanceAdjustment{}
+		if err := rows.Scan(
+			&adjustment.ID,
+			&adjustment.UserID,
+			&adjustment.Amount,
+			&adjustment.Reason,
+			&adjustment.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan adjustment: %w", err)
+		}
+		adjustments = append(adjustments, adjustment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate adjustments: %w", err)
+	}
+
+	return adjustments, nil
+}
+
+func (r *balanceRepository) GetExpiring(ctx context.Context, userID int, before time.Time) ([]*models.PointsExpiry, error) {
+	query := `
+        SELECT expires_at, SUM(remaining)
+        FROM point_lots
+        WHERE user_id = $1 AND remaining > 0 AND expires_at <= $2
+        GROUP BY expires_at
+        ORDER BY expires_at
+    `
+
+	rows, err := r.db.QueryContext(ctx, query, userID, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expiring points: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("failed to close rows: %v", err)
+		}
+	}()
+
+	var expiring []*models.PointsExpiry
+	for rows.Next() {
+		e := &models.PointsExpiry{}
+		if err := rows.Scan(&e.ExpiresAt, &e.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan expiring points: %w", err)
+		}
+		expiring = append(expiring, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate expiring points: %w", err)
+	}
+
+	return expiring, nil
+}
+
+func (r *balanceRepository) Expire(ctx context.Context, now time.Time) (float64, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT DISTINCT user_id
+        FROM point_lots
+        WHERE remaining > 0 AND expires_at <= $1
+    `, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find expired points: %w", err)
+	}
+
+	var users []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, userID)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, fmt.Errorf("failed to close rows: %w", err)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate expired points: %w", err)
+	}
+
+	// Ошибка у одного пользователя не останавливает сжигание у остальных:
+	// иначе она повторялась бы на каждом проходе и блокировала всех после него.
+	var (
+		total  float64
+		failed int
+		errs   []error
+	)
+	for _, userID := range users {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		amount, err := r.expireUser(ctx, userID, now)
+		if err != nil {
+			r.db.logger.WithError(err).WithField("userID", userID).Error("Failed to expire points for user")
+			failed++
+			errs = append(errs, fmt.Errorf("user %d: %w", userID, err))
+			continue
+		}
+		total += amount
+	}
+	if len(errs) > 0 {
+		return roundCents(total), fmt.Errorf("failed to expire points for %d of %d users: %w", failed, len(users), errors.Join(errs...))
+	}
+
+	return roundCents(total), nil
+}
+
+// expireUser сжигает просроченные партии одного пользователя. Баланс
+// блокируется первым, как и при списании, чтобы не было взаимоблокировок.
+func (r *balanceRepository) expireUser(ctx context.Context, userID int, now time.Time) (_ float64, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer rollbackOnError(tx, &err)
+
+	if _, err = tx.ExecContext(ctx, `SELECT 1 FROM balance WHERE user_id = $1 FOR UPDATE`, userID); err != nil {
+		return 0, fmt.Errorf("failed to lock balance: %w", err)
+	}
+
+	var amount float64
+	err = tx.QueryRowContext(ctx, `
+        WITH expired AS (
+            UPDATE point_lots
+            SET expired = remaining, remaining = 0
+            WHERE user_id = $1 AND remaining > 0 AND expires_at <= $2
+            RETURNING expired
+        )
+        SELECT COALESCE(SUM(expired), 0) FROM expired
+    `, userID, now).Scan(&amount)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire points: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+        UPDATE balance SET current = current - $1 WHERE user_id = $2
+    `, amount, userID); err != nil {
+		return 0, fmt.Errorf("failed to debit expired points: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit expiration: %w", err)
+	}
+
+	return amount, nil
+}
 
+func insertLot(ctx context.Context, tx *sql.Tx, userID int, amount float64, expiresAt *time.Time) error {
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO point_lots (user_id, amount, remaining, created_at, expires_at)
+        VALUES ($1, $2, $2, NOW(), $3)
+    `, userID, amount, expiresAt); err != nil {
+		return fmt.Errorf("failed to record point lot: %w", err)
+	}
 	return nil
 }
+
+// consumeLots уменьшает остатки партий на amount, начиная с тех, что сгорают
+// раньше; бессрочные партии списываются последними. Возвращает списанные
+// части партий: Remaining — сколько списано, ExpiresAt — срок партии.
+func consumeLots(ctx context.Context, tx *sql.Tx, userID int, amount float64) ([]models.PointLot, error) {
+	rows, err := tx.QueryContext(ctx, `
+        SELECT id, remaining, expires_at
+        FROM point_lots
+        WHERE user_id = $1 AND remaining > 0
+        ORDER BY expires_at NULLS LAST, id
+        FOR UPDATE
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get point lots: %w", err)
+	}
+
+	var lots []models.PointLot
+	for rows.Next() {
+		var lot models.PointLot
+		if err := rows.Scan(&lot.ID, &lot.Remaining, &lot.ExpiresAt); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to scan point lot: %w", err)
+		}
+		lots = append(lots, lot)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close rows: %w", err)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate point lots: %w", err)
+	}
+
+	var consumed []models.PointLot
+	for _, lot := range lots {
+		if amount <= 0 {
+			break
+		}
+		take := math.Min(lot.Remaining, amount)
+		if _, err := tx.ExecContext(ctx, `
+            UPDATE point_lots SET remaining = remaining - $1 WHERE id = $2
+        `, take, lot.ID); err != nil {
+			return nil, fmt.Errorf("failed to consume point lot: %w", err)
+		}
+		amount = roundCents(amount - take)
+		lot.Remaining = take
+		consumed = append(consumed, lot)
+	}
+
+	return consumed, nil
+}
+
+func rollbackOnError(tx *sql.Tx, err *error) {
+	if *err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			log.Printf("failed to rollback transaction: %v", rerr)
+		}
+	}
+}
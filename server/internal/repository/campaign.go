@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+var (
+	// ErrBonusExists возвращается AddBonus, если бонус кампании по заказу
+	// уже начислен.
+	ErrBonusExists = errors.New("campaign bonus already granted")
+	// ErrBonusReversed возвращается ReverseBonus для уже отменённого бонуса.
+	ErrBonusReversed = errors.New("campaign bonus already reversed")
+)
+
+// CampaignRepository хранит промоакции и начисленные по ним бонусы.
+type CampaignRepository interface {
+	CreateCampaign(ctx context.Context, campaign *models.Campaign) (*models.Campaign, error)
+	// ListCampaigns возвращает кампании в порядке создания.
+	ListCampaigns(ctx context.Context) ([]*models.Campaign, error)
+	// ActiveCampaigns возвращает кампании, действующие в момент at.
+	ActiveCampaigns(ctx context.Context, at time.Time) ([]*models.Campaign, error)
+	// EndCampaign завершает кампанию в момент at, если она заканчивается
+	// позже. Возвращает false, если кампании нет.
+	EndCampaign(ctx context.Context, id int, at time.Time) (bool, error)
+	// AddBonus записывает бонус и в той же транзакции зачисляет его на
+	// баланс партией, сгорающей в expiresAt.
+	AddBonus(ctx context.Context, bonus *models.CampaignBonus, expiresAt *time.Time) (*models.CampaignBonus, error)
+	// GetBonuses возвращает бонусы пользователя, новые первыми.
+	GetBonuses(ctx context.Context, userID int) ([]*models.CampaignBonus, error)
+	// ReverseBonus отменяет бонус и списывает его с баланса. Возвращает nil,
+	// если бонуса нет, и ErrInsufficientFunds, если баллов не хватает.
+	ReverseBonus(ctx context.Context, id int) (*models.CampaignBonus, error)
+}
+
+type campaignRepository struct {
+	db *DB
+}
+
+func NewCampaignRepository(db *DB) CampaignRepository {
+	return &campaignRepository{db: db}
+}
+
+const campaignColumns = `id, name, starts_at, ends_at, multiplier, bonus, min_accrual, tier, created_at`
+
+func (r *campaignRepository) CreateCampaign(ctx context.Context, campaign *models.Campaign) (*models.Campaign, error) {
+	query := `
+        INSERT INTO campaigns (name, starts_at, ends_at, multiplier, bonus, min_accrual, tier, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+        RETURNING ` + campaignColumns
+
+	created, err := scanCampaign(r.db.QueryRowContext(ctx, query,
+		campaign.Name,
+		campaign.StartsAt,
+		campaign.EndsAt,
+		campaign.Multiplier,
+		campaign.Bonus,
+		campaign.MinAccrual,
+		campaign.Tier,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create campaign: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *campaignRepository) ListCampaigns(ctx context.Context) ([]*models.Campaign, error) {
+	return r.queryCampaigns(ctx, `SELECT `+campaignColumns+` FROM campaigns ORDER BY id`)
+}
+
+func (r *campaignRepository) ActiveCampaigns(ctx context.Context, at time.Time) ([]*models.Campaign, error) {
+	query := `
+        SELECT ` + campaignColumns + `
+        FROM campaigns
+        WHERE starts_at <= $1 AND ends_at > $1
+        ORDER BY id
+    `
+	return r.queryCampaigns(ctx, query, at)
+}
+
+func (r *campaignRepository) queryCampaigns(ctx context.Context, query string, args ...any) ([]*models.Campaign, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("failed to close rows: %v", err)
+		}
+	}()
+
+	var campaigns []*models.Campaign
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan campaign: %w", err)
+		}
+		campaigns = append(campaigns, campaign)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate campaigns: %w", err)
+	}
+
+	return campaigns, nil
+}
+
+func (r *campaignRepository) EndCampaign(ctx context.Context, id int, at time.Time) (bool, error) {
+	// Кампания, которая ещё не началась, заканчивается в момент начала.
+	result, err := r.db.ExecContext(ctx, `
+        UPDATE campaigns
+        SET ends_at = GREATEST(starts_at, LEAST(ends_at, $2))
+        WHERE id = $1
+    `, id, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to end campaign: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+func (r *campaignRepository) AddBonus(ctx context.Context, bonus *models.CampaignBonus, expiresAt *time.Time) (_ *models.CampaignBonus, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx, &err)
+
+	created := *bonus
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO campaign_bonuses (campaign_id, user_id, order_number, amount, created_at)
+        VALUES ($1, $2, $3, $4, NOW())
+        ON CONFLICT (campaign_id, order_number) DO NOTHING
+        RETURNING id, created_at
+    `, bonus.CampaignID, bonus.UserID, bonus.OrderNumber, bonus.Amount).Scan(&created.ID, &created.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBonusExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record campaign bonus: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+        INSERT INTO balance (user_id, current, withdrawn)
+        VALUES ($1, $2, 0)
+        ON CONFLICT (user_id)
+        DO UPDATE SET current = balance.current + EXCLUDED.current
+    `, bonus.UserID, bonus.Amount); err != nil {
+		return nil, fmt.Errorf("failed to add campaign bonus: %w", err)
+	}
+
+	if err = insertLot(ctx, tx, bonus.UserID, bonus.Amount, expiresAt); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit campaign bonus: %w", err)
+	}
+
+	return &created, nil
+}
+
+const bonusQuery = `
+        SELECT b.id, b.campaign_id, c.name, b.user_id, b.order_number, b.amount, b.created_at, b.reversed_at
+        FROM campaign_bonuses b
+        JOIN campaigns c ON c.id = b.campaign_id
+    `
+
+func (r *campaignRepository) GetBonuses(ctx context.Context, userID int) ([]*models.CampaignBonus, error) {
+	rows, err := r.db.QueryContext(ctx, bonusQuery+`
+        WHERE b.user_id = $1
+        ORDER BY b.created_at DESC, b.id DESC
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign bonuses: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("failed to close rows: %v", err)
+		}
+	}()
+
+	var bonuses []*models.CampaignBonus
+	for rows.Next() {
+		bonus, err := scanBonus(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan campaign bonus: %w", err)
+		}
+		bonuses = append(bonuses, bonus)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate campaign bonuses: %w", err)
+	}
+
+	return bonuses, nil
+}
+
+func (r *campaignRepository) ReverseBonus(ctx context.Context, id int) (_ *models.CampaignBonus, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx, &err)
+
+	bonus, err := scanBonus(tx.QueryRowContext(ctx, bonusQuery+`
+        WHERE b.id = $1
+        FOR UPDATE OF b
+    `, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		// rollbackOnError не сработает: транзакция закрывается без ошибки.
+		_ = tx.Rollback()
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign bonus: %w", err)
+	}
+	if bonus.ReversedAt != nil {
+		return nil, ErrBonusReversed
+	}
+
+	result, err := tx.ExecContext(ctx, `
+        UPDATE balance
+        SET current = current - $1
+        WHERE user_id = $2 AND current >= $1
+    `, bonus.Amount, bonus.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reverse campaign bonus: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return nil, ErrInsufficientFunds
+	}
+
+	if _, err = consumeLots(ctx, tx, bonus.UserID, bonus.Amount); err != nil {
+		return nil, err
+	}
+
+	var reversedAt time.Time
+	if err = tx.QueryRowContext(ctx, `
+        UPDATE campaign_bonuses SET reversed_at = NOW() WHERE id = $1 RETURNING reversed_at
+    `, id).Scan(&reversedAt); err != nil {
+		return nil, fmt.Errorf("failed to mark campaign bonus reversed: %w", err)
+	}
+	bonus.ReversedAt = &reversedAt
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit bonus reversal: %w", err)
+	}
+
+	return bonus, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCampaign(row rowScanner) (*models.Campaign, error) {
+	campaign := &models.Campaign{}
+	err := row.Scan(
+		&campaign.ID,
+		&campaign.Name,
+		&campaign.StartsAt,
+		&campaign.EndsAt,
+		&campaign.Multiplier,
+		&campaign.Bonus,
+		&campaign.MinAccrual,
+		&campaign.Tier,
+		&campaign.CreatedAt,
+	)
+	return campaign, err
+}
+
+func scanBonus(row rowScanner) (*models.CampaignBonus, error) {
+	bonus := &models.CampaignBonus{}
+	err := row.Scan(
+		&bonus.ID,
+		&bonus.CampaignID,
+		&bonus.Campaign,
+		&bonus.UserID,
+		&bonus.OrderNumber,
+		&bonus.Amount,
+		&bonus.CreatedAt,
+		&bonus.ReversedAt,
+	)
+	return bonus, err
+}
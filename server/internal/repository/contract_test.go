@@ -0,0 +1,1079 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+// repositorySet — набор репозиториев одного хранилища для контрактных тестов.
+type repositorySet struct {
+	users       UserRepository
+	orders      OrderRepository
+	balances    BalanceRepository
+	withdrawals WithdrawalRepository
+	rewards     RewardRepository
+	campaigns   CampaignRepository
+	referrals   ReferralRepository
+	transfers   TransferRepository
+}
+
+// runRepositoryContract проверяет поведение, общее для всех реализаций
+// репозиториев. newSet должен возвращать пустое хранилище.
+func runRepositoryContract(t *testing.T, newSet func(t *testing.T) repositorySet) {
+	t.Run("users", func(t *testing.T) { testUserContract(t, newSet(t)) })
+	t.Run("orders", func(t *testing.T) { testOrderContract(t, newSet(t)) })
+	t.Run("order batch", func(t *testing.T) { testOrderBatchContract(t, newSet(t)) })
+	t.Run("concurrent orders", func(t *testing.T) { testConcurrentOrderContract(t, newSet(t)) })
+	t.Run("balance", func(t *testing.T) { testBalanceContract(t, newSet(t)) })
+	t.Run("balance rounding", func(t *testing.T) { testBalanceRoundingContract(t, newSet(t)) })
+	t.Run("balance adjustments", func(t *testing.T) { testAdjustmentContract(t, newSet(t)) })
+	t.Run("point expiration", func(t *testing.T) { testPointExpirationContract(t, newSet(t)) })
+	t.Run("concurrent withdrawals", func(t *testing.T) { testConcurrentWithdrawContract(t, newSet(t)) })
+	t.Run("withdrawals", func(t *testing.T) { testWithdrawalContract(t, newSet(t)) })
+	t.Run("concurrent limited withdrawals", func(t *testing.T) { testConcurrentLimitedWithdrawContract(t, newSet(t)) })
+	t.Run("reward rules", func(t *testing.T) { testRewardRuleContract(t, newSet(t)) })
+	t.Run("registered orders", func(t *testing.T) { testRegisteredOrderContract(t, newSet(t)) })
+	t.Run("campaigns", func(t *testing.T) { testCampaignContract(t, newSet(t)) })
+	t.Run("campaign bonuses", func(t *testing.T) { testCampaignBonusContract(t, newSet(t)) })
+	t.Run("referrals", func(t *testing.T) { testReferralContract(t, newSet(t)) })
+	t.Run("concurrent referrals", func(t *testing.T) { testConcurrentReferralContract(t, newSet(t)) })
+	t.Run("transfers", func(t *testing.T) { testTransferContract(t, newSet(t)) })
+}
+
+func createUser(t *testing.T, repos repositorySet, login string) *models.User {
+	t.Helper()
+
+	user, err := repos.users.Create(context.Background(), &models.User{
+		Login:        login,
+		PasswordHash: "hash-" + login,
+		ReferralCode: strings.ToUpper(login),
+	})
+	if err != nil {
+		t.Fatalf("create user %q: %v", login, err)
+	}
+	return user
+}
+
+func testUserContract(t *testing.T, repos repositorySet) {
+	ctx := context.Background()
+
+	user := createUser(t, repos, "alice")
+	if user.ID == 0 || user.Login != "alice" || user.ReferralCode != "ALICE" || user.ReferredBy != nil || user.CreatedAt.IsZero() {
+		t.Errorf("created user = %+v", user)
+	}
+
+	if _, err := repos.users.Create(ctx, &models.User{Login: "alice", PasswordHash: "other", ReferralCode: "OTHER"}); err == nil {
+		t.Error("duplicate login was accepted")
+	}
+	if _, err := repos.users.Create(ctx, &models.User{Login: "carol", PasswordHash: "other", ReferralCode: "ALICE"}); !errors.Is(err, ErrReferralCodeExists) {
+		t.Errorf("duplicate referral code error = %v, want ErrReferralCodeExists", err)
+	}
+
+	byLogin, err := repos.users.GetByLogin(ctx, "alice")
+	if err != nil || byLogin == nil {
+		t.Fatalf("GetByLogin = %v, %v", byLogin, err)
+	}
+	if byLogin.ID != user.ID || byLogin.PasswordHash != "hash-alice" {
+		t.Errorf("GetByLogin = %+v", byLogin)
+	}
+
+	byID, err := repos.users.GetByID(ctx, user.ID)
+	if err != nil || byID == nil || byID.Login != "alice" {
+		t.Errorf("GetByID = %+v, %v", byID, err)
+	}
+
+	if missing, err := repos.users.GetByLogin(ctx, "bob"); missing != nil || err != nil {
+		t.Errorf("GetByLogin(missing) = %+v, %v; want nil, nil", missing, err)
+	}
+	if missing, err := repos.users.GetByID(ctx, user.ID+1000); missing != nil || err != nil {
+		t.Errorf("GetByID(missing) = %+v, %v; want nil, nil", missing, err)
+	}
+	if byCode, err := repos.users.GetByReferralCode(ctx, "ALICE"); err != nil || byCode == nil || byCode.ID != user.ID {
+		t.Errorf("GetByReferralCode = %+v, %v", byCode, err)
+	}
+	if missing, err := repos.users.GetByReferralCode(ctx, "NOBODY"); missing != nil || err != nil {
+		t.Errorf("GetByReferralCode(missing) = %+v, %v; want nil, nil", missing, err)
+	}
+
+	bob := createUser(t, repos, "bob")
+	users, err := repos.users.List(ctx)
+	if err != nil || len(users) != 2 || users[0].ID != user.ID || users[1].ID != bob.ID {
+		t.Errorf("List = %+v, %v; want alice, bob", users, err)
+	}
+
+	if user.Tier != "" {
+		t.Errorf("new user tier = %q, want empty", user.Tier)
+	}
+	if err := repos.users.SetTier(ctx, user.ID, "silver"); err != nil {
+		t.Fatal(err)
+	}
+	if byID, err := repos.users.GetByID(ctx, user.ID); err != nil || byID.Tier != "silver" {
+		t.Errorf("GetByID after SetTier = %+v, %v", byID, err)
+	}
+	if byLogin, err := repos.users.GetByLogin(ctx, "bob"); err != nil || byLogin.Tier != "" {
+		t.Errorf("other user = %+v, %v; want no tier", byLogin, err)
+	}
+	if dave, err := repos.users.Create(ctx, &models.User{Login: "dave", PasswordHash: "hash", Tier: "bronze", ReferralCode: "DAVE"}); err != nil || dave.Tier != "bronze" {
+		t.Errorf("created user with tier = %+v, %v", dave, err)
+	}
+}
+
+func testOrderContract(t *testing.T, repos repositorySet) {
+	ctx := context.Background()
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+
+	first, created, err := repos.orders.CreateOrGet(ctx, "12345678903", alice.ID)
+	if err != nil || !created {
+		t.Fatalf("CreateOrGet = %v, %v", created, err)
+	}
+	if first.Status != models.OrderStatusNew || first.Accrual != nil || first.UserID != alice.ID {
+		t.Errorf("created order = %+v", first)
+	}
+
+	again, created, err := repos.orders.CreateOrGet(ctx, "12345678903", bob.ID)
+	if err != nil || created {
+		t.Fatalf("CreateOrGet(existing) = %v, %v", created, err)
+	}
+	if again.ID != first.ID || again.UserID != alice.ID {
+		t.Errorf("existing order = %+v, want owner %d", again, alice.ID)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	second, _, err := repos.orders.CreateOrGet(ctx, "9278923470", alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orders, err := repos.orders.GetByUserID(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 || orders[0].Number != second.Number || orders[1].Number != first.Number {
+		t.Errorf("GetByUserID = %+v, want newest first", orders)
+	}
+	if orders, _ := repos.orders.GetByUserID(ctx, bob.ID); len(orders) != 0 {
+		t.Errorf("GetByUserID(bob) = %+v, want empty", orders)
+	}
+
+	if missing, err := repos.orders.GetByNumber(ctx, "346436439"); missing != nil || err != nil {
+		t.Errorf("GetByNumber(missing) = %+v, %v; want nil, nil", missing, err)
+	}
+
+	pending, err := repos.orders.GetPendingOrders(ctx, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].Number != first.Number {
+		t.Errorf("GetPendingOrders = %+v, want oldest first", pending)
+	}
+	if limited, _ := repos.orders.GetPendingOrders(ctx, 0, 1); len(limited) != 1 || limited[0].Number != first.Number {
+		t.Errorf("GetPendingOrders(limit 1) = %+v, want oldest only", limited)
+	}
+	if next, _ := repos.orders.GetPendingOrders(ctx, first.ID, 1); len(next) != 1 || next[0].Number != second.Number {
+		t.Errorf("GetPendingOrders(after first) = %+v, want the next order", next)
+	}
+
+	if err := repos.orders.UpdateStatusByID(ctx, first.ID, models.OrderStatusProcessed, 500); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.orders.UpdateStatusByID(ctx, first.ID, models.OrderStatusProcessed, 500); !errors.Is(err, ErrOrderFinalized) {
+		t.Errorf("second UpdateStatusByID err = %v, want ErrOrderFinalized", err)
+	}
+	if err := repos.orders.UpdateStatus(ctx, second.Number, models.OrderStatusProcessing, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repos.orders.GetByNumber(ctx, first.Number)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.OrderStatusProcessed || got.Accrual == nil || *got.Accrual != 500 {
+		t.Errorf("updated order = %+v", got)
+	}
+
+	pending, _ = repos.orders.GetPendingOrders(ctx, 0, 0)
+	if len(pending) != 1 || pending[0].Number != second.Number || pending[0].Status != models.OrderStatusProcessing {
+		t.Errorf("GetPendingOrders after update = %+v", pending)
+	}
+
+	// Обработанный заказ не возвращается в очередь, остальные — сбрасываются в NEW.
+	if _, err := repos.orders.Requeue(ctx, first.Number); !errors.Is(err, ErrOrderFinalized) {
+		t.Errorf("Requeue(processed) err = %v, want ErrOrderFinalized", err)
+	}
+	if _, err := repos.orders.Requeue(ctx, "346436439"); !errors.Is(err, ErrOrderFinalized) {
+		t.Errorf("Requeue(missing) err = %v, want ErrOrderFinalized", err)
+	}
+	requeued, err := repos.orders.Requeue(ctx, second.Number)
+	if err != nil || requeued.ID != second.ID || requeued.Status != models.OrderStatusNew || requeued.Accrual != nil {
+		t.Errorf("Requeue = %+v, %v; want NEW without accrual", requeued, err)
+	}
+	if got, _ := repos.orders.GetByNumber(ctx, first.Number); got.Status != models.OrderStatusProcessed {
+		t.Errorf("processed order after Requeue = %+v", got)
+	}
+
+	// В сумму начислений входят только обработанные заказы пользователя.
+	if total, err := repos.orders.GetAccrualTotal(ctx, alice.ID); err != nil || total != 500 {
+		t.Errorf("GetAccrualTotal = %v, %v; want 500", total, err)
+	}
+	if total, err := repos.orders.GetAccrualTotal(ctx, bob.ID); err != nil || total != 0 {
+		t.Errorf("GetAccrualTotal(bob) = %v, %v; want 0", total, err)
+	}
+}
+
+func testOrderBatchContract(t *testing.T, repos repositorySet) {
+	ctx := context.Background()
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+
+	if _, _, err := repos.orders.CreateOrGet(ctx, "9278923470", bob.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := repos.orders.CreateBatch(ctx, []string{"12345678903", "9278923470", "12345678903"}, alice.ID)
+	if err != nil {
+		t.Fatalf("CreateBatch: %v", err)
+	}
+
+	byNumber := make(map[string]*OrderInsertResult)
+	for _, res := range results {
+		byNumber[res.Number] = res
+	}
+	if len(byNumber) != 2 || len(results) != 2 {
+		t.Fatalf("CreateBatch results = %+v, want one per distinct number", results)
+	}
+	if res := byNumber["12345678903"]; !res.Created || res.OwnerID != alice.ID {
+		t.Errorf("new number result = %+v", res)
+	}
+	if res := byNumber["9278923470"]; res.Created || res.OwnerID != bob.ID {
+		t.Errorf("existing number result = %+v", res)
+	}
+
+	if order, _ := repos.orders.GetByNumber(ctx, "12345678903"); order == nil || order.UserID != alice.ID {
+		t.Errorf("batch order was not stored: %+v", order)
+	}
+}
+
+func testConcurrentOrderContract(t *testing.T, repos repositorySet) {
+	const workers = 20
+
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+		owners  = make(map[int]int)
+	)
+	for i := 0; i < workers; i++ {
+		userID := alice.ID
+		if i%2 == 1 {
+			userID = bob.ID
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order, ok, err := repos.orders.CreateOrGet(context.Background(), "12345678903", userID)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				created++
+			}
+			owners[order.UserID]++
+		}()
+	}
+	wg.Wait()
+
+	if created != 1 || len(owners) != 1 {
+		t.Errorf("created = %d, owners = %v; want a single owner", created, owners)
+	}
+}
+
+func testBalanceContract(t *testing.T, repos repositorySet) {
+	ctx := context.Background()
+	user := createUser(t, repos, "alice")
+
+	balance, err := repos.balances.GetByUserID(ctx, user.ID)
+	if err != nil || balance.Current != 0 || balance.Withdrawn != 0 {
+		t.Fatalf("initial balance = %+v, %v", balance, err)
+	}
+
+	if err := repos.balances.Withdraw(ctx, user.ID, 1); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("Withdraw without balance = %v, want ErrInsufficientFunds", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := repos.balances.Create(ctx, user.ID); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	if err := repos.balances.AddAccrual(ctx, user.ID, 100.25, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.balances.AddAccrual(ctx, user.ID, 50, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.balances.Withdraw(ctx, user.ID, 150.26); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("Withdraw over balance = %v, want ErrInsufficientFunds", err)
+	}
+	if err := repos.balances.Withdraw(ctx, user.ID, 50.25); err != nil {
+		t.Fatalf("Withdraw: %v", err)
+	}
+
+	balance, err = repos.balances.GetByUserID(ctx, user.ID)
+	if err != nil || balance.Current != 100 || balance.Withdrawn != 50.25 {
+		t.Errorf("balance = %+v, %v; want current 100, withdrawn 50.25", balance, err)
+	}
+}
+
+// testBalanceRoundingContract проверяет, что суммы округляются до копеек,
+// как в столбцах DECIMAL(10,2).
+func testBalanceRoundingContract(t *testing.T, repos repositorySet) {
+	ctx := context.Background()
+	user := createUser(t, repos, "alice")
+
+	for _, amount := range []float64{10.004, 0.336} {
+		if err := repos.balances.AddAccrual(ctx, user.ID, amount, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repos.balances.Withdraw(ctx, user.ID, 0.333); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.balances.Adjust(ctx, user.ID, 1.116, "rounding", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err := repos.balances.GetByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Current != 11.13 || balance.Withdrawn != 0.33 {
+		t.Errorf("balance = %+v, want current 11.13 and withdrawn 0.33", balance)
+	}
+	if adjustments, err := repos.balances.GetAdjustments(ctx, user.ID); err != nil || len(adjustments) != 1 || adjustments[0].Amount != 1.12 {
+		t.Errorf("adjustments = %+v, %v; want amount 1.12", adjustments, err)
+	}
+}
+
+func testAdjustmentContract(t *testing.T, repos repositorySet) {
+	ctx := context.Background()
+	user := createUser(t, repos, "alice")
+
+	if _, err := repos.balances.Adjust(ctx, user.ID, -1, "chargeback", nil); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("Adjust below zero = %v, want ErrInsufficientFunds", err)
+	}
+
+	credit, err := repos.balances.Adjust(ctx, user.ID, 100, "goodwill", nil)
+	if err != nil {
+		t.Fatalf("Adjust: %v", err)
+	}
+	if credit.ID == 0 || credit.UserID != user.ID || credit.Amount != 100 || credit.Reason != "goodwill" || credit.CreatedAt.IsZero() {
+		t.Errorf("adjustment = %+v", credit)
+	}
+	if _, err := repos.balances.Adjust(ctx, user.ID, -30.5, "chargeback", nil); err != nil {
+		t.Fatalf("Adjust: %v", err)
+	}
+
+	balance, err := repos.balances.GetByUserID(ctx, user.ID)
+	if err != nil || balance.Current != 69.5 || balance.Withdrawn != 0 {
+		t.Errorf("balance = %+v, %v; want current 69.5", balance, err)
+	}
+
+	adjustments, err := repos.balances.GetAdjustments(ctx, user.ID)
+	if err != nil || len(adjustments) != 2 {
+		t.Fatalf("GetAdjustments = %v, %v", adjustments, err)
+	}
+	if adjustments[0].Amount != -30.5 || adjustments[1].Amount != 100 {
+		t.Errorf("adjustments not newest first: %+v, %+v", adjustments[0], adjustments[1])
+	}
+
+	if other, err := repos.balances.GetAdjustments(ctx, user.ID+1000); err != nil || len(other) != 0 {
+		t.Errorf("GetAdjustments(other) = %v, %v", other, err)
+	}
+}
+
+func testConcurrentWithdrawContract(t *testing.T, repos repositorySet) {
+	const workers = 20
+
+	ctx := context.Background()
+	user := createUser(t, repos, "alice")
+	if err := repos.balances.AddAccrual(ctx, user.ID, 100, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repos.balances.Withdraw(ctx, user.ID, 30)
+			if err != nil && !errors.Is(err, ErrInsufficientFunds) {
+				t.Error(err)
+				return
+			}
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	balance, err := repos.balances.GetByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if succeeded != 3 || balance.Current != 10 || balance.Withdrawn != 90 {
+		t.Errorf("succeeded = %d, balance = %+v; want 3 withdrawals leaving 10", succeeded, balance)
+	}
+}
+
+func testConcurrentLimitedWithdrawContract(t *testing.T, repos repositorySet) {
+	const workers = 20
+
+	for _, tt := range []struct {
+		login string
+		// limits ограничивают параллельные списания по 10 баллов при
+		// балансе 100.
+		limits models.WithdrawalsConfig
+		want   int
+	}{
+		{"hourly", models.WithdrawalsConfig{MaxPerHour: 3}, 3},
+		{"daily", models.WithdrawalsConfig{MaxDailySum: 45}, 4},
+		{"minbalance", models.WithdrawalsConfig{MinBalance: 75}, 2},
+	} {
+		ctx := context.Background()
+		user := createUser(t, repos, tt.login)
+		if err := repos.balances.AddAccrual(ctx, user.ID, 100, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			succeeded int
+		)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := repos.withdrawals.Withdraw(ctx, user.ID, "2377225624", 10, tt.limits, time.Now())
+				if err != nil && !errors.Is(err, ErrWithdrawalRateExceeded) &&
+					!errors.Is(err, ErrWithdrawalDailyLimit) && !errors.Is(err, ErrWithdrawalMinBalance) {
+					t.Error(err)
+					return
+				}
+				if err == nil {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		balance, err := repos.balances.GetByUserID(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		withdrawals, err := repos.withdrawals.GetByUserID(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := float64(tt.want * 10)
+		if succeeded != tt.want || len(withdrawals) != tt.want || balance.Withdrawn != want || balance.Current != 100-want {
+			t.Errorf("%s: succeeded = %d, withdrawals = %d, balance = %+v; want %d withdrawals",
+				tt.login, succeeded, len(withdrawals), balance, tt.want)
+		}
+	}
+}
+
+func testWithdrawalContract(t *testing.T, repos repositorySet) {
+	ctx := context.Background()
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+
+	first, err := repos.withdrawals.Create(ctx, alice.ID, "2377225624", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.UserID != alice.ID || first.OrderNumber != "2377225624" || first.Sum != 100 || first.ProcessedAt.IsZero() {
+		t.Errorf("created withdrawal = %+v", first)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if _, err := repos.withdrawals.Create(ctx, alice.ID, "12345678903", 50.5); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := repos.withdrawals.GetByUserID(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].OrderNumber != "12345678903" || list[1].OrderNumber != "2377225624" {
+		t.Errorf("GetByUserID = %+v, want newest first", list)
+	}
+
+	if list, _ := repos.withdrawals.GetByUserID(ctx, bob.ID); len(list) != 0 {
+		t.Errorf("GetByUserID(bob) = %+v, want empty", list)
+	}
+
+	if count, sum, err := repos.withdrawals.GetTotals(ctx, alice.ID, first.ProcessedAt.Add(-time.Hour)); err != nil || count != 2 || sum != 150.5 {
+		t.Errorf("GetTotals = %d, %v, %v; want 2, 150.5", count, sum, err)
+	}
+	if count, sum, err := repos.withdrawals.GetTotals(ctx, alice.ID, first.ProcessedAt); err != nil || count != 1 || sum != 50.5 {
+		t.Errorf("GetTotals(after first) = %d, %v, %v; want 1, 50.5", count, sum, err)
+	}
+	if count, sum, err := repos.withdrawals.GetTotals(ctx, bob.ID, first.ProcessedAt.Add(-time.Hour)); err != nil || count != 0 || sum != 0 {
+		t.Errorf("GetTotals(bob) = %d, %v, %v; want zero", count, sum, err)
+	}
+}
+
+func testRewardRuleContract(t *testing.T, repos repositorySet) {
+	ctx := context.Background()
+
+	bork, err := repos.rewards.CreateRule(ctx, &models.RewardRule{Match: "Bork", Reward: 10, RewardType: models.RewardTypePercent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bork.ID == 0 || bork.Match != "Bork" || bork.Reward != 10 || bork.RewardType != models.RewardTypePercent || bork.CreatedAt.IsZero() {
+		t.Errorf("created rule = %+v", bork)
+	}
+
+	if _, err := repos.rewards.CreateRule(ctx, &models.RewardRule{Match: "Bork", Reward: 5, RewardType: models.RewardTypePoints}); !errors.Is(err, ErrRewardRuleExists) {
+		t.Errorf("duplicate CreateRule error = %v, want ErrRewardRuleExists", err)
+	}
+
+	if _, err := repos.rewards.CreateRule(ctx, &models.RewardRule{Match: "LG", Reward: 25.5, RewardType: models.RewardTypePoints}); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := repos.rewards.ListRules(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].Match != "Bork" || rules[1].Match != "LG" || rules[1].Reward != 25.5 {
+		t.Errorf("ListRules = %+v, want Bork then LG", rules)
+	}
+
+	deleted, err := repos.rewards.DeleteRule(ctx, bork.ID)
+	if err != nil || !deleted {
+		t.Fatalf("DeleteRule = %v, %v", deleted, err)
+	}
+	if deleted, _ := repos.rewards.DeleteRule(ctx, bork.ID); deleted {
+		t.Error("second DeleteRule reported a deletion")
+	}
+
+	rules, _ = repos.rewards.ListRules(ctx)
+	if len(rules) != 1 || rules[0].Match != "LG" {
+		t.Errorf("ListRules after delete = %+v", rules)
+	}
+}
+
+func testRegisteredOrderContract(t *testing.T, repos repositorySet) {
+	ctx := context.Background()
+
+	missing, err := repos.rewards.GetRegisteredOrder(ctx, "12345678903")
+	if err != nil || missing != nil {
+		t.Fatalf("GetRegisteredOrder(missing) = %+v, %v", missing, err)
+	}
+
+	order := &models.RegisteredOrder{
+		Number: "12345678903",
+		Goods: []models.OrderGood{
+			{Description: "Чайник Bork", Price: 7000},
+			{Description: "Кружка", Price: 350.5},
+		},
+		Accrual: 700,
+	}
+	registered, err := repos.rewards.RegisterOrder(ctx, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registered.Number != order.Number || registered.Accrual != 700 || registered.RegisteredAt.IsZero() {
+		t.Errorf("registered order = %+v", registered)
+	}
+
+	if _, err := repos.rewards.RegisterOrder(ctx, order); !errors.Is(err, ErrOrderRegistered) {
+		t.Errorf("duplicate RegisterOrder error = %v, want ErrOrderRegistered", err)
+	}
+
+	got, err := repos.rewards.GetRegisteredOrder(ctx, order.Number)
+	if err != nil || got == nil {
+		t.Fatalf("GetRegisteredOrder = %+v, %v", got, err)
+	}
+	if got.Accrual != 700 || len(got.Goods) != 2 || got.Goods[1] != order.Goods[1] {
+		t.Errorf("GetRegisteredOrder = %+v", got)
+	}
+}
+
+func testPointExpirationContract(t *testing.T, repos repositorySet) {
+	ctx := context.Background()
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+
+	now := time.Now().Truncate(time.Second)
+	soon, later := now.Add(time.Hour), now.Add(48*time.Hour)
+
+	// Бессрочные баллы списываются последними, сгорающие — по сроку.
+	for _, lot := range []struct {
+		amount    float64
+		expiresAt *time.Time
+	}{
+		{amount: 100},
+		{amount: 30, expiresAt: &later},
+		{amount: 50, expiresAt: &soon},
+	} {
+		if err := repos.balances.AddAccrual(ctx, alice.ID, lot.amount, lot.expiresAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repos.balances.Adjust(ctx, alice.ID, 20, "goodwill", &soon); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.balances.AddAccrual(ctx, bob.ID, 10, &soon); err != nil {
+		t.Fatal(err)
+	}
+
+	// 60 списываются с партий, сгорающих через час: от 50+20 остаётся 10.
+	if err := repos.balances.Withdraw(ctx, alice.ID, 60); err != nil {
+		t.Fatal(err)
+	}
+
+	expiring, err := repos.balances.GetExpiring(ctx, alice.ID, later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expiring) != 2 || expiring[0].Amount != 10 || !expiring[0].ExpiresAt.Equal(soon) ||
+		expiring[1].Amount != 30 || !expiring[1].ExpiresAt.Equal(later) {
+		t.Errorf("GetExpiring = %s", formatExpiring(expiring))
+	}
+	if expiring, _ := repos.balances.GetExpiring(ctx, alice.ID, now); len(expiring) != 0 {
+		t.Errorf("GetExpiring(now) = %s, want empty", formatExpiring(expiring))
+	}
+
+	if expired, err := repos.balances.Expire(ctx, now); err != nil || expired != 0 {
+		t.Errorf("Expire(now) = %v, %v; want nothing expired", expired, err)
+	}
+	expired, err := repos.balances.Expire(ctx, soon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expired != 20 {
+		t.Errorf("expired = %v, want 10 of alice and 10 of bob", expired)
+	}
+
+	balance, _ := repos.balances.GetByUserID(ctx, alice.ID)
+	if balance.Current != 130 || balance.Withdrawn != 60 {
+		t.Errorf("alice balance = %+v, want current 130", balance)
+	}
+	if balance, _ := repos.balances.GetByUserID(ctx, bob.ID); balance.Current != 0 {
+		t.Errorf("bob balance = %+v, want 0", balance)
+	}
+
+	// Сгоревшее не сгорает повторно, а списание добирает бессрочные баллы.
+	if expired, _ := repos.balances.Expire(ctx, soon); expired != 0 {
+		t.Errorf("second Expire = %v, want 0", expired)
+	}
+	if err := repos.balances.Withdraw(ctx, alice.ID, 50); err != nil {
+		t.Fatal(err)
+	}
+	if expired, _ := repos.balances.Expire(ctx, later); expired != 0 {
+		t.Errorf("Expire(later) = %v, want 0: the 30 expiring later were withdrawn first", expired)
+	}
+	if balance, _ := repos.balances.GetByUserID(ctx, alice.ID); balance.Current != 80 {
+		t.Errorf("alice balance = %+v, want current 80", balance)
+	}
+}
+
+func formatExpiring(expiring []*models.PointsExpiry) string {
+	var parts []string
+	for _, e := range expiring {
+		parts = append(parts, fmt.Sprintf("%v@%s", e.Amount, e.ExpiresAt.Format(time.RFC3339)))
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+func testCampaignContract(t *testing.T, repos repositorySet) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	weekend, err := repos.campaigns.CreateCampaign(ctx, &models.Campaign{
+		Name:       "weekend",
+		StartsAt:   now.Add(-time.Hour),
+		EndsAt:     now.Add(48 * time.Hour),
+		Multiplier: 2,
+		MinAccrual: 100,
+		Tier:       "gold",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weekend.ID == 0 || weekend.Name != "weekend" || weekend.Multiplier != 2 || weekend.MinAccrual != 100 ||
+		weekend.Tier != "gold" || !weekend.StartsAt.Equal(now.Add(-time.Hour)) || weekend.CreatedAt.IsZero() {
+		t.Errorf("created campaign = %+v", weekend)
+	}
+
+	upcoming, err := repos.campaigns.CreateCampaign(ctx, &models.Campaign{
+		Name: "upcoming", StartsAt: now.Add(24 * time.Hour), EndsAt: now.Add(72 * time.Hour), Bonus: 50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	campaigns, err := repos.campaigns.ListCampaigns(ctx)
+	if err != nil || len(campaigns) != 2 || campaigns[0].ID != weekend.ID || campaigns[1].Bonus != 50 {
+		t.Errorf("ListCampaigns = %+v, %v", campaigns, err)
+	}
+
+	active, err := repos.campaigns.ActiveCampaigns(ctx, now)
+	if err != nil || len(active) != 1 || active[0].ID != weekend.ID {
+		t.Errorf("ActiveCampaigns(now) = %+v, %v; want weekend", active, err)
+	}
+	if active, _ := repos.campaigns.ActiveCampaigns(ctx, now.Add(48*time.Hour)); len(active) != 1 || active[0].ID != upcoming.ID {
+		t.Errorf("ActiveCampaigns at weekend end = %+v; want only upcoming, end is exclusive", active)
+	}
+
+	if ended, err := repos.campaigns.EndCampaign(ctx, weekend.ID, now); err != nil || !ended {
+		t.Fatalf("EndCampaign = %v, %v", ended, err)
+	}
+	if ended, err := repos.campaigns.EndCampaign(ctx, upcoming.ID, now); err != nil || !ended {
+		t.Fatalf("EndCampaign(upcoming) = %v, %v", ended, err)
+	}
+	if ended, _ := repos.campaigns.EndCampaign(ctx, upcoming.ID+1000, now); ended {
+		t.Error("EndCampaign of a missing campaign reported success")
+	}
+
+	if active, _ := repos.campaigns.ActiveCampaigns(ctx, now); len(active) != 0 {
+		t.Errorf("ActiveCampaigns after end = %+v, want none", active)
+	}
+	campaigns, _ = repos.campaigns.ListCampaigns(ctx)
+	if !campaigns[0].EndsAt.Equal(now) || !campaigns[1].EndsAt.Equal(campaigns[1].StartsAt) {
+		t.Errorf("ended campaigns = %+v; want weekend ending now and upcoming ending at its start", campaigns)
+	}
+}
+
+func testCampaignBonusContract(t *testing.T, repos repositorySet) {
+	ctx := context.Background()
+	alice := createUser(t, repos, "alice")
+	now := time.Now().Truncate(time.Second)
+
+	campaign, err := repos.campaigns.CreateCampaign(ctx, &models.Campaign{
+		Name: "weekend", StartsAt: now, EndsAt: now.Add(time.Hour), Multiplier: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, number := range []string{"12345678903", "2377225624"} {
+		if _, _, err := repos.orders.CreateOrGet(ctx, number, alice.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repos.balances.AddAccrual(ctx, alice.ID, 100, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expiresAt := now.Add(24 * time.Hour)
+	first, err := repos.campaigns.AddBonus(ctx, &models.CampaignBonus{
+		CampaignID: campaign.ID, UserID: alice.ID, OrderNumber: "12345678903", Amount: 100,
+	}, &expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID == 0 || first.Amount != 100 || first.CreatedAt.IsZero() || first.ReversedAt != nil {
+		t.Errorf("added bonus = %+v", first)
+	}
+
+	if _, err := repos.campaigns.AddBonus(ctx, &models.CampaignBonus{
+		CampaignID: campaign.ID, UserID: alice.ID, OrderNumber: "12345678903", Amount: 100,
+	}, nil); !errors.Is(err, ErrBonusExists) {
+		t.Errorf("duplicate AddBonus error = %v, want ErrBonusExists", err)
+	}
+	if _, err := repos.campaigns.AddBonus(ctx, &models.CampaignBonus{
+		CampaignID: campaign.ID, UserID: alice.ID, OrderNumber: "2377225624", Amount: 20.5,
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	balance, _ := repos.balances.GetByUserID(ctx, alice.ID)
+	if balance.Current != 220.5 {
+		t.Errorf("balance after bonuses = %v, want 220.5", balance.Current)
+	}
+	expiring, _ := repos.balances.GetExpiring(ctx, alice.ID, expiresAt)
+	if len(expiring) != 1 || expiring[0].Amount != 100 {
+		t.Errorf("expiring = %+v, want the first bonus", expiring)
+	}
+
+	bonuses, err := repos.campaigns.GetBonuses(ctx, alice.ID)
+	if err != nil || len(bonuses) != 2 || bonuses[0].OrderNumber != "2377225624" || bonuses[1].Campaign != "weekend" {
+		t.Errorf("GetBonuses = %+v, %v; want newest first with campaign name", bonuses, err)
+	}
+
+	reversed, err := repos.campaigns.ReverseBonus(ctx, first.ID)
+	if err != nil || reversed == nil || reversed.ReversedAt == nil || reversed.Amount != 100 {
+		t.Fatalf("ReverseBonus = %+v, %v", reversed, err)
+	}
+	if _, err := repos.campaigns.ReverseBonus(ctx, first.ID); !errors.Is(err, ErrBonusReversed) {
+		t.Errorf("second ReverseBonus error = %v, want ErrBonusReversed", err)
+	}
+	if missing, err := repos.campaigns.ReverseBonus(ctx, first.ID+1000); missing != nil || err != nil {
+		t.Errorf("ReverseBonus(missing) = %+v, %v; want nil, nil", missing, err)
+	}
+
+	// Отмена списывает сначала баллы, которые сгорают раньше.
+	balance, _ = repos.balances.GetByUserID(ctx, alice.ID)
+	if balance.Current != 120.5 || balance.Withdrawn != 0 {
+		t.Errorf("balance after reversal = %+v, want current 120.5", balance)
+	}
+	if expiring, _ := repos.balances.GetExpiring(ctx, alice.ID, expiresAt); len(expiring) != 0 {
+		t.Errorf("expiring after reversal = %+v, want none", expiring)
+	}
+
+	if err := repos.balances.Withdraw(ctx, alice.ID, 110); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.campaigns.ReverseBonus(ctx, bonuses[0].ID); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("ReverseBonus with spent points error = %v, want ErrInsufficientFunds", err)
+	}
+	if bonuses, _ := repos.campaigns.GetBonuses(ctx, alice.ID); bonuses[0].ReversedAt != nil {
+		t.Error("failed reversal marked the bonus reversed")
+	}
+}
+
+func testReferralContract(t *testing.T, repos repositorySet) {
+	ctx := context.Background()
+	alice := createUser(t, repos, "alice")
+
+	invite := func(login string) *models.User {
+		t.Helper()
+		user, err := repos.users.Create(ctx, &models.User{
+			Login:        login,
+			PasswordHash: "hash",
+			ReferralCode: strings.ToUpper(login),
+			ReferredBy:   &alice.ID,
+		})
+		if err != nil {
+			t.Fatalf("create user %q: %v", login, err)
+		}
+		return user
+	}
+	bob := invite("bob")
+	carol := invite("carol")
+	dave := invite("dave")
+
+	if byID, err := repos.users.GetByID(ctx, bob.ID); err != nil || byID.ReferredBy == nil || *byID.ReferredBy != alice.ID {
+		t.Fatalf("referee = %+v, %v; want referred by alice", byID, err)
+	}
+
+	for _, o := range []struct {
+		number string
+		userID int
+	}{{"12345678903", bob.ID}, {"2377225624", carol.ID}, {"79927398713", dave.ID}} {
+		if _, _, err := repos.orders.CreateOrGet(ctx, o.number, o.userID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expiresAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	reward, err := repos.referrals.AddReward(ctx, &models.ReferralReward{
+		RefereeID: bob.ID, ReferrerID: alice.ID, OrderNumber: "12345678903", ReferrerAmount: 100, RefereeAmount: 50,
+	}, models.ReferralConfig{MaxRewards: 1}, &expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reward.CreatedAt.IsZero() || reward.ReferrerAmount != 100 {
+		t.Errorf("reward = %+v", reward)
+	}
+	if _, err := repos.referrals.AddReward(ctx, &models.ReferralReward{
+		RefereeID: bob.ID, ReferrerID: alice.ID, OrderNumber: "12345678903", ReferrerAmount: 100, RefereeAmount: 50,
+	}, models.ReferralConfig{}, nil); !errors.Is(err, ErrReferralRewarded) {
+		t.Errorf("second reward error = %v, want ErrReferralRewarded", err)
+	}
+
+	// Вознаграждение сверх общего или суточного лимита записывается без
+	// баллов.
+	for _, limited := range []struct {
+		referee *models.User
+		number  string
+		limits  models.ReferralConfig
+	}{
+		{carol, "2377225624", models.ReferralConfig{MaxRewards: 1}},
+		{dave, "79927398713", models.ReferralConfig{MaxRewards: 5, MaxDailyRewards: 1}},
+	} {
+		reward, err := repos.referrals.AddReward(ctx, &models.ReferralReward{
+			RefereeID: limited.referee.ID, ReferrerID: alice.ID, OrderNumber: limited.number, ReferrerAmount: 100, RefereeAmount: 50,
+		}, limited.limits, nil)
+		if err != nil || !reward.Limited || reward.ReferrerAmount != 0 || reward.RefereeAmount != 0 {
+			t.Errorf("%s reward = %+v, %v; want limited without points", limited.referee.Login, reward, err)
+		}
+	}
+
+	for user, want := range map[*models.User]float64{alice: 100, bob: 50, carol: 0, dave: 0} {
+		if balance, err := repos.balances.GetByUserID(ctx, user.ID); err != nil || balance.Current != want {
+			t.Errorf("%s balance = %+v, %v; want %v", user.Login, balance, err, want)
+		}
+	}
+	if expired, err := repos.balances.Expire(ctx, expiresAt.Add(time.Second)); err != nil || expired != 150 {
+		t.Errorf("Expire = %v, %v; want both reward lots burnt", expired, err)
+	}
+
+	status, err := repos.referrals.GetStats(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *status != (models.ReferralStatus{Invited: 3, Rewarded: 1, Earned: 100}) {
+		t.Errorf("stats = %+v", status)
+	}
+	if status, err := repos.referrals.GetStats(ctx, bob.ID); err != nil || *status != (models.ReferralStatus{}) {
+		t.Errorf("stats without referrals = %+v, %v", status, err)
+	}
+}
+
+func testConcurrentReferralContract(t *testing.T, repos repositorySet) {
+	const referees = 10
+
+	ctx := context.Background()
+	alice := createUser(t, repos, "alice")
+	ids := make([]int, referees)
+	for i := range ids {
+		user, err := repos.users.Create(ctx, &models.User{
+			Login:        fmt.Sprintf("referee%d", i),
+			PasswordHash: "hash",
+			ReferralCode: fmt.Sprintf("REFEREE%d", i),
+			ReferredBy:   &alice.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = user.ID
+	}
+	if _, _, err := repos.orders.CreateOrGet(ctx, "12345678903", alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		rewarded int
+	)
+	for _, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reward, err := repos.referrals.AddReward(ctx, &models.ReferralReward{
+				RefereeID: id, ReferrerID: alice.ID, OrderNumber: "12345678903", ReferrerAmount: 100, RefereeAmount: 50,
+			}, models.ReferralConfig{MaxRewards: 3}, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !reward.Limited {
+				mu.Lock()
+				rewarded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	balance, err := repos.balances.GetByUserID(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rewarded != 3 || balance.Current != 300 {
+		t.Errorf("rewarded = %d, referrer balance = %v; want 3 rewards and 300", rewarded, balance.Current)
+	}
+}
+
+func testTransferContract(t *testing.T, repos repositorySet) {
+	ctx := context.Background()
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+	carol := createUser(t, repos, "carol")
+
+	expiresAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	if err := repos.balances.AddAccrual(ctx, alice.ID, 100, &expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.balances.AddAccrual(ctx, alice.ID, 200, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	limits := models.TransfersConfig{MaxDailySum: 250, MaxDailyCount: 2}
+	transfer, err := repos.transfers.Transfer(ctx, alice.ID, bob.ID, 150, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transfer.ID == 0 || transfer.CreatedAt.IsZero() || transfer.Sum != 150 || transfer.Direction != models.TransferOut {
+		t.Errorf("transfer = %+v", transfer)
+	}
+
+	if _, err := repos.transfers.Transfer(ctx, bob.ID, carol.ID, 151, limits); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("overdraft error = %v, want ErrInsufficientFunds", err)
+	}
+	if _, err := repos.transfers.Transfer(ctx, alice.ID, carol.ID, 101, limits); !errors.Is(err, ErrTransferLimitExceeded) {
+		t.Errorf("daily sum error = %v, want ErrTransferLimitExceeded", err)
+	}
+	if _, err := repos.transfers.Transfer(ctx, alice.ID, carol.ID, 100, limits); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.transfers.Transfer(ctx, alice.ID, carol.ID, 10, models.TransfersConfig{MaxDailyCount: 2}); !errors.Is(err, ErrTransferLimitExceeded) {
+		t.Errorf("daily count error = %v, want ErrTransferLimitExceeded", err)
+	}
+
+	for user, want := range map[*models.User]float64{alice: 50, bob: 150, carol: 100} {
+		if balance, err := repos.balances.GetByUserID(ctx, user.ID); err != nil || balance.Current != want || balance.Withdrawn != 0 {
+			t.Errorf("%s balance = %+v, %v; want current %v", user.Login, balance, err, want)
+		}
+	}
+
+	// Бобу достались сгорающие баллы Алисы вместе со сроком сгорания.
+	if expired, err := repos.balances.Expire(ctx, expiresAt.Add(time.Second)); err != nil || expired != 100 {
+		t.Errorf("Expire = %v, %v; want 100 transferred expiring points", expired, err)
+	}
+	if balance, err := repos.balances.GetByUserID(ctx, bob.ID); err != nil || balance.Current != 50 {
+		t.Errorf("bob balance after expiry = %+v, %v; want 50", balance, err)
+	}
+
+	transfers, err := repos.transfers.GetTransfers(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 2 || transfers[0].To != "carol" || transfers[1].To != "bob" ||
+		transfers[0].From != "alice" || transfers[0].Direction != models.TransferOut {
+		t.Fatalf("alice transfers = %+v", transfers)
+	}
+	transfers, err = repos.transfers.GetTransfers(ctx, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 1 || transfers[0].ID != transfer.ID || transfers[0].From != "alice" || transfers[0].Direction != models.TransferIn {
+		t.Errorf("bob transfers = %+v", transfers)
+	}
+
+	// Сумма перевода хранится с точностью до копеек.
+	if rounded, err := repos.transfers.Transfer(ctx, bob.ID, carol.ID, 10.004, models.TransfersConfig{}); err != nil || rounded.Sum != 10 {
+		t.Errorf("rounded transfer = %+v, %v; want sum 10", rounded, err)
+	}
+	if balance, err := repos.balances.GetByUserID(ctx, bob.ID); err != nil || balance.Current != 40 {
+		t.Errorf("bob balance after rounded transfer = %+v, %v; want 40", balance, err)
+	}
+}
@@ -16,6 +16,9 @@ type (
 		logger *logrus.Logger
 	}
 	DatabaseConfig struct {
+		// URL — строка подключения (DATABASE_URI). Если задана, отдельные
+		// параметры подключения не используются.
+		URL             string
 		Host            string
 		Port            int
 		User            string
@@ -30,8 +33,11 @@ type (
 )
 
 func NewDB(cfg *DatabaseConfig, logger *logrus.Logger) (*DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Database, cfg.SSLMode)
+	dsn := cfg.URL
+	if dsn == "" {
+		dsn = fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Database, cfg.SSLMode)
+	}
 
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
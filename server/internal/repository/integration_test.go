@@ -0,0 +1,588 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// Интеграционные тесты репозиториев на PostgreSQL:
+//
+//	go test -tags integration ./internal/repository/
+//
+// База берётся из DATABASE_URI (все данные в ней удаляются), иначе
+// поднимается временный кластер из локально установленных initdb и pg_ctl.
+// Если ни то ни другое недоступно, тесты пропускаются.
+
+var integrationDB *DB
+
+func TestMain(m *testing.M) {
+	os.Exit(runIntegration(m))
+}
+
+func runIntegration(m *testing.M) int {
+	uri := os.Getenv("DATABASE_URI")
+	if uri == "" {
+		var (
+			stop func()
+			err  error
+		)
+		uri, stop, err = startLocalPostgres()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "integration: PostgreSQL is unavailable, tests will be skipped: %v\n", err)
+			return m.Run()
+		}
+		defer stop()
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	db, err := NewDB(&DatabaseConfig{URL: uri, MaxOpenConns: 20, MaxIdleConns: 20}, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "integration: connect: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := db.RunMigrations(); err != nil {
+		fmt.Fprintf(os.Stderr, "integration: migrate: %v\n", err)
+		return 1
+	}
+
+	integrationDB = db
+	return m.Run()
+}
+
+// startLocalPostgres поднимает временный кластер PostgreSQL на unix-сокете.
+func startLocalPostgres() (string, func(), error) {
+	initdb, err := findPostgresBinary("initdb")
+	if err != nil {
+		return "", nil, err
+	}
+	pgCtl := filepath.Join(filepath.Dir(initdb), "pg_ctl")
+
+	dir, err := os.MkdirTemp("", "gophermart-pg-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+
+	data := filepath.Join(dir, "data")
+	if out, err := exec.Command(initdb, "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-locale").CombinedOutput(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("initdb: %w: %s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	opts := fmt.Sprintf("-p %d -k %s -c listen_addresses=''", port, dir)
+	if out, err := exec.Command(pgCtl, "-D", data, "-o", opts, "-l", filepath.Join(dir, "log"), "-w", "start").CombinedOutput(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("pg_ctl start: %w: %s", err, out)
+	}
+
+	stop := func() {
+		_ = exec.Command(pgCtl, "-D", data, "-m", "immediate", "-w", "stop").Run()
+		cleanup()
+	}
+	return fmt.Sprintf("host=%s port=%d user=postgres dbname=postgres sslmode=disable", dir, port), stop, nil
+}
+
+func findPostgresBinary(name string) (string, error) {
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+
+	for _, pattern := range []string{
+		"/usr/lib/postgresql/*/bin/" + name,
+		"/usr/local/opt/postgresql*/bin/" + name,
+		"/opt/homebrew/opt/postgresql*/bin/" + name,
+	} {
+		matches, _ := filepath.Glob(pattern)
+		if len(matches) > 0 {
+			sort.Strings(matches)
+			return matches[len(matches)-1], nil
+		}
+	}
+
+	return "", fmt.Errorf("%s not found", name)
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = l.Close() }()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// postgresRepositories очищает базу и возвращает репозитории поверх неё.
+func postgresRepositories(t *testing.T) repositorySet {
+	t.Helper()
+
+	if integrationDB == nil {
+		t.Skip("PostgreSQL is unavailable")
+	}
+	if _, err := integrationDB.Exec(`TRUNCATE users, orders, balance, withdrawals, balance_adjustments, reward_rules, registered_orders, point_lots, campaigns, campaign_bonuses, referral_rewards, transfers RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+
+	return repositorySet{
+		users:       NewUserRepository(integrationDB),
+		orders:      NewOrderRepository(integrationDB),
+		balances:    NewBalanceRepository(integrationDB),
+		withdrawals: NewWithdrawalRepository(integrationDB),
+		rewards:     NewRewardRepository(integrationDB),
+		campaigns:   NewCampaignRepository(integrationDB),
+		referrals:   NewReferralRepository(integrationDB),
+		transfers:   NewTransferRepository(integrationDB),
+	}
+}
+
+func TestPostgresRepositories(t *testing.T) {
+	runRepositoryContract(t, postgresRepositories)
+}
+
+func TestPostgres_UniqueViolations(t *testing.T) {
+	repos := postgresRepositories(t)
+	ctx := context.Background()
+
+	createUser(t, repos, "alice")
+
+	_, err := repos.users.Create(ctx, &models.User{Login: "alice", PasswordHash: "other", ReferralCode: "OTHER"})
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		t.Errorf("duplicate login error = %v, want unique_violation", err)
+	}
+}
+
+func TestPostgres_ForeignKeys(t *testing.T) {
+	repos := postgresRepositories(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"order", func() error { _, _, err := repos.orders.CreateOrGet(ctx, "12345678903", 999); return err }},
+		{"withdrawal", func() error { _, err := repos.withdrawals.Create(ctx, 999, "2377225624", 10); return err }},
+		{"balance", func() error { return repos.balances.AddAccrual(ctx, 999, 10, nil) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			var pqErr *pq.Error
+			if !errors.As(err, &pqErr) || pqErr.Code != "23503" {
+				t.Errorf("error = %v, want foreign_key_violation", err)
+			}
+		})
+	}
+}
+
+func TestPostgres_CascadeOnUserDelete(t *testing.T) {
+	repos := postgresRepositories(t)
+	ctx := context.Background()
+
+	user := createUser(t, repos, "alice")
+	if _, _, err := repos.orders.CreateOrGet(ctx, "12345678903", user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.balances.AddAccrual(ctx, user.ID, 10, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.withdrawals.Create(ctx, user.ID, "2377225624", 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := integrationDB.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, user.ID); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+
+	for _, table := range []string{"orders", "balance", "withdrawals"} {
+		var n int
+		if err := integrationDB.QueryRowContext(ctx, `SELECT count(*) FROM `+table).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("%s has %d rows after user delete, want 0", table, n)
+		}
+	}
+}
+
+func TestPostgres_AmountsRoundedToCents(t *testing.T) {
+	repos := postgresRepositories(t)
+	ctx := context.Background()
+
+	user := createUser(t, repos, "alice")
+	if err := repos.balances.AddAccrual(ctx, user.ID, 10.005, nil); err != nil {
+		t.Fatal(err)
+	}
+	order, _, err := repos.orders.CreateOrGet(ctx, "12345678903", user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.orders.UpdateStatusByID(ctx, order.ID, models.OrderStatusProcessed, 729.984); err != nil {
+		t.Fatal(err)
+	}
+
+	balance, _ := repos.balances.GetByUserID(ctx, user.ID)
+	if balance.Current != 10.01 {
+		t.Errorf("current = %v, want 10.01", balance.Current)
+	}
+	got, _ := repos.orders.GetByNumber(ctx, order.Number)
+	if got.Accrual == nil || *got.Accrual != 729.98 {
+		t.Errorf("accrual = %v, want 729.98", got.Accrual)
+	}
+}
+
+func TestPostgres_UpdateStatusClearsAccrual(t *testing.T) {
+	repos := postgresRepositories(t)
+	ctx := context.Background()
+
+	user := createUser(t, repos, "alice")
+	order, _, err := repos.orders.CreateOrGet(ctx, "12345678903", user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.orders.UpdateStatusByID(ctx, order.ID, models.OrderStatusProcessed, 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.orders.UpdateStatus(ctx, order.Number, models.OrderStatusInvalid, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := repos.orders.GetByNumber(ctx, order.Number)
+	if got.Status != models.OrderStatusInvalid || got.Accrual != nil {
+		t.Errorf("order = %+v, want INVALID without accrual", got)
+	}
+}
+
+// TestPostgres_ConcurrentBatches загружает пересекающиеся пакеты параллельно:
+// каждый номер должен получить ровно одного владельца и ни один пакет не
+// должен завершиться ошибкой.
+func TestPostgres_ConcurrentBatches(t *testing.T) {
+	const users = 8
+
+	repos := postgresRepositories(t)
+	ctx := context.Background()
+
+	numbers := []string{"12345678903", "9278923470", "346436439", "2377225624"}
+	ids := make([]int, users)
+	for i := range ids {
+		ids[i] = createUser(t, repos, fmt.Sprintf("user%d", i)).ID
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created = make(map[string]int)
+		owners  = make(map[string]map[int]bool)
+	)
+	for _, userID := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, err := repos.orders.CreateBatch(ctx, numbers, userID)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, res := range results {
+				if res.Created {
+					created[res.Number]++
+				}
+				if owners[res.Number] == nil {
+					owners[res.Number] = make(map[int]bool)
+				}
+				owners[res.Number][res.OwnerID] = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, number := range numbers {
+		if created[number] != 1 || len(owners[number]) != 1 || owners[number][0] {
+			t.Errorf("%s: created %d times, owners %v", number, created[number], owners[number])
+		}
+	}
+}
+
+// TestPostgres_ConcurrentUploads загружает одни и те же номера параллельно от
+// разных пользователей: вставка проходит ровно один раз, а проигравшие
+// конкурентную вставку получают из повторного чтения тот же заказ.
+func TestPostgres_ConcurrentUploads(t *testing.T) {
+	const (
+		users   = 4
+		uploads = 5
+	)
+
+	repos := postgresRepositories(t)
+	ctx := context.Background()
+
+	numbers := []string{"12345678903", "9278923470", "346436439"}
+	ids := make([]int, users)
+	for i := range ids {
+		ids[i] = createUser(t, repos, fmt.Sprintf("user%d", i)).ID
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created = make(map[string]int)
+		orders  = make(map[string]map[[2]int]bool)
+	)
+	for _, number := range numbers {
+		for _, userID := range ids {
+			for range uploads {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					order, ok, err := repos.orders.CreateOrGet(ctx, number, userID)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					if order.Number != number || order.Status != models.OrderStatusNew || order.UploadedAt.IsZero() {
+						t.Errorf("order = %+v", order)
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					if ok {
+						created[number]++
+					}
+					if orders[number] == nil {
+						orders[number] = make(map[[2]int]bool)
+					}
+					orders[number][[2]int{order.ID, order.UserID}] = true
+				}()
+			}
+		}
+	}
+	wg.Wait()
+
+	for _, number := range numbers {
+		if created[number] != 1 || len(orders[number]) != 1 {
+			t.Errorf("%s: created %d times, distinct orders %v", number, created[number], orders[number])
+		}
+	}
+}
+
+// schemaSnapshot описывает столбцы, ограничения и индексы публичной схемы.
+func schemaSnapshot(t *testing.T) []string {
+	t.Helper()
+
+	rows, err := integrationDB.Query(`
+        SELECT 'column ' || table_name || '.' || column_name || ' ' || data_type || ' ' || is_nullable || ' ' || coalesce(column_default, '')
+        FROM information_schema.columns
+        WHERE table_schema = 'public' AND table_name <> 'schema_migrations'
+        UNION ALL
+        SELECT 'constraint ' || conrelid::regclass || ' ' || conname || ' ' || pg_get_constraintdef(oid)
+        FROM pg_constraint
+        WHERE connamespace = 'public'::regnamespace
+        UNION ALL
+        SELECT 'index ' || indexdef
+        FROM pg_indexes
+        WHERE schemaname = 'public' AND tablename <> 'schema_migrations'
+        ORDER BY 1
+    `)
+	if err != nil {
+		t.Fatalf("snapshot schema: %v", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var snapshot []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			t.Fatal(err)
+		}
+		snapshot = append(snapshot, line)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return snapshot
+}
+
+func newTestMigrator(t *testing.T) *Migrator {
+	t.Helper()
+
+	if integrationDB == nil {
+		t.Skip("PostgreSQL is unavailable")
+	}
+	m, err := integrationDB.NewMigrator(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = m.Close() })
+	return m
+}
+
+// TestMigrations_UpDownUp откатывает миграции по одной до пустой базы и
+// применяет их снова: схема должна совпасть с исходной.
+func TestMigrations_UpDownUp(t *testing.T) {
+	m := newTestMigrator(t)
+
+	latest, err := LatestMigrationVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := schemaSnapshot(t)
+
+	for v := latest; v > 0; v-- {
+		if err := m.Down(1); err != nil {
+			t.Fatalf("down from %d: %v", v, err)
+		}
+		status, err := m.Status()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Version != v-1 || status.Dirty {
+			t.Fatalf("after down from %d: %+v", v, status)
+		}
+	}
+	if tables := schemaSnapshot(t); len(tables) != 0 {
+		t.Fatalf("schema not empty after full down: %v", tables)
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	status, err := m.Check()
+	if err != nil || status.Version != latest {
+		t.Fatalf("after up: %+v, %v", status, err)
+	}
+
+	after := schemaSnapshot(t)
+	if strings.Join(before, "\n") != strings.Join(after, "\n") {
+		t.Errorf("schema differs after up→down→up\nbefore:\n%s\nafter:\n%s", strings.Join(before, "\n"), strings.Join(after, "\n"))
+	}
+}
+
+func TestMigrations_Check(t *testing.T) {
+	m := newTestMigrator(t)
+
+	latest, err := LatestMigrationVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := m.Force(int(latest)); err != nil {
+			t.Errorf("restore version: %v", err)
+		}
+	})
+
+	if err := m.Force(int(latest) + 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Check(); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("newer schema: error = %v, want ErrSchemaTooNew", err)
+	}
+	if err := integrationDB.RunMigrations(); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("RunMigrations on newer schema: error = %v, want ErrSchemaTooNew", err)
+	}
+
+	if err := m.Force(int(latest)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := integrationDB.Exec(`UPDATE schema_migrations SET dirty = true`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Check(); !errors.Is(err, ErrSchemaDirty) {
+		t.Errorf("dirty schema: error = %v, want ErrSchemaDirty", err)
+	}
+}
+
+func TestPostgres_CheckConstraints(t *testing.T) {
+	repos := postgresRepositories(t)
+	ctx := context.Background()
+
+	user := createUser(t, repos, "alice")
+	order, _, err := repos.orders.CreateOrGet(ctx, "12345678903", user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"unknown status", func() error { return repos.orders.UpdateStatus(ctx, order.Number, "DONE", nil) }},
+		{"negative accrual", func() error {
+			return repos.orders.UpdateStatusByID(ctx, order.ID, models.OrderStatusProcessed, -1)
+		}},
+		{"negative balance", func() error { return repos.balances.AddAccrual(ctx, user.ID, -1, nil) }},
+		{"non-positive withdrawal", func() error { _, err := repos.withdrawals.Create(ctx, user.ID, "2377225624", 0); return err }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			var pqErr *pq.Error
+			if !errors.As(err, &pqErr) || pqErr.Code != "23514" {
+				t.Errorf("error = %v, want check_violation", err)
+			}
+		})
+	}
+}
+
+// TestPostgres_ExpireContinuesAfterUserFailure проверяет, что ошибка сжигания
+// у одного пользователя не мешает сжечь баллы остальных.
+func TestPostgres_ExpireContinuesAfterUserFailure(t *testing.T) {
+	repos := postgresRepositories(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	expired := now.Add(-time.Hour)
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+	for _, id := range []int{alice.ID, bob.ID} {
+		if err := repos.balances.AddAccrual(ctx, id, 100, &expired); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Баланс меньше сгорающей партии: списание у alice нарушит ограничение.
+	if _, err := integrationDB.Exec(`UPDATE balance SET current = 50 WHERE user_id = $1`, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	total, err := repos.balances.Expire(ctx, now)
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23514" {
+		t.Errorf("Expire error = %v, want check_violation", err)
+	}
+	if total != 100 {
+		t.Errorf("expired = %v, want 100 from bob", total)
+	}
+	if balance, _ := repos.balances.GetByUserID(ctx, bob.ID); balance.Current != 0 {
+		t.Errorf("bob balance = %+v, want expired", balance)
+	}
+	if balance, _ := repos.balances.GetByUserID(ctx, alice.ID); balance.Current != 50 {
+		t.Errorf("alice balance = %+v, want unchanged", balance)
+	}
+}
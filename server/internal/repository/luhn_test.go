@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateLuhn(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{"12345678903", true},
+		{"9278923470", true},
+		{"346436439", true},
+		{"2377225624", true},
+		{"0", true},
+		{"18", true},
+		{"12345678900", false},
+		{"2377225625", false},
+		{"1", false},
+		{"", false},
+		{"1234-5678", false},
+		{" 12345678903", false},
+		{"12345678903\n", false},
+		{"-0", false},
+		{"１８", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidateLuhn(tt.number); got != tt.want {
+			t.Errorf("ValidateLuhn(%q) = %v, want %v", tt.number, got, tt.want)
+		}
+	}
+}
+
+// FuzzValidateLuhn проверяет свойства алгоритма: для любой строки цифр ровно
+// одна контрольная цифра делает номер корректным, а строки с нецифровыми
+// символами всегда отклоняются.
+func FuzzValidateLuhn(f *testing.F) {
+	for _, seed := range []string{"", "0", "1234567890", "12345678903", "abc", "12 34"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		digitsOnly := s != "" && strings.Trim(s, "0123456789") == ""
+		if !digitsOnly {
+			if ValidateLuhn(s) {
+				t.Fatalf("ValidateLuhn(%q) = true for non-digit input", s)
+			}
+			if s != "" {
+				return
+			}
+		}
+
+		valid := 0
+		for d := '0'; d <= '9'; d++ {
+			if ValidateLuhn(s + string(d)) {
+				valid++
+			}
+		}
+		if valid != 1 {
+			t.Fatalf("%d check digits are valid for %q, want exactly 1", valid, s)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"sync"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+// MemoryStore хранит данные in-memory репозиториев. Все репозитории,
+// созданные поверх одного хранилища, видят общие данные и защищены общей
+// блокировкой, как таблицы одной базы.
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	users        map[int]*models.User
+	usersByLogin map[string]int
+	usersByCode  map[string]int
+	orders       map[string]*models.Order
+	balances     map[int]*models.Balance
+	withdrawals  []*models.Withdrawal
+	adjustments  []*models.BalanceAdjustment
+	pointLots    []*models.PointLot
+
+	rewardRules      []*models.RewardRule
+	registeredOrders map[string]*models.RegisteredOrder
+
+	campaigns       []*models.Campaign
+	campaignBonuses []*models.CampaignBonus
+
+	referralRewards map[int]*models.ReferralReward
+
+	transfers []*models.Transfer
+
+	nextUserID       int
+	nextOrderID      int
+	nextWithdrawalID int
+	nextAdjustmentID int
+	nextPointLotID   int
+	nextRewardRuleID int
+
+	nextCampaignID      int
+	nextCampaignBonusID int
+	nextTransferID      int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:        make(map[int]*models.User),
+		usersByLogin: make(map[string]int),
+		usersByCode:  make(map[string]int),
+		orders:       make(map[string]*models.Order),
+		balances:     make(map[int]*models.Balance),
+
+		registeredOrders: make(map[string]*models.RegisteredOrder),
+		referralRewards:  make(map[int]*models.ReferralReward),
+	}
+}
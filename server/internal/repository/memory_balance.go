@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+type memoryBalanceRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryBalanceRepository(store *MemoryStore) BalanceRepository {
+	return &memoryBalanceRepository{store: store}
+}
+
+func (r *memoryBalanceRepository) GetByUserID(_ context.Context, userID int) (*models.Balance, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	balance, ok := r.store.balances[userID]
+	if !ok {
+		return &models.Balance{UserID: userID, Current: 0, Withdrawn: 0}, nil
+	}
+	b := *balance
+	return &b, nil
+}
+
+func (r *memoryBalanceRepository) Create(_ context.Context, userID int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.balance(userID)
+	return nil
+}
+
+func (r *memoryBalanceRepository) AddAccrual(_ context.Context, userID int, amount float64, expiresAt *time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	amount = roundCents(amount)
+	balance := r.balance(userID)
+	balance.Current = roundCents(balance.Current + amount)
+	r.addLot(userID, amount, expiresAt)
+	return nil
+}
+
+func (r *memoryBalanceRepository) Withdraw(_ context.Context, userID int, amount float64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	balance, ok := r.store.balances[userID]
+	if !ok || balance.Current < amount {
+		return ErrInsufficientFunds
+	}
+
+	amount = roundCents(amount)
+	balance.Current = roundCents(balance.Current - amount)
+	balance.Withdrawn = roundCents(balance.Withdrawn + amount)
+	r.consumeLots(userID, amount)
+	return nil
+}
+
+func (r *memoryBalanceRepository) Adjust(_ context.Context, userID int, amount float64, reason string, expiresAt *time.Time) (*models.BalanceAdjustment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	amount = roundCents(amount)
+	balance := r.balance(userID)
+	if balance.Current+amount < 0 {
+		return nil, ErrInsufficientFunds
+	}
+	balance.Current = roundCents(balance.Current + amount)
+	if amount > 0 {
+		r.addLot(userID, amount, expiresAt)
+	} else {
+		r.consumeLots(userID, -amount)
+	}
+
+	r.store.nextAdjustmentID++
+	adjustment := &models.BalanceAdjustment{
+		ID:        r.store.nextAdjustmentID,
+		UserID:    userID,
+		Amount:    amount,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	r.store.adjustments = append(r.store.adjustments, adjustment)
+
+	a := *adjustment
+	return &a, nil
+}
+
+func (r *memoryBalanceRepository) GetAdjustments(_ context.Context, userID int) ([]*models.BalanceAdjustment, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var adjustments []*models.BalanceAdjustment
+	for i := len(r.store.adjustments) - 1; i >= 0; i-- {
+		if a := *r.store.adjustments[i]; a.UserID == userID {
+			adjustments = append(adjustments, &a)
+		}
+	}
+	return adjustments, nil
+}
+
+func (r *memoryBalanceRepository) GetExpiring(_ context.Context, userID int, before time.Time) ([]*models.PointsExpiry, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	byTime := make(map[time.Time]*models.PointsExpiry)
+	var expiring []*models.PointsExpiry
+	for _, lot := range r.store.pointLots {
+		if lot.UserID != userID || lot.Remaining <= 0 || lot.ExpiresAt == nil || lot.ExpiresAt.After(before) {
+			continue
+		}
+		e, ok := byTime[*lot.ExpiresAt]
+		if !ok {
+			e = &models.PointsExpiry{ExpiresAt: *lot.ExpiresAt}
+			byTime[*lot.ExpiresAt] = e
+			expiring = append(expiring, e)
+		}
+		e.Amount = roundCents(e.Amount + lot.Remaining)
+	}
+	slices.SortFunc(expiring, func(a, b *models.PointsExpiry) int {
+		return a.ExpiresAt.Compare(b.ExpiresAt)
+	})
+	return expiring, nil
+}
+
+func (r *memoryBalanceRepository) Expire(_ context.Context, now time.Time) (float64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var total float64
+	for _, lot := range r.store.pointLots {
+		if lot.Remaining <= 0 || lot.ExpiresAt == nil || lot.ExpiresAt.After(now) {
+			continue
+		}
+		balance := r.balance(lot.UserID)
+		balance.Current = roundCents(balance.Current - lot.Remaining)
+		total += lot.Remaining
+		lot.Expired, lot.Remaining = lot.Remaining, 0
+	}
+	return roundCents(total), nil
+}
+
+// addLot записывает партию баллов. Вызывается под блокировкой хранилища.
+func (r *memoryBalanceRepository) addLot(userID int, amount float64, expiresAt *time.Time) {
+	r.store.nextPointLotID++
+	r.store.pointLots = append(r.store.pointLots, &models.PointLot{
+		ID:        r.store.nextPointLotID,
+		UserID:    userID,
+		Amount:    amount,
+		Remaining: amount,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	})
+}
+
+// consumeLots уменьшает остатки партий на amount, начиная с тех, что сгорают
+// раньше; бессрочные партии списываются последними. Возвращает списанные
+// части партий, как consumeLots для PostgreSQL. Вызывается под блокировкой
+// хранилища.
+func (r *memoryBalanceRepository) consumeLots(userID int, amount float64) []models.PointLot {
+	var lots []*models.PointLot
+	for _, lot := range r.store.pointLots {
+		if lot.UserID == userID && lot.Remaining > 0 {
+			lots = append(lots, lot)
+		}
+	}
+	slices.SortStableFunc(lots, func(a, b *models.PointLot) int {
+		switch {
+		case a.ExpiresAt == nil && b.ExpiresAt == nil:
+			return cmp.Compare(a.ID, b.ID)
+		case a.ExpiresAt == nil:
+			return 1
+		case b.ExpiresAt == nil:
+			return -1
+		}
+		return a.ExpiresAt.Compare(*b.ExpiresAt)
+	})
+
+	var consumed []models.PointLot
+	for _, lot := range lots {
+		if amount <= 0 {
+			break
+		}
+		take := math.Min(lot.Remaining, amount)
+		lot.Remaining = roundCents(lot.Remaining - take)
+		amount = roundCents(amount - take)
+		consumed = append(consumed, models.PointLot{ID: lot.ID, UserID: userID, Remaining: take, ExpiresAt: lot.ExpiresAt})
+	}
+	return consumed
+}
+
+// balance возвращает баланс пользователя, создавая пустой при отсутствии.
+// Вызывается под блокировкой хранилища.
+func (r *memoryBalanceRepository) balance(userID int) *models.Balance {
+	balance, ok := r.store.balances[userID]
+	if !ok {
+		balance = &models.Balance{UserID: userID}
+		r.store.balances[userID] = balance
+	}
+	return balance
+}
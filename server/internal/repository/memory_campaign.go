@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+type memoryCampaignRepository struct {
+	store *MemoryStore
+	// balances даёт доступ к балансу и партиям баллов того же хранилища.
+	balances *memoryBalanceRepository
+}
+
+func NewMemoryCampaignRepository(store *MemoryStore) CampaignRepository {
+	return &memoryCampaignRepository{store: store, balances: &memoryBalanceRepository{store: store}}
+}
+
+func (r *memoryCampaignRepository) CreateCampaign(_ context.Context, campaign *models.Campaign) (*models.Campaign, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.nextCampaignID++
+	created := *campaign
+	created.ID = r.store.nextCampaignID
+	created.Multiplier = roundCents(created.Multiplier)
+	created.Bonus = roundCents(created.Bonus)
+	created.MinAccrual = roundCents(created.MinAccrual)
+	created.CreatedAt = time.Now()
+	r.store.campaigns = append(r.store.campaigns, &created)
+
+	c := created
+	return &c, nil
+}
+
+func (r *memoryCampaignRepository) ListCampaigns(_ context.Context) ([]*models.Campaign, error) {
+	return r.filter(func(*models.Campaign) bool { return true }), nil
+}
+
+func (r *memoryCampaignRepository) ActiveCampaigns(_ context.Context, at time.Time) ([]*models.Campaign, error) {
+	return r.filter(func(c *models.Campaign) bool {
+		return !c.StartsAt.After(at) && c.EndsAt.After(at)
+	}), nil
+}
+
+func (r *memoryCampaignRepository) filter(match func(*models.Campaign) bool) []*models.Campaign {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var campaigns []*models.Campaign
+	for _, campaign := range r.store.campaigns {
+		if match(campaign) {
+			c := *campaign
+			campaigns = append(campaigns, &c)
+		}
+	}
+	return campaigns
+}
+
+func (r *memoryCampaignRepository) EndCampaign(_ context.Context, id int, at time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	campaign := r.campaign(id)
+	if campaign == nil {
+		return false, nil
+	}
+	if at.Before(campaign.EndsAt) {
+		campaign.EndsAt = at
+	}
+	if campaign.EndsAt.Before(campaign.StartsAt) {
+		campaign.EndsAt = campaign.StartsAt
+	}
+	return true, nil
+}
+
+func (r *memoryCampaignRepository) AddBonus(_ context.Context, bonus *models.CampaignBonus, expiresAt *time.Time) (*models.CampaignBonus, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.campaignBonuses {
+		if existing.CampaignID == bonus.CampaignID && existing.OrderNumber == bonus.OrderNumber {
+			return nil, ErrBonusExists
+		}
+	}
+
+	r.store.nextCampaignBonusID++
+	created := *bonus
+	created.ID = r.store.nextCampaignBonusID
+	created.Amount = roundCents(created.Amount)
+	created.CreatedAt = time.Now()
+	created.ReversedAt = nil
+	r.store.campaignBonuses = append(r.store.campaignBonuses, &created)
+
+	balance := r.balances.balance(bonus.UserID)
+	balance.Current = roundCents(balance.Current + created.Amount)
+	r.balances.addLot(bonus.UserID, created.Amount, expiresAt)
+
+	return r.withCampaign(&created), nil
+}
+
+func (r *memoryCampaignRepository) GetBonuses(_ context.Context, userID int) ([]*models.CampaignBonus, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var bonuses []*models.CampaignBonus
+	for i := len(r.store.campaignBonuses) - 1; i >= 0; i-- {
+		if bonus := r.store.campaignBonuses[i]; bonus.UserID == userID {
+			bonuses = append(bonuses, r.withCampaign(bonus))
+		}
+	}
+	return bonuses, nil
+}
+
+func (r *memoryCampaignRepository) ReverseBonus(_ context.Context, id int) (*models.CampaignBonus, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, bonus := range r.store.campaignBonuses {
+		if bonus.ID != id {
+			continue
+		}
+		if bonus.ReversedAt != nil {
+			return nil, ErrBonusReversed
+		}
+		balance := r.balances.balance(bonus.UserID)
+		if balance.Current < bonus.Amount {
+			return nil, ErrInsufficientFunds
+		}
+		balance.Current = roundCents(balance.Current - bonus.Amount)
+		r.balances.consumeLots(bonus.UserID, bonus.Amount)
+
+		now := time.Now()
+		bonus.ReversedAt = &now
+		return r.withCampaign(bonus), nil
+	}
+	return nil, nil
+}
+
+// campaign возвращает кампанию по id. Вызывается под блокировкой хранилища.
+func (r *memoryCampaignRepository) campaign(id int) *models.Campaign {
+	for _, campaign := range r.store.campaigns {
+		if campaign.ID == id {
+			return campaign
+		}
+	}
+	return nil
+}
+
+// withCampaign возвращает копию бонуса с названием кампании. Вызывается под
+// блокировкой хранилища.
+func (r *memoryCampaignRepository) withCampaign(bonus *models.CampaignBonus) *models.CampaignBonus {
+	b := *bonus
+	if campaign := r.campaign(b.CampaignID); campaign != nil {
+		b.Campaign = campaign.Name
+	}
+	return &b
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+type memoryOrderRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryOrderRepository(store *MemoryStore) OrderRepository {
+	return &memoryOrderRepository{store: store}
+}
+
+// createOrGet вызывается под блокировкой хранилища.
+func (r *memoryOrderRepository) createOrGet(number string, userID int) (*models.Order, bool) {
+	if existing, ok := r.store.orders[number]; ok {
+		return existing, false
+	}
+
+	r.store.nextOrderID++
+	order := &models.Order{
+		ID:         r.store.nextOrderID,
+		Number:     number,
+		UserID:     userID,
+		Status:     models.OrderStatusNew,
+		UploadedAt: time.Now(),
+	}
+	r.store.orders[number] = order
+	return order, true
+}
+
+func (r *memoryOrderRepository) CreateOrGet(_ context.Context, number string, userID int) (*models.Order, bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	order, created := r.createOrGet(number, userID)
+	return copyOrder(order), created, nil
+}
+
+func (r *memoryOrderRepository) CreateBatch(_ context.Context, numbers []string, userID int) ([]*OrderInsertResult, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	results := make([]*OrderInsertResult, 0, len(numbers))
+	seen := make(map[string]bool, len(numbers))
+	for _, number := range numbers {
+		if seen[number] {
+			continue
+		}
+		seen[number] = true
+
+		order, created := r.createOrGet(number, userID)
+		results = append(results, &OrderInsertResult{Number: number, OwnerID: order.UserID, Created: created})
+	}
+
+	return results, nil
+}
+
+func (r *memoryOrderRepository) GetByNumber(_ context.Context, number string) (*models.Order, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	order, ok := r.store.orders[number]
+	if !ok {
+		return nil, nil
+	}
+	return copyOrder(order), nil
+}
+
+func (r *memoryOrderRepository) GetByUserID(_ context.Context, userID int) ([]*models.Order, error) {
+	orders := r.filter(func(o *models.Order) bool { return o.UserID == userID })
+	sort.Slice(orders, func(i, j int) bool { return orders[i].ID > orders[j].ID })
+	return orders, nil
+}
+
+func (r *memoryOrderRepository) UpdateStatus(_ context.Context, number, status string, accrual *float64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if order, ok := r.store.orders[number]; ok {
+		order.Status = status
+		order.Accrual = copyFloat(accrual)
+	}
+	return nil
+}
+
+func (r *memoryOrderRepository) UpdateStatusByID(_ context.Context, orderID int, status string, accrual float64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, order := range r.store.orders {
+		if order.ID != orderID {
+			continue
+		}
+		if order.Status != models.OrderStatusNew && order.Status != models.OrderStatusProcessing {
+			break
+		}
+		order.Status = status
+		order.Accrual = &accrual
+		return nil
+	}
+	return ErrOrderFinalized
+}
+
+func (r *memoryOrderRepository) Requeue(_ context.Context, number string) (*models.Order, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	order, ok := r.store.orders[number]
+	if !ok || order.Status == models.OrderStatusProcessed {
+		return nil, ErrOrderFinalized
+	}
+	order.Status = models.OrderStatusNew
+	order.Accrual = nil
+	return copyOrder(order), nil
+}
+
+func (r *memoryOrderRepository) GetPendingOrders(_ context.Context, afterID, limit int) ([]*models.Order, error) {
+	orders := r.filter(func(o *models.Order) bool {
+		return o.ID > afterID && (o.Status == models.OrderStatusNew || o.Status == models.OrderStatusProcessing)
+	})
+	sort.Slice(orders, func(i, j int) bool { return orders[i].ID < orders[j].ID })
+	if limit > 0 && len(orders) > limit {
+		orders = orders[:limit]
+	}
+	return orders, nil
+}
+
+func (r *memoryOrderRepository) GetAccrualTotal(_ context.Context, userID int) (float64, error) {
+	var total float64
+	for _, o := range r.filter(func(o *models.Order) bool { return o.UserID == userID && o.Status == models.OrderStatusProcessed }) {
+		if o.Accrual != nil {
+			total += *o.Accrual
+		}
+	}
+	return roundCents(total), nil
+}
+
+func (r *memoryOrderRepository) filter(match func(*models.Order) bool) []*models.Order {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var orders []*models.Order
+	for _, order := range r.store.orders {
+		if match(order) {
+			orders = append(orders, copyOrder(order))
+		}
+	}
+	return orders
+}
+
+func copyOrder(order *models.Order) *models.Order {
+	o := *order
+	o.Accrual = copyFloat(order.Accrual)
+	return &o
+}
+
+func copyFloat(v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
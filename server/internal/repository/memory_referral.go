@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+type memoryReferralRepository struct {
+	store *MemoryStore
+	// balances даёт доступ к балансу и партиям баллов того же хранилища.
+	balances *memoryBalanceRepository
+}
+
+func NewMemoryReferralRepository(store *MemoryStore) ReferralRepository {
+	return &memoryReferralRepository{store: store, balances: &memoryBalanceRepository{store: store}}
+}
+
+func (r *memoryReferralRepository) AddReward(_ context.Context, reward *models.ReferralReward, limits models.ReferralConfig, expiresAt *time.Time) (*models.ReferralReward, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.referralRewards[reward.RefereeID]; ok {
+		return nil, ErrReferralRewarded
+	}
+
+	now := time.Now()
+	var total, daily int
+	for _, existing := range r.store.referralRewards {
+		if existing.ReferrerID != reward.ReferrerID || existing.Limited {
+			continue
+		}
+		total++
+		if existing.CreatedAt.After(now.Add(-24 * time.Hour)) {
+			daily++
+		}
+	}
+
+	created := *reward
+	if referralLimitReached(limits, total, daily) {
+		created.Limited = true
+		created.ReferrerAmount = 0
+		created.RefereeAmount = 0
+	}
+	created.ReferrerAmount = roundCents(created.ReferrerAmount)
+	created.RefereeAmount = roundCents(created.RefereeAmount)
+	created.CreatedAt = now
+	r.store.referralRewards[created.RefereeID] = &created
+
+	for _, credit := range []struct {
+		userID int
+		amount float64
+	}{
+		{created.ReferrerID, created.ReferrerAmount},
+		{created.RefereeID, created.RefereeAmount},
+	} {
+		if credit.amount <= 0 {
+			continue
+		}
+		balance := r.balances.balance(credit.userID)
+		balance.Current = roundCents(balance.Current + credit.amount)
+		r.balances.addLot(credit.userID, credit.amount, expiresAt)
+	}
+
+	c := created
+	return &c, nil
+}
+
+func (r *memoryReferralRepository) GetStats(_ context.Context, referrerID int) (*models.ReferralStatus, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	status := &models.ReferralStatus{}
+	for _, user := range r.store.users {
+		if user.ReferredBy != nil && *user.ReferredBy == referrerID {
+			status.Invited++
+		}
+	}
+	for _, reward := range r.store.referralRewards {
+		if reward.ReferrerID != referrerID {
+			continue
+		}
+		if !reward.Limited {
+			status.Rewarded++
+		}
+		status.Earned = roundCents(status.Earned + reward.ReferrerAmount)
+	}
+	return status, nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+type memoryRewardRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryRewardRepository(store *MemoryStore) RewardRepository {
+	return &memoryRewardRepository{store: store}
+}
+
+func (r *memoryRewardRepository) CreateRule(_ context.Context, rule *models.RewardRule) (*models.RewardRule, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.rewardRules {
+		if existing.Match == rule.Match {
+			return nil, ErrRewardRuleExists
+		}
+	}
+
+	r.store.nextRewardRuleID++
+	created := &models.RewardRule{
+		ID:         r.store.nextRewardRuleID,
+		Match:      rule.Match,
+		Reward:     roundCents(rule.Reward),
+		RewardType: rule.RewardType,
+		CreatedAt:  time.Now(),
+	}
+	r.store.rewardRules = append(r.store.rewardRules, created)
+
+	c := *created
+	return &c, nil
+}
+
+func (r *memoryRewardRepository) ListRules(_ context.Context) ([]*models.RewardRule, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var rules []*models.RewardRule
+	for _, rule := range r.store.rewardRules {
+		c := *rule
+		rules = append(rules, &c)
+	}
+	return rules, nil
+}
+
+func (r *memoryRewardRepository) DeleteRule(_ context.Context, id int) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	before := len(r.store.rewardRules)
+	r.store.rewardRules = slices.DeleteFunc(r.store.rewardRules, func(rule *models.RewardRule) bool {
+		return rule.ID == id
+	})
+	return len(r.store.rewardRules) < before, nil
+}
+
+func (r *memoryRewardRepository) RegisterOrder(_ context.Context, order *models.RegisteredOrder) (*models.RegisteredOrder, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.registeredOrders[order.Number]; ok {
+		return nil, ErrOrderRegistered
+	}
+
+	registered := &models.RegisteredOrder{
+		Number:       order.Number,
+		Goods:        slices.Clone(order.Goods),
+		Accrual:      roundCents(order.Accrual),
+		RegisteredAt: time.Now(),
+	}
+	r.store.registeredOrders[order.Number] = registered
+
+	c := *registered
+	c.Goods = slices.Clone(registered.Goods)
+	return &c, nil
+}
+
+func (r *memoryRewardRepository) GetRegisteredOrder(_ context.Context, number string) (*models.RegisteredOrder, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	order, ok := r.store.registeredOrders[number]
+	if !ok {
+		return nil, nil
+	}
+	c := *order
+	c.Goods = slices.Clone(order.Goods)
+	return &c, nil
+}
+
+// roundCents округляет сумму до копеек, как столбцы DECIMAL(10,2).
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,19 @@
+package repository
+
+import "testing"
+
+func TestMemoryRepositories(t *testing.T) {
+	runRepositoryContract(t, func(*testing.T) repositorySet {
+		store := NewMemoryStore()
+		return repositorySet{
+			users:       NewMemoryUserRepository(store),
+			orders:      NewMemoryOrderRepository(store),
+			balances:    NewMemoryBalanceRepository(store),
+			withdrawals: NewMemoryWithdrawalRepository(store),
+			rewards:     NewMemoryRewardRepository(store),
+			campaigns:   NewMemoryCampaignRepository(store),
+			referrals:   NewMemoryReferralRepository(store),
+			transfers:   NewMemoryTransferRepository(store),
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+type memoryTransferRepository struct {
+	store *MemoryStore
+	// balances даёт доступ к балансу и партиям баллов того же хранилища.
+	balances *memoryBalanceRepository
+}
+
+func NewMemoryTransferRepository(store *MemoryStore) TransferRepository {
+	return &memoryTransferRepository{store: store, balances: &memoryBalanceRepository{store: store}}
+}
+
+func (r *memoryTransferRepository) Transfer(_ context.Context, fromID, toID int, sum float64, limits models.TransfersConfig) (*models.Transfer, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// Сумма хранится с точностью DECIMAL(10,2), как в PostgreSQL.
+	sum = roundCents(sum)
+	from := r.balances.balance(fromID)
+	if from.Current < sum {
+		return nil, ErrInsufficientFunds
+	}
+
+	now := time.Now()
+	since := now.Add(-24 * time.Hour)
+	var (
+		count int
+		total float64
+	)
+	for _, t := range r.store.transfers {
+		if t.FromID == fromID && t.CreatedAt.After(since) {
+			count++
+			total += t.Sum
+		}
+	}
+	if exceedsTransferLimits(limits, count, total, sum) {
+		return nil, ErrTransferLimitExceeded
+	}
+
+	to := r.balances.balance(toID)
+	from.Current = roundCents(from.Current - sum)
+	to.Current = roundCents(to.Current + sum)
+	for _, lot := range transferredLots(r.balances.consumeLots(fromID, sum), sum) {
+		r.balances.addLot(toID, lot.Remaining, lot.ExpiresAt)
+	}
+
+	r.store.nextTransferID++
+	transfer := &models.Transfer{
+		ID:        r.store.nextTransferID,
+		FromID:    fromID,
+		ToID:      toID,
+		Sum:       sum,
+		CreatedAt: now,
+	}
+	r.store.transfers = append(r.store.transfers, transfer)
+
+	t := *transfer
+	t.Direction = models.TransferOut
+	return &t, nil
+}
+
+func (r *memoryTransferRepository) GetTransfers(_ context.Context, userID int) ([]*models.Transfer, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var transfers []*models.Transfer
+	for i := len(r.store.transfers) - 1; i >= 0; i-- {
+		stored := r.store.transfers[i]
+		if stored.FromID != userID && stored.ToID != userID {
+			continue
+		}
+		t := *stored
+		t.From = r.store.users[t.FromID].Login
+		t.To = r.store.users[t.ToID].Login
+		t.Direction = transferDirection(&t, userID)
+		transfers = append(transfers, &t)
+	}
+	return transfers, nil
+}
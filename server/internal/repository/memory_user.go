@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+type memoryUserRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryUserRepository(store *MemoryStore) UserRepository {
+	return &memoryUserRepository{store: store}
+}
+
+func (r *memoryUserRepository) Create(_ context.Context, newUser *models.User) (*models.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.usersByLogin[newUser.Login]; ok {
+		return nil, fmt.Errorf("failed to create user: login %q already exists", newUser.Login)
+	}
+	if _, ok := r.store.usersByCode[newUser.ReferralCode]; ok {
+		return nil, ErrReferralCodeExists
+	}
+	if newUser.ReferredBy != nil {
+		if _, ok := r.store.users[*newUser.ReferredBy]; !ok {
+			return nil, fmt.Errorf("failed to create user: referrer %d not found", *newUser.ReferredBy)
+		}
+	}
+
+	r.store.nextUserID++
+	user := &models.User{
+		ID:           r.store.nextUserID,
+		Login:        newUser.Login,
+		PasswordHash: newUser.PasswordHash,
+		Tier:         newUser.Tier,
+		ReferralCode: newUser.ReferralCode,
+		ReferredBy:   newUser.ReferredBy,
+		CreatedAt:    time.Now(),
+	}
+	r.store.users[user.ID] = user
+	r.store.usersByLogin[user.Login] = user.ID
+	r.store.usersByCode[user.ReferralCode] = user.ID
+
+	u := *user
+	return &u, nil
+}
+
+func (r *memoryUserRepository) GetByLogin(_ context.Context, login string) (*models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	id, ok := r.store.usersByLogin[login]
+	if !ok {
+		return nil, nil
+	}
+	u := *r.store.users[id]
+	return &u, nil
+}
+
+func (r *memoryUserRepository) GetByID(_ context.Context, id int) (*models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return nil, nil
+	}
+	u := *user
+	return &u, nil
+}
+
+func (r *memoryUserRepository) GetByReferralCode(_ context.Context, code string) (*models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	id, ok := r.store.usersByCode[code]
+	if !ok {
+		return nil, nil
+	}
+	u := *r.store.users[id]
+	return &u, nil
+}
+
+func (r *memoryUserRepository) List(_ context.Context) ([]*models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	users := make([]*models.User, 0, len(r.store.users))
+	for _, user := range r.store.users {
+		u := *user
+		users = append(users, &u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (r *memoryUserRepository) SetTier(_ context.Context, id int, tier string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if user, ok := r.store.users[id]; ok {
+		user.Tier = tier
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+type memoryWithdrawalRepository struct {
+	store *MemoryStore
+	// balances даёт доступ к балансу и партиям баллов того же хранилища.
+	balances *memoryBalanceRepository
+}
+
+func NewMemoryWithdrawalRepository(store *MemoryStore) WithdrawalRepository {
+	return &memoryWithdrawalRepository{store: store, balances: &memoryBalanceRepository{store: store}}
+}
+
+func (r *memoryWithdrawalRepository) Create(_ context.Context, userID int, orderNumber string, sum float64) (*models.Withdrawal, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.create(userID, orderNumber, sum), nil
+}
+
+func (r *memoryWithdrawalRepository) Withdraw(_ context.Context, userID int, orderNumber string, sum float64, limits models.WithdrawalsConfig, now time.Time) (*models.Withdrawal, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	sum = roundCents(sum)
+	balance, ok := r.store.balances[userID]
+	if !ok {
+		return nil, ErrInsufficientFunds
+	}
+
+	var (
+		hourly int
+		daily  float64
+	)
+	for _, w := range r.store.withdrawals {
+		if w.UserID != userID || !w.ProcessedAt.After(now.Add(-24*time.Hour)) {
+			continue
+		}
+		daily += w.Sum
+		if w.ProcessedAt.After(now.Add(-time.Hour)) {
+			hourly++
+		}
+	}
+	if err := checkWithdrawalLimits(limits, balance.Current, hourly, roundCents(daily), sum); err != nil {
+		return nil, err
+	}
+	if balance.Current < sum {
+		return nil, ErrInsufficientFunds
+	}
+
+	balance.Current = roundCents(balance.Current - sum)
+	balance.Withdrawn = roundCents(balance.Withdrawn + sum)
+	r.balances.consumeLots(userID, sum)
+	return r.create(userID, orderNumber, sum), nil
+}
+
+// create записывает списание; вызывается под блокировкой хранилища.
+func (r *memoryWithdrawalRepository) create(userID int, orderNumber string, sum float64) *models.Withdrawal {
+	r.store.nextWithdrawalID++
+	withdrawal := &models.Withdrawal{
+		ID:          r.store.nextWithdrawalID,
+		UserID:      userID,
+		OrderNumber: orderNumber,
+		Sum:         roundCents(sum),
+		ProcessedAt: time.Now(),
+	}
+	r.store.withdrawals = append(r.store.withdrawals, withdrawal)
+
+	w := *withdrawal
+	return &w
+}
+
+func (r *memoryWithdrawalRepository) GetByUserID(_ context.Context, userID int) ([]*models.Withdrawal, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var withdrawals []*models.Withdrawal
+	for i := len(r.store.withdrawals) - 1; i >= 0; i-- {
+		if w := *r.store.withdrawals[i]; w.UserID == userID {
+			withdrawals = append(withdrawals, &w)
+		}
+	}
+	return withdrawals, nil
+}
+
+func (r *memoryWithdrawalRepository) GetTotals(_ context.Context, userID int, since time.Time) (int, float64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var (
+		count int
+		sum   float64
+	)
+	for _, w := range r.store.withdrawals {
+		if w.UserID == userID && w.ProcessedAt.After(since) {
+			count++
+			sum += w.Sum
+		}
+	}
+	return count, roundCents(sum), nil
+}
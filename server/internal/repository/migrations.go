@@ -1,27 +1,193 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"io/fs"
 
+	"github.com/RoGogDBD/loyalty_service/server/migrations"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
-func (db *DB) RunMigrations() error {
-	driver, err := postgres.WithInstance(db.DB, &postgres.Config{})
+var (
+	// ErrSchemaDirty — последняя миграция не завершилась, схему нужно
+	// исправить вручную и выполнить migrate force.
+	ErrSchemaDirty = errors.New("database schema is dirty")
+	// ErrSchemaTooNew — база мигрирована более новой версией сервиса.
+	ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+)
+
+// MigrationStatus — состояние схемы базы данных.
+type MigrationStatus struct {
+	// Version — номер последней применённой миграции, 0 если миграций не было.
+	Version uint
+	// Dirty — миграция Version завершилась ошибкой и схема требует ручного
+	// исправления (migrate force).
+	Dirty bool
+}
+
+// Migrator применяет встроенные миграции к базе данных. Мигратор занимает
+// отдельное соединение из пула, после работы его нужно закрыть.
+type Migrator struct {
+	m    *migrate.Migrate
+	conn *sql.Conn
+}
+
+// NewMigrator создаёт мигратор над встроенным набором миграций.
+func (db *DB) NewMigrator(ctx context.Context) (*Migrator, error) {
+	source, err := iofs.New(migrations.FS, ".")
 	if err != nil {
-		return fmt.Errorf("create db driver: %w", err)
+		return nil, fmt.Errorf("open migrations source: %w", err)
 	}
 
-	m, err := migrate.NewWithDatabaseInstance("file://./migrations", "postgres", driver)
+	// WithInstance закрывает *sql.DB вместе с мигратором, поэтому драйверу
+	// передаётся только одно соединение.
+	conn, err := db.Conn(ctx)
 	if err != nil {
-		return fmt.Errorf("create migrator: %w", err)
+		return nil, fmt.Errorf("acquire connection: %w", err)
+	}
+
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("create db driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("create migrator: %w", err)
+	}
+
+	return &Migrator{m: m, conn: conn}, nil
+}
+
+// Up применяет все ещё не применённые миграции.
+func (m *Migrator) Up() error {
+	if err := m.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("migrate up: %w", err)
 	}
+	return nil
+}
 
-	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return fmt.Errorf("run migrations: %w", err)
+// Down откатывает steps последних миграций; steps <= 0 откатывает все.
+func (m *Migrator) Down(steps int) error {
+	var err error
+	if steps <= 0 {
+		err = m.m.Down()
+	} else {
+		err = m.m.Steps(-steps)
+	}
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("migrate down: %w", err)
 	}
 	return nil
 }
+
+// Status возвращает текущую версию схемы.
+func (m *Migrator) Status() (MigrationStatus, error) {
+	version, dirty, err := m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return MigrationStatus{}, nil
+	}
+	if err != nil {
+		return MigrationStatus{}, fmt.Errorf("read schema version: %w", err)
+	}
+	return MigrationStatus{Version: version, Dirty: dirty}, nil
+}
+
+// Check проверяет, что сервис может работать с текущей схемой: она не dirty
+// и её версия не новее последней встроенной миграции.
+func (m *Migrator) Check() (MigrationStatus, error) {
+	status, err := m.Status()
+	if err != nil {
+		return status, err
+	}
+
+	latest, err := LatestMigrationVersion()
+	if err != nil {
+		return status, err
+	}
+
+	if status.Dirty {
+		return status, fmt.Errorf("%w: version %d", ErrSchemaDirty, status.Version)
+	}
+	if status.Version > latest {
+		return status, fmt.Errorf("%w: version %d, latest known %d", ErrSchemaTooNew, status.Version, latest)
+	}
+	return status, nil
+}
+
+// Force записывает версию схемы без выполнения миграций и снимает признак
+// dirty. Версия -1 означает «миграции не применялись».
+func (m *Migrator) Force(version int) error {
+	if err := m.m.Force(version); err != nil {
+		return fmt.Errorf("migrate force: %w", err)
+	}
+	return nil
+}
+
+// Close освобождает соединение мигратора.
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	if err := errors.Join(srcErr, dbErr); err != nil {
+		return fmt.Errorf("close migrator: %w", err)
+	}
+	return nil
+}
+
+// LatestMigrationVersion возвращает номер последней встроенной миграции.
+func LatestMigrationVersion() (uint, error) {
+	entries, err := fs.ReadDir(migrations.FS, ".")
+	if err != nil {
+		return 0, fmt.Errorf("read migrations: %w", err)
+	}
+
+	var latest uint
+	for _, entry := range entries {
+		migration, err := source.Parse(entry.Name())
+		if err != nil {
+			continue
+		}
+		latest = max(latest, migration.Version)
+	}
+	return latest, nil
+}
+
+// CheckMigrations проверяет состояние схемы без применения миграций.
+func (db *DB) CheckMigrations() (status MigrationStatus, err error) {
+	m, err := db.NewMigrator(context.Background())
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+	defer func() {
+		if cerr := m.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	return m.Check()
+}
+
+// RunMigrations проверяет состояние схемы и применяет все встроенные миграции.
+func (db *DB) RunMigrations() (err error) {
+	m, err := db.NewMigrator(context.Background())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := m.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	if _, err := m.Check(); err != nil {
+		return err
+	}
+	return m.Up()
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/RoGogDBD/loyalty_service/server/migrations"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// TestMigrations_Complete проверяет, что версии миграций идут без пропусков
+// и у каждой есть up- и down-файл.
+func TestMigrations_Complete(t *testing.T) {
+	entries, err := fs.ReadDir(migrations.FS, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	directions := make(map[uint]map[source.Direction]bool)
+	for _, entry := range entries {
+		m, err := source.Parse(entry.Name())
+		if err != nil {
+			t.Errorf("unexpected file %s: %v", entry.Name(), err)
+			continue
+		}
+		if directions[m.Version] == nil {
+			directions[m.Version] = make(map[source.Direction]bool)
+		}
+		directions[m.Version][m.Direction] = true
+	}
+
+	latest, err := LatestMigrationVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest == 0 || int(latest) != len(directions) {
+		t.Fatalf("latest version %d, %d versions embedded", latest, len(directions))
+	}
+
+	for v := uint(1); v <= latest; v++ {
+		if !directions[v][source.Up] || !directions[v][source.Down] {
+			t.Errorf("migration %d: up=%v down=%v", v, directions[v][source.Up], directions[v][source.Down])
+		}
+	}
+}
@@ -7,15 +7,41 @@ import (
 	"fmt"
 
 	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/lib/pq"
 )
 
+// ErrOrderFinalized возвращается UpdateStatusByID и Requeue, если заказ уже в
+// окончательном статусе или не найден.
+var ErrOrderFinalized = errors.New("order is already finalized")
+
+// OrderInsertResult — итог вставки одного номера при пакетной загрузке.
+type OrderInsertResult struct {
+	Number  string
+	OwnerID int
+	Created bool
+}
+
 type OrderRepository interface {
-	Create(ctx context.Context, number string, userID int) (*models.Order, error)
+	CreateOrGet(ctx context.Context, number string, userID int) (*models.Order, bool, error)
+	CreateBatch(ctx context.Context, numbers []string, userID int) ([]*OrderInsertResult, error)
 	GetByNumber(ctx context.Context, number string) (*models.Order, error)
 	GetByUserID(ctx context.Context, userID int) ([]*models.Order, error)
 	UpdateStatus(ctx context.Context, number, status string, accrual *float64) error
+	// UpdateStatusByID записывает результат расчёта начисления. Заказ в
+	// статусе PROCESSED или INVALID не меняется, возвращается
+	// ErrOrderFinalized: так повторный результат не зачисляется дважды.
 	UpdateStatusByID(ctx context.Context, orderID int, status string, accrual float64) error
-	GetPendingOrders(ctx context.Context) ([]*models.Order, error)
+	// Requeue возвращает заказ в статус NEW и сбрасывает начисление одним
+	// условным обновлением. Заказ в статусе PROCESSED не меняется,
+	// возвращается ErrOrderFinalized, как и для отсутствующего заказа.
+	Requeue(ctx context.Context, number string) (*models.Order, error)
+	// GetPendingOrders возвращает до limit заказов в статусах NEW и
+	// PROCESSING с ID больше afterID, от старых к новым; limit <= 0 снимает
+	// ограничение. По afterID очередь читается частями от прохода к проходу.
+	GetPendingOrders(ctx context.Context, afterID, limit int) ([]*models.Order, error)
+	// GetAccrualTotal возвращает сумму начислений по обработанным заказам
+	// пользователя.
+	GetAccrualTotal(ctx context.Context, userID int) (float64, error)
 }
 
 type orderRepository struct {
@@ -26,10 +52,13 @@ func NewOrderRepository(db *DB) OrderRepository {
 	return &orderRepository{db: db}
 }
 
-func (r *orderRepository) Create(ctx context.Context, number string, userID int) (*models.Order, error) {
+// CreateOrGet атомарно вставляет заказ. Если номер уже существует, возвращает
+// существующий заказ и created = false.
+func (r *orderRepository) CreateOrGet(ctx context.Context, number string, userID int) (*models.Order, bool, error) {
 	query := `
         INSERT INTO orders (number, user_id, status, uploaded_at)
         VALUES ($1, $2, $3, NOW())
+        ON CONFLICT (number) DO NOTHING
         RETURNING id, number, user_id, status, accrual, uploaded_at
     `
 
@@ -45,15 +74,90 @@ func (r *orderRepository) Create(ctx context.Context, number string, userID int)
 		&order.UploadedAt,
 	)
 
+	if errors.Is(err, sql.ErrNoRows) {
+		// Конфликт: ON CONFLICT дожидается фиксации конкурирующей вставки,
+		// поэтому отдельный запрос гарантированно увидит владельца.
+		existing, err := r.GetByNumber(ctx, number)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing == nil {
+			return nil, false, fmt.Errorf("order %s vanished after insert conflict", number)
+		}
+		return existing, false, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to create order: %w", err)
+		return nil, false, fmt.Errorf("failed to create order: %w", err)
 	}
 
 	if accrual.Valid {
 		order.Accrual = &accrual.Float64
 	}
 
-	return order, nil
+	return order, true, nil
+}
+
+// CreateBatch вставляет номера одним запросом. Уже существующие номера не
+// перезаписываются, для них возвращается владелец.
+func (r *orderRepository) CreateBatch(ctx context.Context, numbers []string, userID int) ([]*OrderInsertResult, error) {
+	query := `
+        WITH input AS (
+            SELECT DISTINCT unnest($1::text[]) AS number
+        ), inserted AS (
+            INSERT INTO orders (number, user_id, status, uploaded_at)
+            SELECT number, $2, $3, NOW() FROM input
+            ON CONFLICT (number) DO NOTHING
+            RETURNING number, user_id
+        )
+        SELECT i.number, COALESCE(ins.user_id, o.user_id), ins.number IS NOT NULL
+        FROM input i
+        LEFT JOIN inserted ins ON ins.number = i.number
+        LEFT JOIN orders o ON o.number = i.number
+    `
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(numbers), userID, models.OrderStatusNew)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create orders: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			err = fmt.Errorf("failed to close rows: %w", closeErr)
+		}
+	}()
+
+	results := make([]*OrderInsertResult, 0, len(numbers))
+	var unresolved []*OrderInsertResult
+	for rows.Next() {
+		res := &OrderInsertResult{}
+		var ownerID sql.NullInt64
+		if err := rows.Scan(&res.Number, &ownerID, &res.Created); err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		// Номер, вставленный конкурирующей транзакцией, не виден в снимке
+		// этого запроса: владельца дочитываем отдельно.
+		if !ownerID.Valid {
+			unresolved = append(unresolved, res)
+		}
+		res.OwnerID = int(ownerID.Int64)
+		results = append(results, res)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	for _, res := range unresolved {
+		existing, err := r.GetByNumber(ctx, res.Number)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, fmt.Errorf("order %s vanished after insert conflict", res.Number)
+		}
+		res.OwnerID = existing.UserID
+	}
+
+	return results, nil
 }
 
 func (r *orderRepository) GetByNumber(ctx context.Context, number string) (*models.Order, error) {
@@ -157,26 +261,76 @@ func (r *orderRepository) UpdateStatusByID(ctx context.Context, orderID int, sta
 	query := `
         UPDATE orders
         SET status = $1, accrual = $2
-        WHERE id = $3
+        WHERE id = $3 AND status IN ($4, $5)
     `
 
-	_, err := r.db.ExecContext(ctx, query, status, accrual, orderID)
+	result, err := r.db.ExecContext(ctx, query, status, accrual, orderID, models.OrderStatusNew, models.OrderStatusProcessing)
 	if err != nil {
 		return fmt.Errorf("failed to update order status: %w", err)
 	}
 
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrOrderFinalized
+	}
+
 	return nil
 }
 
-func (r *orderRepository) GetPendingOrders(ctx context.Context) ([]*models.Order, error) {
+func (r *orderRepository) Requeue(ctx context.Context, number string) (*models.Order, error) {
+	query := `
+        UPDATE orders
+        SET status = $1, accrual = NULL
+        WHERE number = $2 AND status <> $3
+        RETURNING id, number, user_id, status, uploaded_at
+    `
+
+	order := &models.Order{}
+	err := r.db.QueryRowContext(ctx, query, models.OrderStatusNew, number, models.OrderStatusProcessed).Scan(
+		&order.ID,
+		&order.Number,
+		&order.UserID,
+		&order.Status,
+		&order.UploadedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderFinalized
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to requeue order: %w", err)
+	}
+
+	return order, nil
+}
+
+func (r *orderRepository) GetAccrualTotal(ctx context.Context, userID int) (float64, error) {
+	var total float64
+	err := r.db.QueryRowContext(ctx, `
+        SELECT COALESCE(SUM(accrual), 0)
+        FROM orders
+        WHERE user_id = $1 AND status = $2
+    `, userID, models.OrderStatusProcessed).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get accrual total: %w", err)
+	}
+
+	return total, nil
+}
+
+func (r *orderRepository) GetPendingOrders(ctx context.Context, afterID, limit int) ([]*models.Order, error) {
 	query := `
         SELECT id, number, user_id, status, accrual, uploaded_at
         FROM orders
-        WHERE status IN ('NEW', 'PROCESSING')
-        ORDER BY uploaded_at ASC
+        WHERE status IN ('NEW', 'PROCESSING') AND id > $1
+        ORDER BY id ASC
+        LIMIT $2
     `
 
-	rows, err := r.db.QueryContext(ctx, query)
+	// LIMIT NULL в PostgreSQL означает отсутствие ограничения.
+	rows, err := r.db.QueryContext(ctx, query, afterID, sql.NullInt64{Int64: int64(limit), Valid: limit > 0})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pending orders: %w", err)
 	}
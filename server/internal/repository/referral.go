@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+// ErrReferralRewarded возвращается AddReward, если за приглашённого уже
+// начислено вознаграждение.
+var ErrReferralRewarded = errors.New("referral already rewarded")
+
+// ReferralRepository хранит вознаграждения реферальной программы.
+type ReferralRepository interface {
+	// AddReward записывает вознаграждение и в той же транзакции зачисляет
+	// баллы обоим участникам партиями, сгорающими в expiresAt. Если
+	// пригласивший исчерпал лимиты limits, вознаграждение записывается без
+	// баллов с признаком Limited.
+	AddReward(ctx context.Context, reward *models.ReferralReward, limits models.ReferralConfig, expiresAt *time.Time) (*models.ReferralReward, error)
+	// GetStats возвращает итоги приглашений пользователя без кода.
+	GetStats(ctx context.Context, referrerID int) (*models.ReferralStatus, error)
+}
+
+type referralRepository struct {
+	db *DB
+}
+
+func NewReferralRepository(db *DB) ReferralRepository {
+	return &referralRepository{db: db}
+}
+
+func (r *referralRepository) AddReward(ctx context.Context, reward *models.ReferralReward, limits models.ReferralConfig, expiresAt *time.Time) (_ *models.ReferralReward, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx, &err)
+
+	// Блокировка пригласившего упорядочивает его вознаграждения, чтобы
+	// параллельные начисления не превысили лимиты.
+	if _, err = tx.ExecContext(ctx, `SELECT id FROM users WHERE id = $1 FOR NO KEY UPDATE`, reward.ReferrerID); err != nil {
+		return nil, fmt.Errorf("failed to lock referrer: %w", err)
+	}
+	var total, daily int
+	if err = tx.QueryRowContext(ctx, `
+        SELECT COUNT(*), COUNT(*) FILTER (WHERE created_at > NOW() - INTERVAL '24 hours')
+        FROM referral_rewards
+        WHERE referrer_id = $1 AND NOT limited
+    `, reward.ReferrerID).Scan(&total, &daily); err != nil {
+		return nil, fmt.Errorf("failed to count referral rewards: %w", err)
+	}
+
+	created := *reward
+	if referralLimitReached(limits, total, daily) {
+		created.Limited = true
+		created.ReferrerAmount = 0
+		created.RefereeAmount = 0
+	}
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO referral_rewards (referee_id, referrer_id, order_number, referrer_amount, referee_amount, limited, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, NOW())
+        ON CONFLICT (referee_id) DO NOTHING
+        RETURNING created_at
+    `, created.RefereeID, created.ReferrerID, created.OrderNumber, created.ReferrerAmount, created.RefereeAmount, created.Limited).Scan(&created.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReferralRewarded
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record referral reward: %w", err)
+	}
+
+	for _, credit := range []struct {
+		userID int
+		amount float64
+	}{
+		{created.ReferrerID, created.ReferrerAmount},
+		{created.RefereeID, created.RefereeAmount},
+	} {
+		if credit.amount <= 0 {
+			continue
+		}
+		if _, err = tx.ExecContext(ctx, `
+            INSERT INTO balance (user_id, current, withdrawn)
+            VALUES ($1, $2, 0)
+            ON CONFLICT (user_id)
+            DO UPDATE SET current = balance.current + EXCLUDED.current
+        `, credit.userID, credit.amount); err != nil {
+			return nil, fmt.Errorf("failed to add referral reward: %w", err)
+		}
+		if err = insertLot(ctx, tx, credit.userID, credit.amount, expiresAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit referral reward: %w", err)
+	}
+
+	return &created, nil
+}
+
+func (r *referralRepository) GetStats(ctx context.Context, referrerID int) (*models.ReferralStatus, error) {
+	status := &models.ReferralStatus{}
+	err := r.db.QueryRowContext(ctx, `
+        SELECT
+            (SELECT COUNT(*) FROM users WHERE referred_by = $1),
+            COUNT(*) FILTER (WHERE NOT limited),
+            COALESCE(SUM(referrer_amount), 0)
+        FROM referral_rewards
+        WHERE referrer_id = $1
+    `, referrerID).Scan(&status.Invited, &status.Rewarded, &status.Earned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get referral stats: %w", err)
+	}
+
+	return status, nil
+}
+
+// referralLimitReached сообщает, исчерпал ли пригласивший лимиты, если ему
+// начислено total вознаграждений, из них daily — за последние сутки.
+// Вознаграждения, отклонённые по лимиту, не считаются.
+func referralLimitReached(limits models.ReferralConfig, total, daily int) bool {
+	return (limits.MaxRewards > 0 && total >= limits.MaxRewards) ||
+		(limits.MaxDailyRewards > 0 && daily >= limits.MaxDailyRewards)
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+var (
+	// ErrRewardRuleExists возвращается CreateRule, если правило с таким
+	// Match уже есть.
+	ErrRewardRuleExists = errors.New("reward rule already exists")
+	// ErrOrderRegistered возвращается RegisterOrder, если состав заказа уже
+	// зарегистрирован.
+	ErrOrderRegistered = errors.New("order already registered")
+)
+
+// RewardRepository хранит правила и заказы встроенного расчёта начислений.
+type RewardRepository interface {
+	CreateRule(ctx context.Context, rule *models.RewardRule) (*models.RewardRule, error)
+	// ListRules возвращает правила в порядке создания.
+	ListRules(ctx context.Context) ([]*models.RewardRule, error)
+	// DeleteRule возвращает false, если правила нет.
+	DeleteRule(ctx context.Context, id int) (bool, error)
+	RegisterOrder(ctx context.Context, order *models.RegisteredOrder) (*models.RegisteredOrder, error)
+	GetRegisteredOrder(ctx context.Context, number string) (*models.RegisteredOrder, error)
+}
+
+type rewardRepository struct {
+	db *DB
+}
+
+func NewRewardRepository(db *DB) RewardRepository {
+	return &rewardRepository{db: db}
+}
+
+func (r *rewardRepository) CreateRule(ctx context.Context, rule *models.RewardRule) (*models.RewardRule, error) {
+	query := `
+        INSERT INTO reward_rules (match, reward, reward_type, created_at)
+        VALUES ($1, $2, $3, NOW())
+        ON CONFLICT (match) DO NOTHING
+        RETURNING id, match, reward, reward_type, created_at
+    `
+
+	created := &models.RewardRule{}
+	err := r.db.QueryRowContext(ctx, query, rule.Match, rule.Reward, rule.RewardType).Scan(
+		&created.ID,
+		&created.Match,
+		&created.Reward,
+		&created.RewardType,
+		&created.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRewardRuleExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reward rule: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *rewardRepository) ListRules(ctx context.Context) ([]*models.RewardRule, error) {
+	query := `
+        SELECT id, match, reward, reward_type, created_at
+        FROM reward_rules
+        ORDER BY id
+    `
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reward rules: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("failed to close rows: %v", err)
+		}
+	}()
+
+	var rules []*models.RewardRule
+	for rows.Next() {
+		rule := &models.RewardRule{}
+		if err := rows.Scan(
+			&rule.ID,
+			&rule.Match,
+			&rule.Reward,
+			&rule.RewardType,
+			&rule.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan reward rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate reward rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (r *rewardRepository) DeleteRule(ctx context.Context, id int) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM reward_rules WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete reward rule: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+func (r *rewardRepository) RegisterOrder(ctx context.Context, order *models.RegisteredOrder) (*models.RegisteredOrder, error) {
+	goods, err := json.Marshal(order.Goods)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode goods: %w", err)
+	}
+
+	query := `
+        INSERT INTO registered_orders (number, goods, accrual, registered_at)
+        VALUES ($1, $2, $3, NOW())
+        ON CONFLICT (number) DO NOTHING
+        RETURNING registered_at, accrual
+    `
+
+	registered := &models.RegisteredOrder{Number: order.Number, Goods: order.Goods}
+	err = r.db.QueryRowContext(ctx, query, order.Number, goods, order.Accrual).Scan(
+		&registered.RegisteredAt,
+		&registered.Accrual,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderRegistered
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to register order: %w", err)
+	}
+
+	return registered, nil
+}
+
+func (r *rewardRepository) GetRegisteredOrder(ctx context.Context, number string) (*models.RegisteredOrder, error) {
+	query := `
+        SELECT number, goods, accrual, registered_at
+        FROM registered_orders
+        WHERE number = $1
+    `
+
+	order := &models.RegisteredOrder{}
+	var goods []byte
+	err := r.db.QueryRowContext(ctx, query, number).Scan(
+		&order.Number,
+		&goods,
+		&order.Accrual,
+		&order.RegisteredAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get registered order: %w", err)
+	}
+
+	if err := json.Unmarshal(goods, &order.Goods); err != nil {
+		return nil, fmt.Errorf("failed to decode goods: %w", err)
+	}
+
+	return order, nil
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/lib/pq"
+)
+
+// ErrTransferLimitExceeded возвращается Transfer, если перевод превысил бы
+// суточный лимит отправителя.
+var ErrTransferLimitExceeded = errors.New("daily transfer limit exceeded")
+
+// TransferRepository хранит переводы баллов между пользователями.
+type TransferRepository interface {
+	// Transfer в одной транзакции списывает sum с баланса fromID, зачисляет
+	// её на баланс toID и записывает перевод. Получатель получает баллы с
+	// теми же сроками сгорания, что были у отправителя. Возвращает
+	// ErrInsufficientFunds и ErrTransferLimitExceeded, если перевод
+	// невозможен.
+	Transfer(ctx context.Context, fromID, toID int, sum float64, limits models.TransfersConfig) (*models.Transfer, error)
+	// GetTransfers возвращает входящие и исходящие переводы пользователя,
+	// новые первыми.
+	GetTransfers(ctx context.Context, userID int) ([]*models.Transfer, error)
+}
+
+type transferRepository struct {
+	db *DB
+}
+
+func NewTransferRepository(db *DB) TransferRepository {
+	return &transferRepository{db: db}
+}
+
+func (r *transferRepository) Transfer(ctx context.Context, fromID, toID int, sum float64, limits models.TransfersConfig) (_ *models.Transfer, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx, &err)
+
+	// Балансы блокируются в порядке user_id, чтобы встречные переводы не
+	// взаимоблокировались. Блокировка баланса отправителя заодно
+	// упорядочивает его переводы для проверки лимитов.
+	if _, err = tx.ExecContext(ctx, `
+        INSERT INTO balance (user_id, current, withdrawn)
+        SELECT unnest($1::int[]), 0, 0
+        ON CONFLICT (user_id) DO NOTHING
+    `, pq.Array([]int{fromID, toID})); err != nil {
+		return nil, fmt.Errorf("failed to create balance: %w", err)
+	}
+	var current float64
+	if err = tx.QueryRowContext(ctx, `
+        SELECT SUM(current) FILTER (WHERE user_id = $1)
+        FROM (
+            SELECT user_id, current FROM balance
+            WHERE user_id IN ($1, $2)
+            ORDER BY user_id
+            FOR UPDATE
+        ) locked
+    `, fromID, toID).Scan(&current); err != nil {
+		return nil, fmt.Errorf("failed to lock balances: %w", err)
+	}
+	if current < sum {
+		return nil, ErrInsufficientFunds
+	}
+
+	var (
+		count int
+		total float64
+	)
+	if err = tx.QueryRowContext(ctx, `
+        SELECT COUNT(*), COALESCE(SUM(sum), 0)
+        FROM transfers
+        WHERE from_user_id = $1 AND created_at > NOW() - INTERVAL '24 hours'
+    `, fromID).Scan(&count, &total); err != nil {
+		return nil, fmt.Errorf("failed to get daily transfers: %w", err)
+	}
+	if exceedsTransferLimits(limits, count, total, sum) {
+		return nil, ErrTransferLimitExceeded
+	}
+
+	if _, err = tx.ExecContext(ctx, `UPDATE balance SET current = current - $1 WHERE user_id = $2`, sum, fromID); err != nil {
+		return nil, fmt.Errorf("failed to debit balance: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE balance SET current = current + $1 WHERE user_id = $2`, sum, toID); err != nil {
+		return nil, fmt.Errorf("failed to credit balance: %w", err)
+	}
+
+	lots, err := consumeLots(ctx, tx, fromID, sum)
+	if err != nil {
+		return nil, err
+	}
+	for _, lot := range transferredLots(lots, sum) {
+		if err = insertLot(ctx, tx, toID, lot.Remaining, lot.ExpiresAt); err != nil {
+			return nil, err
+		}
+	}
+
+	transfer := &models.Transfer{FromID: fromID, ToID: toID, Direction: models.TransferOut}
+	if err = tx.QueryRowContext(ctx, `
+        INSERT INTO transfers (from_user_id, to_user_id, sum, created_at)
+        VALUES ($1, $2, $3, NOW())
+        RETURNING id, sum, created_at
+    `, fromID, toID, sum).Scan(&transfer.ID, &transfer.Sum, &transfer.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to record transfer: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transfer: %w", err)
+	}
+
+	return transfer, nil
+}
+
+func (r *transferRepository) GetTransfers(ctx context.Context, userID int) ([]*models.Transfer, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT t.id, t.from_user_id, f.login, t.to_user_id, u.login, t.sum, t.created_at
+        FROM transfers t
+        JOIN users f ON f.id = t.from_user_id
+        JOIN users u ON u.id = t.to_user_id
+        WHERE t.from_user_id = $1 OR t.to_user_id = $1
+        ORDER BY t.created_at DESC, t.id DESC
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfers: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("failed to close rows: %v", err)
+		}
+	}()
+
+	var transfers []*models.Transfer
+	for rows.Next() {
+		t := &models.Transfer{}
+		if err := rows.Scan(&t.ID, &t.FromID, &t.From, &t.ToID, &t.To, &t.Sum, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan transfer: %w", err)
+		}
+		t.Direction = transferDirection(t, userID)
+		transfers = append(transfers, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate transfers: %w", err)
+	}
+
+	return transfers, nil
+}
+
+// exceedsTransferLimits сообщает, превысит ли перевод sum суточные лимиты,
+// если за сутки отправитель уже сделал count переводов на сумму total.
+func exceedsTransferLimits(limits models.TransfersConfig, count int, total, sum float64) bool {
+	return (limits.MaxDailyCount > 0 && count+1 > limits.MaxDailyCount) ||
+		(limits.MaxDailySum > 0 && roundCents(total+sum) > limits.MaxDailySum)
+}
+
+// transferredLots возвращает партии, которые получает получатель перевода:
+// списанные у отправителя части с их сроками и бессрочный остаток, если
+// баллы отправителя были накоплены до учёта партий.
+func transferredLots(consumed []models.PointLot, sum float64) []models.PointLot {
+	lots := consumed
+	for _, lot := range consumed {
+		sum = roundCents(sum - lot.Remaining)
+	}
+	if sum > 0 {
+		lots = append(lots, models.PointLot{Remaining: sum})
+	}
+	return lots
+}
+
+func transferDirection(t *models.Transfer, userID int) string {
+	if t.FromID == userID {
+		return models.TransferOut
+	}
+	return models.TransferIn
+}
@@ -5,14 +5,27 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log"
 
 	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/lib/pq"
 )
 
+// ErrReferralCodeExists возвращается Create, если реферальный код уже занят.
+var ErrReferralCodeExists = errors.New("referral code already exists")
+
 type UserRepository interface {
-	Create(ctx context.Context, login, passwordHash string) (*models.User, error)
+	// Create сохраняет пользователя с логином, хэшем пароля, начальным
+	// уровнем, реферальным кодом и, если задан, пригласившим.
+	Create(ctx context.Context, user *models.User) (*models.User, error)
 	GetByLogin(ctx context.Context, login string) (*models.User, error)
 	GetByID(ctx context.Context, id int) (*models.User, error)
+	// GetByReferralCode возвращает владельца кода или nil.
+	GetByReferralCode(ctx context.Context, code string) (*models.User, error)
+	// List возвращает всех пользователей в порядке регистрации.
+	List(ctx context.Context) ([]*models.User, error)
+	// SetTier сохраняет уровень лояльности пользователя.
+	SetTier(ctx context.Context, id int, tier string) error
 }
 
 type userRepository struct {
@@ -23,73 +36,125 @@ func NewUserRepository(db *DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) Create(ctx context.Context, login, passwordHash string) (*models.User, error) {
+const userColumns = `id, login, password_hash, tier, referral_code, referred_by, created_at`
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) (*models.User, error) {
 	query := `
-        INSERT INTO users (login, password_hash, created_at)
-        VALUES ($1, $2, NOW())
-        RETURNING id, login, created_at
-    `
+        INSERT INTO users (login, password_hash, tier, referral_code, referred_by, created_at)
+        VALUES ($1, $2, $3, $4, $5, NOW())
+        RETURNING ` + userColumns
+
+	created, err := scanUser(r.db.QueryRowContext(ctx, query,
+		user.Login,
+		user.PasswordHash,
+		user.Tier,
+		user.ReferralCode,
+		user.ReferredBy,
+	))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "users_referral_code_key" {
+		return nil, ErrReferralCodeExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
 
-	user := &models.User{PasswordHash: passwordHash}
-	err := r.db.QueryRowContext(ctx, query, login, passwordHash).Scan(
-		&user.ID,
-		&user.Login,
-		&user.CreatedAt,
-	)
+	return created, nil
+}
 
+func (r *userRepository) GetByLogin(ctx context.Context, login string) (*models.User, error) {
+	user, err := r.getBy(ctx, "login", login)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+		return nil, fmt.Errorf("failed to get user by login: %w", err)
 	}
+	return user, nil
+}
 
+func (r *userRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	user, err := r.getBy(ctx, "id", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by id: %w", err)
+	}
 	return user, nil
 }
 
-func (r *userRepository) GetByLogin(ctx context.Context, login string) (*models.User, error) {
-	query := `
-        SELECT id, login, password_hash, created_at
-        FROM users
-        WHERE login = $1
-    `
+func (r *userRepository) GetByReferralCode(ctx context.Context, code string) (*models.User, error) {
+	user, err := r.getBy(ctx, "referral_code", code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by referral code: %w", err)
+	}
+	return user, nil
+}
 
-	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, query, login).Scan(
-		&user.ID,
-		&user.Login,
-		&user.PasswordHash,
-		&user.CreatedAt,
-	)
+// getBy возвращает пользователя по значению уникального столбца column.
+func (r *userRepository) getBy(ctx context.Context, column string, value any) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE ` + column + ` = $1`
 
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, value))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user by login: %w", err)
+		return nil, err
 	}
 
 	return user, nil
 }
 
-func (r *userRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
-	query := `
-        SELECT id, login, password_hash, created_at
-        FROM users
-        WHERE id = $1
-    `
+func (r *userRepository) List(ctx context.Context) ([]*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("failed to close rows: %v", err)
+		}
+	}()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate users: %w", err)
+	}
 
+	return users, nil
+}
+
+func (r *userRepository) SetTier(ctx context.Context, id int, tier string) error {
+	query := `UPDATE users SET tier = $1 WHERE id = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, tier, id); err != nil {
+		return fmt.Errorf("failed to set user tier: %w", err)
+	}
+	return nil
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
 	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	var referredBy sql.NullInt64
+	if err := row.Scan(
 		&user.ID,
 		&user.Login,
 		&user.PasswordHash,
+		&user.Tier,
+		&user.ReferralCode,
+		&referredBy,
 		&user.CreatedAt,
-	)
-
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
+	); err != nil {
+		return nil, err
 	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user by id: %w", err)
+	if referredBy.Valid {
+		id := int(referredBy.Int64)
+		user.ReferredBy = &id
 	}
-
 	return user, nil
 }
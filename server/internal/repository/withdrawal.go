@@ -2,15 +2,34 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/RoGogDBD/loyalty_service/server/internal/models"
 )
 
+// Ошибки ограничений списаний, возвращаемые Withdraw.
+var (
+	ErrWithdrawalSumLimit     = errors.New("withdrawal sum exceeds the limit")
+	ErrWithdrawalDailyLimit   = errors.New("daily withdrawal limit exceeded")
+	ErrWithdrawalMinBalance   = errors.New("withdrawal would leave less than the minimum balance")
+	ErrWithdrawalRateExceeded = errors.New("too many withdrawals in the last hour")
+)
+
 type WithdrawalRepository interface {
 	Create(ctx context.Context, userID int, orderNumber string, sum float64) (*models.Withdrawal, error)
+	// Withdraw в одной транзакции проверяет ограничения limits, списывает sum
+	// с баланса и партий пользователя и записывает списание. Часовое и
+	// суточное окна отсчитываются от now. Возвращает ErrInsufficientFunds
+	// или ошибку ограничения, если списание невозможно.
+	Withdraw(ctx context.Context, userID int, orderNumber string, sum float64, limits models.WithdrawalsConfig, now time.Time) (*models.Withdrawal, error)
 	GetByUserID(ctx context.Context, userID int) ([]*models.Withdrawal, error)
+	// GetTotals возвращает число и сумму списаний пользователя, сделанных
+	// после since.
+	GetTotals(ctx context.Context, userID int, since time.Time) (count int, sum float64, err error)
 }
 
 type withdrawalRepository struct {
@@ -44,6 +63,75 @@ func (r *withdrawalRepository) Create(ctx context.Context, userID int, orderNumb
 	return withdrawal, nil
 }
 
+func (r *withdrawalRepository) Withdraw(ctx context.Context, userID int, orderNumber string, sum float64, limits models.WithdrawalsConfig, now time.Time) (_ *models.Withdrawal, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx, &err)
+
+	// Блокировка баланса упорядочивает списания пользователя, поэтому
+	// параллельные списания не обходят ограничения.
+	var current float64
+	err = tx.QueryRowContext(ctx, `SELECT current FROM balance WHERE user_id = $1 FOR UPDATE`, userID).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInsufficientFunds
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock balance: %w", err)
+	}
+
+	var (
+		hourly int
+		daily  float64
+	)
+	if err = tx.QueryRowContext(ctx, `
+        SELECT COUNT(*) FILTER (WHERE processed_at > $2), COALESCE(SUM(sum), 0)
+        FROM withdrawals
+        WHERE user_id = $1 AND processed_at > $3
+    `, userID, now.Add(-time.Hour), now.Add(-24*time.Hour)).Scan(&hourly, &daily); err != nil {
+		return nil, fmt.Errorf("failed to get recent withdrawals: %w", err)
+	}
+	if err = checkWithdrawalLimits(limits, current, hourly, daily, sum); err != nil {
+		return nil, err
+	}
+	if current < sum {
+		return nil, ErrInsufficientFunds
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+        UPDATE balance
+        SET current = current - $1, withdrawn = withdrawn + $1
+        WHERE user_id = $2
+    `, sum, userID); err != nil {
+		return nil, fmt.Errorf("failed to withdraw: %w", err)
+	}
+	if _, err = consumeLots(ctx, tx, userID, sum); err != nil {
+		return nil, err
+	}
+
+	withdrawal := &models.Withdrawal{}
+	if err = tx.QueryRowContext(ctx, `
+        INSERT INTO withdrawals (user_id, order_number, sum, processed_at)
+        VALUES ($1, $2, $3, NOW())
+        RETURNING id, user_id, order_number, sum, processed_at
+    `, userID, orderNumber, sum).Scan(
+		&withdrawal.ID,
+		&withdrawal.UserID,
+		&withdrawal.OrderNumber,
+		&withdrawal.Sum,
+		&withdrawal.ProcessedAt,
+	); err != nil {
+		return nil, fmt.Errorf("failed to create withdrawal: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit withdrawal: %w", err)
+	}
+
+	return withdrawal, nil
+}
+
 func (r *withdrawalRepository) GetByUserID(ctx context.Context, userID int) ([]*models.Withdrawal, error) {
 	query := `
         SELECT id, user_id, order_number, sum, processed_at
@@ -79,3 +167,38 @@ func (r *withdrawalRepository) GetByUserID(ctx context.Context, userID int) ([]*
 
 	return withdrawals, nil
 }
+
+func (r *withdrawalRepository) GetTotals(ctx context.Context, userID int, since time.Time) (int, float64, error) {
+	var (
+		count int
+		sum   float64
+	)
+	err := r.db.QueryRowContext(ctx, `
+        SELECT COUNT(*), COALESCE(SUM(sum), 0)
+        FROM withdrawals
+        WHERE user_id = $1 AND processed_at > $2
+    `, userID, since).Scan(&count, &sum)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get withdrawal totals: %w", err)
+	}
+
+	return count, sum, nil
+}
+
+// checkWithdrawalLimits проверяет, что списание sum не нарушает limits, если
+// на счёте current баллов, за последний час сделано hourly списаний, а за
+// сутки списано daily. Нехватка баллов на само списание проверяется
+// отдельно и даёт ErrInsufficientFunds.
+func checkWithdrawalLimits(limits models.WithdrawalsConfig, current float64, hourly int, daily, sum float64) error {
+	switch {
+	case limits.MaxSum > 0 && sum > limits.MaxSum:
+		return ErrWithdrawalSumLimit
+	case limits.MaxPerHour > 0 && hourly >= limits.MaxPerHour:
+		return ErrWithdrawalRateExceeded
+	case limits.MaxDailySum > 0 && roundCents(daily+sum) > limits.MaxDailySum:
+		return ErrWithdrawalDailyLimit
+	case limits.MinBalance > 0 && current >= sum && roundCents(current-sum) < limits.MinBalance:
+		return ErrWithdrawalMinBalance
+	}
+	return nil
+}
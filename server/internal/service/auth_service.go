@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/RoGogDBD/loyalty_service/server/internal/models"
 	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
@@ -13,6 +15,7 @@ import (
 var (
 	ErrUserExists         = errors.New("user already exists")
 	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrUnknownReferrer    = errors.New("unknown referral code")
 )
 
 type AuthService interface {
@@ -22,10 +25,17 @@ type AuthService interface {
 
 type authService struct {
 	userRepo repository.UserRepository
+	tier     string
 }
 
-func NewAuthService(userRepo repository.UserRepository) AuthService {
-	return &authService{userRepo: userRepo}
+// NewAuthService создаёт сервис регистрации и входа. Если программа уровней
+// включена, новый пользователь сразу получает начальный уровень.
+func NewAuthService(userRepo repository.UserRepository, tiers models.TiersConfig) AuthService {
+	s := &authService{userRepo: userRepo}
+	if len(tiers.Levels) > 0 {
+		s.tier = tiers.Levels[0].Name
+	}
+	return s
 }
 
 func (s *authService) Register(ctx context.Context, creds *models.UserCredentials) (*models.User, error) {
@@ -42,12 +52,52 @@ func (s *authService) Register(ctx context.Context, creds *models.UserCredential
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	user, err := s.userRepo.Create(ctx, creds.Login, string(hash))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+	newUser := &models.User{Login: creds.Login, PasswordHash: string(hash), Tier: s.tier}
+	if code := normalizeReferralCode(creds.Referrer); code != "" {
+		referrer, err := s.userRepo.GetByReferralCode(ctx, code)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get referrer: %w", err)
+		}
+		if referrer == nil {
+			return nil, ErrUnknownReferrer
+		}
+		newUser.ReferredBy = &referrer.ID
 	}
 
-	return user, nil
+	// Коды случайны, поэтому совпадение маловероятно, но возможно.
+	for attempt := 1; ; attempt++ {
+		newUser.ReferralCode = generateReferralCode()
+		user, err := s.userRepo.Create(ctx, newUser)
+		if errors.Is(err, repository.ErrReferralCodeExists) && attempt < referralCodeAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+		return user, nil
+	}
+}
+
+const (
+	// referralCodeAlphabet — заглавные буквы и цифры без похожих 0/O и 1/I.
+	referralCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	referralCodeLength   = 8
+	referralCodeAttempts = 5
+)
+
+func generateReferralCode() string {
+	b := make([]byte, referralCodeLength)
+	_, _ = rand.Read(b)
+	for i := range b {
+		// 256 делится на размер алфавита, поэтому символы равновероятны.
+		b[i] = referralCodeAlphabet[int(b[i])%len(referralCodeAlphabet)]
+	}
+	return string(b)
+}
+
+// normalizeReferralCode приводит введённый код к виду, в котором он хранится.
+func normalizeReferralCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
 }
 
 func (s *authService) Login(ctx context.Context, creds *models.UserCredentials) (*models.User, error) {
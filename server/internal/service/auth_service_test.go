@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+)
+
+func TestAuthService_Register(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryUserRepository(repository.NewMemoryStore())
+	svc := NewAuthService(repo, models.TiersConfig{})
+
+	user, err := svc.Register(ctx, &models.UserCredentials{Login: "alice", Password: "secret"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if user.Login != "alice" {
+		t.Errorf("login = %q, want alice", user.Login)
+	}
+	if user.PasswordHash == "" || user.PasswordHash == "secret" {
+		t.Errorf("password is not hashed: %q", user.PasswordHash)
+	}
+
+	_, err = svc.Register(ctx, &models.UserCredentials{Login: "alice", Password: "other"})
+	if !errors.Is(err, ErrUserExists) {
+		t.Errorf("second Register error = %v, want ErrUserExists", err)
+	}
+}
+
+func TestAuthService_RegisterTier(t *testing.T) {
+	ctx := context.Background()
+	svc := NewAuthService(repository.NewMemoryUserRepository(repository.NewMemoryStore()), models.TiersConfig{Levels: testTiers})
+
+	user, err := svc.Register(ctx, &models.UserCredentials{Login: "alice", Password: "secret"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if user.Tier != "bronze" {
+		t.Errorf("tier = %q, want bronze", user.Tier)
+	}
+}
+
+func TestAuthService_RegisterReferral(t *testing.T) {
+	ctx := context.Background()
+	svc := NewAuthService(repository.NewMemoryUserRepository(repository.NewMemoryStore()), models.TiersConfig{})
+
+	alice, err := svc.Register(ctx, &models.UserCredentials{Login: "alice", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alice.ReferralCode) != referralCodeLength || strings.Trim(alice.ReferralCode, referralCodeAlphabet) != "" {
+		t.Errorf("referral code = %q", alice.ReferralCode)
+	}
+	if alice.ReferredBy != nil {
+		t.Errorf("alice referred by %d", *alice.ReferredBy)
+	}
+
+	bob, err := svc.Register(ctx, &models.UserCredentials{
+		Login: "bob", Password: "secret", Referrer: " " + strings.ToLower(alice.ReferralCode),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bob.ReferredBy == nil || *bob.ReferredBy != alice.ID || bob.ReferralCode == alice.ReferralCode {
+		t.Errorf("bob = %+v, want referred by alice with own code", bob)
+	}
+
+	_, err = svc.Register(ctx, &models.UserCredentials{Login: "carol", Password: "secret", Referrer: "NOBODY"})
+	if !errors.Is(err, ErrUnknownReferrer) {
+		t.Errorf("unknown referrer error = %v, want ErrUnknownReferrer", err)
+	}
+}
+
+func TestAuthService_Login(t *testing.T) {
+	ctx := context.Background()
+	svc := NewAuthService(repository.NewMemoryUserRepository(repository.NewMemoryStore()), models.TiersConfig{})
+
+	registered, err := svc.Register(ctx, &models.UserCredentials{Login: "alice", Password: "secret"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		creds   models.UserCredentials
+		wantErr error
+	}{
+		{name: "valid", creds: models.UserCredentials{Login: "alice", Password: "secret"}},
+		{name: "wrong password", creds: models.UserCredentials{Login: "alice", Password: "wrong"}, wantErr: ErrInvalidCredentials},
+		{name: "unknown login", creds: models.UserCredentials{Login: "bob", Password: "secret"}, wantErr: ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := svc.Login(ctx, &tt.creds)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && user.ID != registered.ID {
+				t.Errorf("user ID = %d, want %d", user.ID, registered.ID)
+			}
+		})
+	}
+}
@@ -4,24 +4,42 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"strings"
+	"time"
 
 	"github.com/RoGogDBD/loyalty_service/server/internal/models"
 	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
 )
 
-var ErrInsufficientFunds = errors.New("insufficient funds")
+var (
+	ErrInsufficientFunds     = errors.New("insufficient funds")
+	ErrInvalidAdjustment     = errors.New("adjustment amount must be non-zero")
+	ErrAdjustmentReasonEmpty = errors.New("adjustment reason is required")
+)
 
 type BalanceService interface {
+	// GetBalance возвращает баланс вместе с баллами, сгорающими в пределах
+	// окна ExpiringWindow.
 	GetBalance(ctx context.Context, userID int) (*models.Balance, error)
 	AddAccrual(ctx context.Context, userID int, amount float64) error
+	// Adjust вручную изменяет баланс пользователя с указанием причины.
+	Adjust(ctx context.Context, userID int, amount float64, reason string) (*models.BalanceAdjustment, error)
+	GetAdjustments(ctx context.Context, userID int) ([]*models.BalanceAdjustment, error)
+	// ExpirePoints сжигает баллы с истёкшим сроком и возвращает их сумму.
+	ExpirePoints(ctx context.Context) (float64, error)
 }
 
 type balanceService struct {
 	balanceRepo repository.BalanceRepository
+	points      models.PointsConfig
+	now         func() time.Time
 }
 
-func NewBalanceService(balanceRepo repository.BalanceRepository) BalanceService {
-	return &balanceService{balanceRepo: balanceRepo}
+// NewBalanceService создаёт сервис баланса. Начисления и положительные
+// корректировки сгорают через points.TTL, если он задан.
+func NewBalanceService(balanceRepo repository.BalanceRepository, points models.PointsConfig) BalanceService {
+	return &balanceService{balanceRepo: balanceRepo, points: points, now: time.Now}
 }
 
 func (s *balanceService) GetBalance(ctx context.Context, userID int) (*models.Balance, error) {
@@ -30,6 +48,13 @@ func (s *balanceService) GetBalance(ctx context.Context, userID int) (*models.Ba
 		return nil, fmt.Errorf("failed to get balance: %w", err)
 	}
 
+	if s.points.ExpiringWindow > 0 && balance.Current > 0 {
+		balance.Expiring, err = s.balanceRepo.GetExpiring(ctx, userID, s.now().Add(s.points.ExpiringWindow))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get expiring points: %w", err)
+		}
+	}
+
 	return balance, nil
 }
 
@@ -38,9 +63,67 @@ func (s *balanceService) AddAccrual(ctx context.Context, userID int, amount floa
 		return fmt.Errorf("invalid accrual amount")
 	}
 
-	if err := s.balanceRepo.AddAccrual(ctx, userID, amount); err != nil {
+	if err := s.balanceRepo.AddAccrual(ctx, userID, amount, s.expiresAt()); err != nil {
 		return fmt.Errorf("failed to add accrual: %w", err)
 	}
 
 	return nil
 }
+
+func (s *balanceService) Adjust(ctx context.Context, userID int, amount float64, reason string) (*models.BalanceAdjustment, error) {
+	// Баланс хранится с точностью до копеек.
+	amount = math.Round(amount*100) / 100
+	if amount == 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return nil, ErrInvalidAdjustment
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrAdjustmentReasonEmpty
+	}
+
+	adjustment, err := s.balanceRepo.Adjust(ctx, userID, amount, reason, s.expiresAt())
+	if err != nil {
+		if errors.Is(err, repository.ErrInsufficientFunds) {
+			return nil, ErrInsufficientFunds
+		}
+		return nil, fmt.Errorf("failed to adjust balance: %w", err)
+	}
+
+	return adjustment, nil
+}
+
+func (s *balanceService) GetAdjustments(ctx context.Context, userID int) ([]*models.BalanceAdjustment, error) {
+	adjustments, err := s.balanceRepo.GetAdjustments(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get adjustments: %w", err)
+	}
+
+	return adjustments, nil
+}
+
+func (s *balanceService) ExpirePoints(ctx context.Context) (float64, error) {
+	expired, err := s.balanceRepo.Expire(ctx, s.now())
+	if err != nil {
+		return expired, fmt.Errorf("failed to expire points: %w", err)
+	}
+
+	return expired, nil
+}
+
+// expiresAt возвращает срок сгорания баллов, начисляемых сейчас, или nil,
+// если баллы не сгорают.
+func (s *balanceService) expiresAt() *time.Time {
+	return pointsExpiry(s.points, s.now())
+}
+
+// pointsExpiry возвращает срок сгорания баллов, начисленных в момент now.
+func pointsExpiry(points models.PointsConfig, now time.Time) *time.Time {
+	if points.TTL <= 0 {
+		return nil
+	}
+	// Срок округляется вверх до начала суток по UTC: начисления одного дня
+	// сгорают вместе и показываются в балансе одной строкой.
+	t := now.UTC().Add(points.TTL).Truncate(24 * time.Hour).Add(24 * time.Hour)
+	return &t
+}
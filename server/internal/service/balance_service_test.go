@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+)
+
+func TestBalanceService_AddAccrual(t *testing.T) {
+	ctx := context.Background()
+	svc := NewBalanceService(repository.NewMemoryBalanceRepository(repository.NewMemoryStore()), models.PointsConfig{})
+
+	for _, amount := range []float64{0, -10} {
+		if err := svc.AddAccrual(ctx, 1, amount); err == nil {
+			t.Errorf("AddAccrual(%v) succeeded, want error", amount)
+		}
+	}
+
+	if err := svc.AddAccrual(ctx, 1, 100.5); err != nil {
+		t.Fatalf("AddAccrual: %v", err)
+	}
+	if err := svc.AddAccrual(ctx, 1, 20); err != nil {
+		t.Fatalf("AddAccrual: %v", err)
+	}
+
+	balance, err := svc.GetBalance(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	if balance.Current != 120.5 || balance.Withdrawn != 0 {
+		t.Errorf("balance = %+v, want current 120.5", balance)
+	}
+}
+
+func TestBalanceService_Adjust(t *testing.T) {
+	ctx := context.Background()
+	svc := NewBalanceService(repository.NewMemoryBalanceRepository(repository.NewMemoryStore()), models.PointsConfig{})
+
+	tests := []struct {
+		name    string
+		amount  float64
+		reason  string
+		wantErr error
+	}{
+		{"zero amount", 0, "typo", ErrInvalidAdjustment},
+		{"rounds to zero", 0.001, "typo", ErrInvalidAdjustment},
+		{"empty reason", 10, "  ", ErrAdjustmentReasonEmpty},
+		{"below zero", -10, "chargeback", ErrInsufficientFunds},
+		{"credit", 10.004, " goodwill ", nil},
+		{"debit", -2.5, "chargeback", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Adjust(ctx, 1, tt.amount, tt.reason)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Adjust(%v, %q) error = %v, want %v", tt.amount, tt.reason, err, tt.wantErr)
+			}
+		})
+	}
+
+	balance, _ := svc.GetBalance(ctx, 1)
+	if balance.Current != 7.5 {
+		t.Errorf("current = %v, want 7.5", balance.Current)
+	}
+
+	adjustments, err := svc.GetAdjustments(ctx, 1)
+	if err != nil || len(adjustments) != 2 || adjustments[1].Reason != "goodwill" || adjustments[1].Amount != 10 {
+		t.Errorf("GetAdjustments = %+v, %v", adjustments, err)
+	}
+}
+
+func TestBalanceService_ExpirePoints(t *testing.T) {
+	ctx := context.Background()
+	svc := NewBalanceService(repository.NewMemoryBalanceRepository(repository.NewMemoryStore()), models.PointsConfig{
+		TTL:            30 * 24 * time.Hour,
+		ExpiringWindow: 3 * 24 * time.Hour,
+	}).(*balanceService)
+
+	start := time.Date(2026, 3, 1, 15, 30, 0, 0, time.UTC)
+	svc.now = func() time.Time { return start }
+	if err := svc.AddAccrual(ctx, 1, 100); err != nil {
+		t.Fatal(err)
+	}
+	svc.now = func() time.Time { return start.Add(6 * time.Hour) }
+	if _, err := svc.Adjust(ctx, 1, 20, "goodwill"); err != nil {
+		t.Fatal(err)
+	}
+	svc.now = func() time.Time { return start.Add(5 * 24 * time.Hour) }
+	if err := svc.AddAccrual(ctx, 1, 50); err != nil {
+		t.Fatal(err)
+	}
+
+	// Начисления одного дня сгорают вместе в начале суток после истечения срока.
+	expiresAt := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	svc.now = func() time.Time { return expiresAt.Add(-8 * 24 * time.Hour) }
+	if balance, _ := svc.GetBalance(ctx, 1); len(balance.Expiring) != 0 {
+		t.Errorf("expiring outside the window = %+v", balance.Expiring)
+	}
+
+	svc.now = func() time.Time { return expiresAt.Add(-24 * time.Hour) }
+	balance, err := svc.GetBalance(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(balance.Expiring) != 1 || balance.Expiring[0].Amount != 120 || !balance.Expiring[0].ExpiresAt.Equal(expiresAt) {
+		t.Errorf("expiring = %+v, want 120 at %s", balance.Expiring, expiresAt)
+	}
+
+	svc.now = func() time.Time { return expiresAt }
+	expired, err := svc.ExpirePoints(ctx)
+	if err != nil || expired != 120 {
+		t.Fatalf("ExpirePoints = %v, %v; want 120", expired, err)
+	}
+	if balance, _ := svc.GetBalance(ctx, 1); balance.Current != 50 {
+		t.Errorf("current = %v, want 50", balance.Current)
+	}
+}
+
+func TestBalanceService_NoExpiry(t *testing.T) {
+	ctx := context.Background()
+	svc := NewBalanceService(repository.NewMemoryBalanceRepository(repository.NewMemoryStore()), models.PointsConfig{
+		ExpiringWindow: 24 * time.Hour,
+	})
+
+	if err := svc.AddAccrual(ctx, 1, 100); err != nil {
+		t.Fatal(err)
+	}
+	if expired, err := svc.ExpirePoints(ctx); err != nil || expired != 0 {
+		t.Errorf("ExpirePoints = %v, %v; want nothing expired", expired, err)
+	}
+	if balance, _ := svc.GetBalance(ctx, 1); balance.Current != 100 || len(balance.Expiring) != 0 {
+		t.Errorf("balance = %+v", balance)
+	}
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+)
+
+var (
+	ErrInvalidCampaign  = errors.New("invalid campaign")
+	ErrCampaignNotFound = errors.New("campaign not found")
+	ErrBonusNotFound    = errors.New("campaign bonus not found")
+	ErrBonusReversed    = errors.New("campaign bonus already reversed")
+)
+
+// CampaignService — промоакции: бонусы к начислениям по заказам,
+// обработанным в период кампании.
+type CampaignService interface {
+	CreateCampaign(ctx context.Context, campaign *models.Campaign) (*models.Campaign, error)
+	ListCampaigns(ctx context.Context) ([]*models.Campaign, error)
+	// EndCampaign досрочно завершает кампанию.
+	EndCampaign(ctx context.Context, id int) error
+	// ApplyBonuses начисляет бонусы действующих кампаний за заказ,
+	// обработанный с положительным начислением accrual. Уровень пользователя для кампаний
+	// с уровнем берётся сохранённый. Повторный вызов для того же заказа
+	// бонусы не дублирует.
+	ApplyBonuses(ctx context.Context, order *models.Order, accrual float64) ([]*models.CampaignBonus, error)
+	GetBonuses(ctx context.Context, userID int) ([]*models.CampaignBonus, error)
+	// ReverseBonus отменяет бонус и списывает его с баланса.
+	ReverseBonus(ctx context.Context, id int) (*models.CampaignBonus, error)
+}
+
+type campaignService struct {
+	campaignRepo repository.CampaignRepository
+	userRepo     repository.UserRepository
+	points       models.PointsConfig
+	now          func() time.Time
+}
+
+// NewCampaignService создаёт сервис кампаний. Бонусы сгорают по тем же
+// правилам points, что и начисления.
+func NewCampaignService(
+	campaignRepo repository.CampaignRepository,
+	userRepo repository.UserRepository,
+	points models.PointsConfig,
+) CampaignService {
+	return &campaignService{
+		campaignRepo: campaignRepo,
+		userRepo:     userRepo,
+		points:       points,
+		now:          time.Now,
+	}
+}
+
+func (s *campaignService) CreateCampaign(ctx context.Context, campaign *models.Campaign) (*models.Campaign, error) {
+	c := *campaign
+	c.Name = strings.TrimSpace(c.Name)
+	c.Tier = strings.TrimSpace(c.Tier)
+	c.Bonus = math.Round(c.Bonus*100) / 100
+	c.MinAccrual = math.Round(c.MinAccrual*100) / 100
+
+	switch {
+	case c.Name == "":
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidCampaign)
+	case !c.EndsAt.After(c.StartsAt):
+		return nil, fmt.Errorf("%w: end must be after start", ErrInvalidCampaign)
+	case (c.Multiplier != 0) == (c.Bonus != 0):
+		return nil, fmt.Errorf("%w: exactly one of multiplier and bonus is required", ErrInvalidCampaign)
+	case c.Multiplier != 0 && !(c.Multiplier > 1 && c.Multiplier <= 100):
+		return nil, fmt.Errorf("%w: multiplier must be greater than 1 and at most 100", ErrInvalidCampaign)
+	case c.Bonus < 0 || math.IsNaN(c.Bonus) || math.IsInf(c.Bonus, 0):
+		return nil, fmt.Errorf("%w: bonus must be positive", ErrInvalidCampaign)
+	case !(c.MinAccrual >= 0) || math.IsInf(c.MinAccrual, 0):
+		return nil, fmt.Errorf("%w: minimum accrual must not be negative", ErrInvalidCampaign)
+	}
+
+	created, err := s.campaignRepo.CreateCampaign(ctx, &c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create campaign: %w", err)
+	}
+
+	return created, nil
+}
+
+func (s *campaignService) ListCampaigns(ctx context.Context) ([]*models.Campaign, error) {
+	campaigns, err := s.campaignRepo.ListCampaigns(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+
+	return campaigns, nil
+}
+
+func (s *campaignService) EndCampaign(ctx context.Context, id int) error {
+	ended, err := s.campaignRepo.EndCampaign(ctx, id, s.now())
+	if err != nil {
+		return fmt.Errorf("failed to end campaign: %w", err)
+	}
+	if !ended {
+		return ErrCampaignNotFound
+	}
+
+	return nil
+}
+
+func (s *campaignService) ApplyBonuses(ctx context.Context, order *models.Order, accrual float64) ([]*models.CampaignBonus, error) {
+	// Заказ без начисления бонусов не получает, даже от кампаний с
+	// фиксированным бонусом.
+	if accrual <= 0 {
+		return nil, nil
+	}
+
+	now := s.now()
+	campaigns, err := s.campaignRepo.ActiveCampaigns(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active campaigns: %w", err)
+	}
+
+	var (
+		granted []*models.CampaignBonus
+		user    *models.User
+	)
+	for _, campaign := range campaigns {
+		if accrual < campaign.MinAccrual {
+			continue
+		}
+		if campaign.Tier != "" {
+			if user == nil {
+				if user, err = s.userRepo.GetByID(ctx, order.UserID); err != nil {
+					return granted, fmt.Errorf("failed to get user: %w", err)
+				}
+				if user == nil {
+					user = &models.User{ID: order.UserID}
+				}
+			}
+			if user.Tier != campaign.Tier {
+				continue
+			}
+		}
+
+		amount := bonusAmount(campaign, accrual)
+		if amount <= 0 {
+			continue
+		}
+
+		bonus, err := s.campaignRepo.AddBonus(ctx, &models.CampaignBonus{
+			CampaignID:  campaign.ID,
+			Campaign:    campaign.Name,
+			UserID:      order.UserID,
+			OrderNumber: order.Number,
+			Amount:      amount,
+		}, pointsExpiry(s.points, now))
+		if errors.Is(err, repository.ErrBonusExists) {
+			continue
+		}
+		if err != nil {
+			return granted, fmt.Errorf("failed to add campaign bonus: %w", err)
+		}
+		granted = append(granted, bonus)
+	}
+
+	return granted, nil
+}
+
+// bonusAmount возвращает бонус кампании к начислению accrual, округлённый
+// до копеек.
+func bonusAmount(campaign *models.Campaign, accrual float64) float64 {
+	if campaign.Multiplier > 0 {
+		return math.Round(accrual*(campaign.Multiplier-1)*100) / 100
+	}
+	return campaign.Bonus
+}
+
+func (s *campaignService) GetBonuses(ctx context.Context, userID int) ([]*models.CampaignBonus, error) {
+	bonuses, err := s.campaignRepo.GetBonuses(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign bonuses: %w", err)
+	}
+
+	return bonuses, nil
+}
+
+func (s *campaignService) ReverseBonus(ctx context.Context, id int) (*models.CampaignBonus, error) {
+	bonus, err := s.campaignRepo.ReverseBonus(ctx, id)
+	switch {
+	case errors.Is(err, repository.ErrBonusReversed):
+		return nil, ErrBonusReversed
+	case errors.Is(err, repository.ErrInsufficientFunds):
+		return nil, ErrInsufficientFunds
+	case err != nil:
+		return nil, fmt.Errorf("failed to reverse campaign bonus: %w", err)
+	case bonus == nil:
+		return nil, ErrBonusNotFound
+	}
+
+	return bonus, nil
+}
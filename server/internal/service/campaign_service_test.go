@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+)
+
+func TestCampaignService_CreateCampaign(t *testing.T) {
+	ctx := context.Background()
+	svc := NewCampaignService(repository.NewMemoryCampaignRepository(repository.NewMemoryStore()), nil, models.PointsConfig{})
+
+	start := time.Date(2026, 10, 24, 0, 0, 0, 0, time.UTC)
+	end := start.Add(48 * time.Hour)
+	tests := []struct {
+		name     string
+		campaign models.Campaign
+		wantErr  error
+	}{
+		{"no name", models.Campaign{Name: " ", StartsAt: start, EndsAt: end, Multiplier: 2}, ErrInvalidCampaign},
+		{"empty period", models.Campaign{Name: "weekend", StartsAt: start, EndsAt: start, Multiplier: 2}, ErrInvalidCampaign},
+		{"no reward", models.Campaign{Name: "weekend", StartsAt: start, EndsAt: end}, ErrInvalidCampaign},
+		{"both rewards", models.Campaign{Name: "weekend", StartsAt: start, EndsAt: end, Multiplier: 2, Bonus: 10}, ErrInvalidCampaign},
+		{"multiplier not above one", models.Campaign{Name: "weekend", StartsAt: start, EndsAt: end, Multiplier: 0.5}, ErrInvalidCampaign},
+		{"negative bonus", models.Campaign{Name: "weekend", StartsAt: start, EndsAt: end, Bonus: -5}, ErrInvalidCampaign},
+		{"negative minimum", models.Campaign{Name: "weekend", StartsAt: start, EndsAt: end, Bonus: 5, MinAccrual: -1}, ErrInvalidCampaign},
+		{"double points", models.Campaign{Name: " weekend ", StartsAt: start, EndsAt: end, Multiplier: 2}, nil},
+		{"fixed bonus", models.Campaign{Name: "welcome", StartsAt: start, EndsAt: end, Bonus: 50.004, MinAccrual: 100}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created, err := svc.CreateCampaign(ctx, &tt.campaign)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateCampaign error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (created.ID == 0 || created.Name != "weekend" && created.Bonus != 50) {
+				t.Errorf("created = %+v", created)
+			}
+		})
+	}
+
+	if err := svc.EndCampaign(ctx, 1000); !errors.Is(err, ErrCampaignNotFound) {
+		t.Errorf("EndCampaign(missing) error = %v, want ErrCampaignNotFound", err)
+	}
+}
+
+func TestCampaignService_ApplyBonuses(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	users := repository.NewMemoryUserRepository(store)
+	orders := repository.NewMemoryOrderRepository(store)
+	balances := repository.NewMemoryBalanceRepository(store)
+	svc := NewCampaignService(repository.NewMemoryCampaignRepository(store), users, models.PointsConfig{})
+
+	alice, _ := users.Create(ctx, &models.User{Login: "alice", PasswordHash: "hash", ReferralCode: "ALICE"})
+	if err := users.SetTier(ctx, alice.ID, "silver"); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for _, c := range []*models.Campaign{
+		{Name: "double", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), Multiplier: 2},
+		{Name: "big orders", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), Bonus: 50, MinAccrual: 100},
+		{Name: "gold only", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), Bonus: 70, Tier: "gold"},
+		{Name: "silver only", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), Bonus: 5, Tier: "silver"},
+		{Name: "expired", StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour), Bonus: 1000},
+	} {
+		if _, err := svc.CreateCampaign(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	order, _, _ := orders.CreateOrGet(ctx, "12345678903", alice.ID)
+	if err := balances.AddAccrual(ctx, alice.ID, 120.5, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Без начисления не начисляются и фиксированные бонусы.
+	empty, _, _ := orders.CreateOrGet(ctx, "2377225624", alice.ID)
+	if bonuses, err := svc.ApplyBonuses(ctx, empty, 0); err != nil || len(bonuses) != 0 {
+		t.Errorf("ApplyBonuses(zero accrual) = %+v, %v; want none", bonuses, err)
+	}
+
+	bonuses, err := svc.ApplyBonuses(ctx, order, 120.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]float64)
+	for _, b := range bonuses {
+		got[b.Campaign] = b.Amount
+	}
+	want := map[string]float64{"double": 120.5, "big orders": 50, "silver only": 5}
+	if len(got) != len(want) || got["double"] != 120.5 || got["big orders"] != 50 || got["silver only"] != 5 {
+		t.Errorf("bonuses = %v, want %v", got, want)
+	}
+
+	// Повторное применение по тому же заказу ничего не начисляет.
+	if again, err := svc.ApplyBonuses(ctx, order, 120.5); err != nil || len(again) != 0 {
+		t.Errorf("second ApplyBonuses = %+v, %v; want none", again, err)
+	}
+
+	balance, _ := balances.GetByUserID(ctx, alice.ID)
+	if balance.Current != 296 {
+		t.Errorf("balance = %v, want 296", balance.Current)
+	}
+
+	reversed, err := svc.ReverseBonus(ctx, bonuses[0].ID)
+	if err != nil || reversed.ReversedAt == nil {
+		t.Fatalf("ReverseBonus = %+v, %v", reversed, err)
+	}
+	if _, err := svc.ReverseBonus(ctx, bonuses[0].ID); !errors.Is(err, ErrBonusReversed) {
+		t.Errorf("second ReverseBonus error = %v, want ErrBonusReversed", err)
+	}
+	if _, err := svc.ReverseBonus(ctx, 1000); !errors.Is(err, ErrBonusNotFound) {
+		t.Errorf("ReverseBonus(missing) error = %v, want ErrBonusNotFound", err)
+	}
+
+	balance, _ = balances.GetByUserID(ctx, alice.ID)
+	if want := 296 - bonuses[0].Amount; balance.Current != want {
+		t.Errorf("balance after reversal = %v, want %v", balance.Current, want)
+	}
+}
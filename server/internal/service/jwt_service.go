@@ -2,6 +2,7 @@ package service
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -11,11 +12,21 @@ type (
 	JWTService interface {
 		GenerateToken(userID int) (string, error)
 		ValidateToken(tokenString string) (int, error)
+		// Reload заменяет ключи и срок жизни токенов. Выданные ранее токены
+		// остаются действительными, если их ключ есть среди verificationKeys.
+		Reload(secretKey string, duration time.Duration, verificationKeys ...string)
 	}
 
 	jwtService struct {
-		secretKey string
-		duration  time.Duration
+		mu       sync.RWMutex
+		keys     jwtKeys
+		duration time.Duration
+	}
+
+	// jwtKeys — ключ подписи и набор ключей, которыми проверяются токены.
+	jwtKeys struct {
+		signing []byte
+		verify  jwt.VerificationKeySet
 	}
 
 	Claims struct {
@@ -24,32 +35,63 @@ type (
 	}
 )
 
-func NewJWTService(secretKey string, duration time.Duration) JWTService {
+// NewJWTService создаёт сервис, подписывающий токены secretKey. Токены,
+// подписанные любым из verificationKeys, тоже принимаются — это позволяет
+// сменить ключ, не разлогинивая пользователей.
+func NewJWTService(secretKey string, duration time.Duration, verificationKeys ...string) JWTService {
 	return &jwtService{
-		secretKey: secretKey,
-		duration:  duration,
+		keys:     newJWTKeys(secretKey, verificationKeys),
+		duration: duration,
+	}
+}
+
+func newJWTKeys(secretKey string, verificationKeys []string) jwtKeys {
+	keys := jwtKeys{signing: []byte(secretKey)}
+	keys.verify.Keys = append(keys.verify.Keys, keys.signing)
+	for _, key := range verificationKeys {
+		if key != secretKey {
+			keys.verify.Keys = append(keys.verify.Keys, []byte(key))
+		}
 	}
+	return keys
+}
+
+func (s *jwtService) Reload(secretKey string, duration time.Duration, verificationKeys ...string) {
+	keys := newJWTKeys(secretKey, verificationKeys)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+	s.duration = duration
 }
 
 func (s *jwtService) GenerateToken(userID int) (string, error) {
+	s.mu.RLock()
+	signing, duration := s.keys.signing, s.duration
+	s.mu.RUnlock()
+
 	claims := &Claims{
 		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.duration)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.secretKey))
+	return token.SignedString(signing)
 }
 
 func (s *jwtService) ValidateToken(tokenString string) (int, error) {
+	s.mu.RLock()
+	verify := s.keys.verify
+	s.mu.RUnlock()
+
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(s.secretKey), nil
+		return verify, nil
 	})
 
 	if err != nil {
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJWTService(t *testing.T) {
+	svc := NewJWTService("secret", time.Hour)
+
+	token, err := svc.GenerateToken(42)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	userID, err := svc.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if userID != 42 {
+		t.Errorf("userID = %d, want 42", userID)
+	}
+
+	tests := []struct {
+		name  string
+		svc   JWTService
+		token string
+	}{
+		{name: "wrong secret", svc: NewJWTService("other", time.Hour), token: token},
+		{name: "garbage", svc: svc, token: "not-a-token"},
+		{name: "expired", svc: svc, token: mustToken(t, NewJWTService("secret", -time.Minute), 42)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.svc.ValidateToken(tt.token); err == nil {
+				t.Error("ValidateToken succeeded, want error")
+			}
+		})
+	}
+}
+
+func mustToken(t *testing.T, svc JWTService, userID int) string {
+	t.Helper()
+	token, err := svc.GenerateToken(userID)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	return token
+}
+
+func TestJWTService_Reload(t *testing.T) {
+	svc := NewJWTService("old", time.Hour)
+	oldToken := mustToken(t, svc, 7)
+
+	svc.Reload("new", time.Hour, "old")
+
+	for name, token := range map[string]string{
+		"token signed with previous key": oldToken,
+		"token signed with new key":      mustToken(t, svc, 7),
+	} {
+		userID, err := svc.ValidateToken(token)
+		if err != nil {
+			t.Fatalf("%s: ValidateToken: %v", name, err)
+		}
+		if userID != 7 {
+			t.Errorf("%s: userID = %d, want 7", name, userID)
+		}
+	}
+
+	if _, err := NewJWTService("old", time.Hour).ValidateToken(mustToken(t, svc, 7)); err == nil {
+		t.Error("token signed with new key accepted by old-only service")
+	}
+
+	svc.Reload("new", time.Hour)
+	if _, err := svc.ValidateToken(oldToken); err == nil {
+		t.Error("token signed with retired key accepted")
+	}
+}
@@ -13,13 +13,28 @@ var (
 	ErrInvalidOrderNumber = errors.New("invalid order number")
 	ErrOrderExists        = errors.New("order already uploaded by this user")
 	ErrOrderConflict      = errors.New("order already uploaded by another user")
+	ErrEmptyOrderBatch    = errors.New("order batch is empty")
+	ErrOrderBatchTooLarge = errors.New("order batch is too large")
+	ErrOrderNotFound      = errors.New("order not found")
+	ErrOrderProcessed     = errors.New("order is already processed")
 )
 
+// MaxOrderBatchSize ограничивает количество номеров в одном пакетном запросе.
+const MaxOrderBatchSize = 1000
+
 type OrderService interface {
 	UploadOrder(ctx context.Context, number string, userID int) (*models.Order, error)
+	UploadOrders(ctx context.Context, numbers []string, userID int) ([]*models.OrderUploadResult, error)
 	GetUserOrders(ctx context.Context, userID int) ([]*models.Order, error)
+	GetOrder(ctx context.Context, number string) (*models.Order, error)
+	// UpdateStatus записывает результат расчёта начисления. Для заказа в
+	// окончательном статусе возвращает ErrOrderProcessed.
 	UpdateStatus(ctx context.Context, orderID int, status string, accrual float64) error
-	GetPendingOrders(ctx context.Context) ([]*models.Order, error)
+	GetPendingOrders(ctx context.Context, afterID, limit int) ([]*models.Order, error)
+	// RequeueOrder возвращает заказ в статус NEW, чтобы он снова был
+	// опрошен в системе начислений. Обработанные заказы не переопрашиваются,
+	// иначе начисление было бы зачислено повторно.
+	RequeueOrder(ctx context.Context, number string) (*models.Order, error)
 }
 
 type orderService struct {
@@ -36,26 +51,72 @@ func (s *orderService) UploadOrder(ctx context.Context, number string, userID in
 		return nil, ErrInvalidOrderNumber
 	}
 
-	// Проверяем существование заказа
-	existing, err := s.orderRepo.GetByNumber(ctx, number)
+	// Вставка и проверка владельца выполняются одним атомарным шагом,
+	// поэтому параллельные загрузки одного номера не приводят к 500.
+	order, created, err := s.orderRepo.CreateOrGet(ctx, number, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check order existence: %w", err)
+		return nil, fmt.Errorf("failed to create order: %w", err)
 	}
 
-	if existing != nil {
-		if existing.UserID == userID {
+	if !created {
+		if order.UserID == userID {
 			return nil, ErrOrderExists
 		}
 		return nil, ErrOrderConflict
 	}
 
-	// Создаём заказ
-	order, err := s.orderRepo.Create(ctx, number, userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create order: %w", err)
+	return order, nil
+}
+
+// UploadOrders загружает пакет номеров и возвращает результат по каждому
+// номеру в порядке запроса.
+func (s *orderService) UploadOrders(ctx context.Context, numbers []string, userID int) ([]*models.OrderUploadResult, error) {
+	if len(numbers) == 0 {
+		return nil, ErrEmptyOrderBatch
+	}
+	if len(numbers) > MaxOrderBatchSize {
+		return nil, ErrOrderBatchTooLarge
 	}
 
-	return order, nil
+	valid := make([]string, 0, len(numbers))
+	for _, number := range numbers {
+		if repository.ValidateLuhn(number) {
+			valid = append(valid, number)
+		}
+	}
+
+	byNumber := make(map[string]*repository.OrderInsertResult, len(valid))
+	if len(valid) > 0 {
+		inserted, err := s.orderRepo.CreateBatch(ctx, valid, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create orders: %w", err)
+		}
+		for _, res := range inserted {
+			byNumber[res.Number] = res
+		}
+	}
+
+	// Повтор номера внутри одного пакета считается уже загруженным.
+	seen := make(map[string]bool, len(numbers))
+	results := make([]*models.OrderUploadResult, 0, len(numbers))
+	for _, number := range numbers {
+		result := &models.OrderUploadResult{Number: number}
+		res, ok := byNumber[number]
+		switch {
+		case !ok:
+			result.Result = models.OrderUploadInvalid
+		case res.OwnerID != userID:
+			result.Result = models.OrderUploadConflict
+		case res.Created && !seen[number]:
+			result.Result = models.OrderUploadAccepted
+		default:
+			result.Result = models.OrderUploadExists
+		}
+		seen[number] = true
+		results = append(results, result)
+	}
+
+	return results, nil
 }
 
 func (s *orderService) GetUserOrders(ctx context.Context, userID int) ([]*models.Order, error) {
@@ -67,14 +128,46 @@ func (s *orderService) GetUserOrders(ctx context.Context, userID int) ([]*models
 	return orders, nil
 }
 
+func (s *orderService) GetOrder(ctx context.Context, number string) (*models.Order, error) {
+	order, err := s.orderRepo.GetByNumber(ctx, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
 func (s *orderService) UpdateStatus(ctx context.Context, orderID int, status string, accrual float64) error {
-	return s.orderRepo.UpdateStatusByID(ctx, orderID, status, accrual)
+	if err := s.orderRepo.UpdateStatusByID(ctx, orderID, status, accrual); err != nil {
+		if errors.Is(err, repository.ErrOrderFinalized) {
+			return ErrOrderProcessed
+		}
+		return err
+	}
+	return nil
 }
 
-func (s *orderService) GetPendingOrders(ctx context.Context) ([]*models.Order, error) {
-	orders, err := s.orderRepo.GetPendingOrders(ctx)
+func (s *orderService) GetPendingOrders(ctx context.Context, afterID, limit int) ([]*models.Order, error) {
+	orders, err := s.orderRepo.GetPendingOrders(ctx, afterID, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pending orders: %w", err)
 	}
 	return orders, nil
 }
+
+func (s *orderService) RequeueOrder(ctx context.Context, number string) (*models.Order, error) {
+	order, err := s.orderRepo.Requeue(ctx, number)
+	if errors.Is(err, repository.ErrOrderFinalized) {
+		// Отсутствующий заказ отличаем от обработанного повторным чтением.
+		if _, err := s.GetOrder(ctx, number); err != nil {
+			return nil, err
+		}
+		return nil, ErrOrderProcessed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to requeue order: %w", err)
+	}
+	return order, nil
+}
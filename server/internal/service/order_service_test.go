@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+)
+
+func newOrderRepo() repository.OrderRepository {
+	return repository.NewMemoryOrderRepository(repository.NewMemoryStore())
+}
+
+type failingOrderRepo struct {
+	repository.OrderRepository
+	err error
+}
+
+func (r *failingOrderRepo) CreateOrGet(context.Context, string, int) (*models.Order, bool, error) {
+	return nil, false, r.err
+}
+
+func TestOrderService_UploadOrder(t *testing.T) {
+	ctx := context.Background()
+	svc := NewOrderService(newOrderRepo())
+
+	if _, err := svc.UploadOrder(ctx, "12345678903", 1); err != nil {
+		t.Fatalf("first upload: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		number  string
+		userID  int
+		wantErr error
+	}{
+		{name: "same user", number: "12345678903", userID: 1, wantErr: ErrOrderExists},
+		{name: "other user", number: "12345678903", userID: 2, wantErr: ErrOrderConflict},
+		{name: "bad checksum", number: "12345678900", userID: 1, wantErr: ErrInvalidOrderNumber},
+		{name: "not digits", number: "12a45", userID: 1, wantErr: ErrInvalidOrderNumber},
+		{name: "new number", number: "9278923470", userID: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := svc.UploadOrder(ctx, tt.number, tt.userID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (order.UserID != tt.userID || order.Status != models.OrderStatusNew) {
+				t.Errorf("order = %+v", order)
+			}
+		})
+	}
+}
+
+func TestOrderService_UploadOrder_RepositoryError(t *testing.T) {
+	svc := NewOrderService(&failingOrderRepo{OrderRepository: newOrderRepo(), err: errors.New("db down")})
+
+	_, err := svc.UploadOrder(context.Background(), "12345678903", 1)
+	if err == nil || errors.Is(err, ErrOrderExists) || errors.Is(err, ErrOrderConflict) {
+		t.Fatalf("error = %v, want wrapped repository error", err)
+	}
+}
+
+// TestOrderService_UploadOrder_Concurrent загружает один номер параллельно
+// от двух пользователей: ровно одна загрузка принимается, остальные получают
+// ErrOrderExists или ErrOrderConflict в зависимости от владельца.
+func TestOrderService_UploadOrder_Concurrent(t *testing.T) {
+	const perUser = 50
+
+	svc := NewOrderService(newOrderRepo())
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted = make(map[int]int)
+		errs     = make(map[error]int)
+	)
+	for i := 0; i < 2*perUser; i++ {
+		userID := i%2 + 1
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.UploadOrder(context.Background(), "12345678903", userID)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				accepted[userID]++
+				return
+			}
+			errs[err]++
+		}()
+	}
+	wg.Wait()
+
+	if len(accepted) != 1 {
+		t.Fatalf("accepted = %v, want exactly one upload", accepted)
+	}
+	if errs[ErrOrderExists] != perUser-1 || errs[ErrOrderConflict] != perUser {
+		t.Errorf("errors = %v, want %d ErrOrderExists and %d ErrOrderConflict", errs, perUser-1, perUser)
+	}
+}
+
+func TestOrderService_UploadOrders(t *testing.T) {
+	ctx := context.Background()
+	svc := NewOrderService(newOrderRepo())
+
+	if _, err := svc.UploadOrder(ctx, "9278923470", 2); err != nil {
+		t.Fatalf("seed order: %v", err)
+	}
+	if _, err := svc.UploadOrder(ctx, "346436439", 1); err != nil {
+		t.Fatalf("seed order: %v", err)
+	}
+
+	got, err := svc.UploadOrders(ctx, []string{"12345678903", "9278923470", "346436439", "123", "12345678903"}, 1)
+	if err != nil {
+		t.Fatalf("UploadOrders: %v", err)
+	}
+
+	want := []*models.OrderUploadResult{
+		{Number: "12345678903", Result: models.OrderUploadAccepted},
+		{Number: "9278923470", Result: models.OrderUploadConflict},
+		{Number: "346436439", Result: models.OrderUploadExists},
+		{Number: "123", Result: models.OrderUploadInvalid},
+		{Number: "12345678903", Result: models.OrderUploadExists},
+	}
+	if !reflect.DeepEqual(got, want) {
+		for i := range got {
+			t.Logf("got[%d] = %+v", i, got[i])
+		}
+		t.Fatal("unexpected results")
+	}
+}
+
+func TestOrderService_UploadOrders_Limits(t *testing.T) {
+	svc := NewOrderService(newOrderRepo())
+
+	if _, err := svc.UploadOrders(context.Background(), nil, 1); !errors.Is(err, ErrEmptyOrderBatch) {
+		t.Errorf("empty batch error = %v, want ErrEmptyOrderBatch", err)
+	}
+
+	numbers := make([]string, MaxOrderBatchSize+1)
+	for i := range numbers {
+		numbers[i] = "12345678903"
+	}
+	if _, err := svc.UploadOrders(context.Background(), numbers, 1); !errors.Is(err, ErrOrderBatchTooLarge) {
+		t.Errorf("large batch error = %v, want ErrOrderBatchTooLarge", err)
+	}
+}
+
+func TestOrderService_RequeueOrder(t *testing.T) {
+	ctx := context.Background()
+	repo := newOrderRepo()
+	svc := NewOrderService(repo)
+
+	if _, err := svc.RequeueOrder(ctx, "12345678903"); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("missing order: error = %v, want ErrOrderNotFound", err)
+	}
+
+	invalid, err := svc.UploadOrder(ctx, "12345678903", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateStatus(ctx, invalid.Number, models.OrderStatusInvalid, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := svc.RequeueOrder(ctx, invalid.Number)
+	if err != nil || order.Status != models.OrderStatusNew {
+		t.Fatalf("RequeueOrder = %+v, %v; want NEW", order, err)
+	}
+	stored, _ := repo.GetByNumber(ctx, invalid.Number)
+	if stored.Status != models.OrderStatusNew {
+		t.Errorf("stored status = %s, want NEW", stored.Status)
+	}
+
+	if err := svc.UpdateStatus(ctx, order.ID, models.OrderStatusProcessed, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.UpdateStatus(ctx, order.ID, models.OrderStatusProcessed, 10); !errors.Is(err, ErrOrderProcessed) {
+		t.Errorf("repeated update: error = %v, want ErrOrderProcessed", err)
+	}
+	if _, err := svc.RequeueOrder(ctx, invalid.Number); !errors.Is(err, ErrOrderProcessed) {
+		t.Errorf("processed order: error = %v, want ErrOrderProcessed", err)
+	}
+}
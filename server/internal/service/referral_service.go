@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+)
+
+// ReferralService — реферальная программа: вознаграждения пригласившему и
+// приглашённому за первый обработанный заказ приглашённого.
+type ReferralService interface {
+	// GetStatus возвращает реферальный код пользователя и итоги приглашений.
+	GetStatus(ctx context.Context, userID int) (*models.ReferralStatus, error)
+	// RewardReferral начисляет вознаграждение, если владелец обработанного
+	// заказа был приглашён и вознаграждение за него ещё не начислялось.
+	// Возвращает nil, если начислять нечего.
+	RewardReferral(ctx context.Context, order *models.Order) (*models.ReferralReward, error)
+}
+
+type referralService struct {
+	cfg          models.ReferralConfig
+	referralRepo repository.ReferralRepository
+	userRepo     repository.UserRepository
+	points       models.PointsConfig
+	now          func() time.Time
+}
+
+// NewReferralService создаёт сервис реферальной программы. Вознаграждения
+// сгорают по тем же правилам points, что и начисления.
+func NewReferralService(
+	cfg models.ReferralConfig,
+	referralRepo repository.ReferralRepository,
+	userRepo repository.UserRepository,
+	points models.PointsConfig,
+) ReferralService {
+	return &referralService{
+		cfg:          cfg,
+		referralRepo: referralRepo,
+		userRepo:     userRepo,
+		points:       points,
+		now:          time.Now,
+	}
+}
+
+func (s *referralService) GetStatus(ctx context.Context, userID int) (*models.ReferralStatus, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %d not found", userID)
+	}
+
+	status, err := s.referralRepo.GetStats(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get referral stats: %w", err)
+	}
+	status.Code = user.ReferralCode
+
+	return status, nil
+}
+
+func (s *referralService) RewardReferral(ctx context.Context, order *models.Order) (*models.ReferralReward, error) {
+	if s.cfg.ReferrerBonus == 0 && s.cfg.RefereeBonus == 0 {
+		return nil, nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, order.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || user.ReferredBy == nil {
+		return nil, nil
+	}
+
+	// Лимиты проверяются в репозитории вместе с записью вознаграждения.
+	// Вознаграждение сверх лимита записывается без баллов, чтобы
+	// приглашённый не получил его позже, когда суточный лимит освободится.
+	reward := &models.ReferralReward{
+		RefereeID:      user.ID,
+		ReferrerID:     *user.ReferredBy,
+		OrderNumber:    order.Number,
+		ReferrerAmount: s.cfg.ReferrerBonus,
+		RefereeAmount:  s.cfg.RefereeBonus,
+	}
+
+	created, err := s.referralRepo.AddReward(ctx, reward, s.cfg, pointsExpiry(s.points, s.now()))
+	if errors.Is(err, repository.ErrReferralRewarded) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to add referral reward: %w", err)
+	}
+
+	return created, nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+)
+
+func TestReferralService_RewardReferral(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	users := repository.NewMemoryUserRepository(store)
+	orders := repository.NewMemoryOrderRepository(store)
+	balances := repository.NewMemoryBalanceRepository(store)
+	auth := NewAuthService(users, models.TiersConfig{})
+	svc := NewReferralService(
+		models.ReferralConfig{ReferrerBonus: 100, RefereeBonus: 50, MaxRewards: 5, MaxDailyRewards: 2},
+		repository.NewMemoryReferralRepository(store),
+		users,
+		models.PointsConfig{},
+	).(*referralService)
+
+	alice, err := auth.Register(ctx, &models.UserCredentials{Login: "alice", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := func(login, number string) *models.Order {
+		t.Helper()
+		user, err := auth.Register(ctx, &models.UserCredentials{Login: login, Password: "secret", Referrer: alice.ReferralCode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		o, _, err := orders.CreateOrGet(ctx, number, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+
+	// Заказ пользователя без пригласившего не вознаграждается.
+	own, _, err := orders.CreateOrGet(ctx, "4561261212345467", alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reward, err := svc.RewardReferral(ctx, own); reward != nil || err != nil {
+		t.Errorf("reward without referrer = %+v, %v", reward, err)
+	}
+
+	bobOrder := order("bob", "12345678903")
+	reward, err := svc.RewardReferral(ctx, bobOrder)
+	if err != nil || reward == nil || reward.ReferrerAmount != 100 || reward.RefereeAmount != 50 || reward.Limited {
+		t.Fatalf("reward = %+v, %v", reward, err)
+	}
+	if again, err := svc.RewardReferral(ctx, bobOrder); again != nil || err != nil {
+		t.Errorf("second reward = %+v, %v; want nil", again, err)
+	}
+	for userID, want := range map[int]float64{alice.ID: 100, bobOrder.UserID: 50} {
+		if balance, _ := balances.GetByUserID(ctx, userID); balance.Current != want {
+			t.Errorf("user %d balance = %v, want %v", userID, balance.Current, want)
+		}
+	}
+
+	if reward, err := svc.RewardReferral(ctx, order("carol", "2377225624")); err != nil || reward.Limited {
+		t.Fatalf("second referee reward = %+v, %v", reward, err)
+	}
+	daveOrder := order("dave", "79927398713")
+	if reward, err := svc.RewardReferral(ctx, daveOrder); err != nil || !reward.Limited || reward.ReferrerAmount != 0 || reward.RefereeAmount != 0 {
+		t.Fatalf("reward over the daily limit = %+v, %v; want limited", reward, err)
+	}
+	// Отклонённое по лимиту вознаграждение не начисляется и позже.
+	svc.now = func() time.Time { return time.Now().Add(25 * time.Hour) }
+	if reward, err := svc.RewardReferral(ctx, daveOrder); reward != nil || err != nil {
+		t.Errorf("reward after the limit reset = %+v, %v; want nil", reward, err)
+	}
+
+	status, err := svc.GetStatus(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *status != (models.ReferralStatus{Code: alice.ReferralCode, Invited: 3, Rewarded: 2, Earned: 200}) {
+		t.Errorf("status = %+v", status)
+	}
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+)
+
+var (
+	ErrInvalidRewardRule      = errors.New("invalid reward rule")
+	ErrRewardRuleExists       = errors.New("reward rule already exists")
+	ErrRewardRuleNotFound     = errors.New("reward rule not found")
+	ErrInvalidOrderGoods      = errors.New("invalid order goods")
+	ErrOrderAlreadyRegistered = errors.New("order already registered")
+)
+
+// MaxOrderGoods ограничивает количество позиций в регистрируемом заказе.
+const MaxOrderGoods = 1000
+
+// RewardService — встроенный расчёт начислений: правила вознаграждения и
+// составы заказов, которые регистрируют партнёры. Реализует тот же метод
+// GetAccrual, что и клиенты внешней системы начислений, поэтому может
+// использоваться воркером вместо неё.
+type RewardService interface {
+	CreateRule(ctx context.Context, match string, reward float64, rewardType string) (*models.RewardRule, error)
+	ListRules(ctx context.Context) ([]*models.RewardRule, error)
+	DeleteRule(ctx context.Context, id int) error
+	// RegisterOrder рассчитывает начисление по действующим правилам и
+	// сохраняет его вместе с составом заказа.
+	RegisterOrder(ctx context.Context, number string, goods []models.OrderGood) (*models.RegisteredOrder, error)
+	// GetAccrual возвращает результат расчёта для зарегистрированного заказа
+	// или nil, если партнёр его ещё не зарегистрировал.
+	GetAccrual(ctx context.Context, number string) (*models.AccrualResult, error)
+}
+
+type rewardService struct {
+	rewardRepo repository.RewardRepository
+}
+
+func NewRewardService(rewardRepo repository.RewardRepository) RewardService {
+	return &rewardService{rewardRepo: rewardRepo}
+}
+
+func (s *rewardService) CreateRule(ctx context.Context, match string, reward float64, rewardType string) (*models.RewardRule, error) {
+	match = strings.TrimSpace(match)
+	if match == "" {
+		return nil, fmt.Errorf("%w: match is required", ErrInvalidRewardRule)
+	}
+	if reward <= 0 || math.IsInf(reward, 0) || math.IsNaN(reward) {
+		return nil, fmt.Errorf("%w: reward must be positive", ErrInvalidRewardRule)
+	}
+	switch rewardType {
+	case models.RewardTypePercent:
+		if reward > 100 {
+			return nil, fmt.Errorf("%w: percentage reward must not exceed 100", ErrInvalidRewardRule)
+		}
+	case models.RewardTypePoints:
+	default:
+		return nil, fmt.Errorf("%w: reward type must be %q or %q", ErrInvalidRewardRule, models.RewardTypePercent, models.RewardTypePoints)
+	}
+
+	rule, err := s.rewardRepo.CreateRule(ctx, &models.RewardRule{Match: match, Reward: reward, RewardType: rewardType})
+	if err != nil {
+		if errors.Is(err, repository.ErrRewardRuleExists) {
+			return nil, ErrRewardRuleExists
+		}
+		return nil, fmt.Errorf("failed to create reward rule: %w", err)
+	}
+
+	return rule, nil
+}
+
+func (s *rewardService) ListRules(ctx context.Context) ([]*models.RewardRule, error) {
+	rules, err := s.rewardRepo.ListRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reward rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (s *rewardService) DeleteRule(ctx context.Context, id int) error {
+	deleted, err := s.rewardRepo.DeleteRule(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete reward rule: %w", err)
+	}
+	if !deleted {
+		return ErrRewardRuleNotFound
+	}
+
+	return nil
+}
+
+func (s *rewardService) RegisterOrder(ctx context.Context, number string, goods []models.OrderGood) (*models.RegisteredOrder, error) {
+	if !repository.ValidateLuhn(number) {
+		return nil, ErrInvalidOrderNumber
+	}
+	if err := validateGoods(goods); err != nil {
+		return nil, err
+	}
+
+	rules, err := s.rewardRepo.ListRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reward rules: %w", err)
+	}
+
+	order, err := s.rewardRepo.RegisterOrder(ctx, &models.RegisteredOrder{
+		Number:  number,
+		Goods:   goods,
+		Accrual: CalculateAccrual(goods, rules),
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderRegistered) {
+			return nil, ErrOrderAlreadyRegistered
+		}
+		return nil, fmt.Errorf("failed to register order: %w", err)
+	}
+
+	return order, nil
+}
+
+func (s *rewardService) GetAccrual(ctx context.Context, number string) (*models.AccrualResult, error) {
+	order, err := s.rewardRepo.GetRegisteredOrder(ctx, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get registered order: %w", err)
+	}
+	if order == nil {
+		return nil, nil
+	}
+
+	return &models.AccrualResult{
+		Order:   order.Number,
+		Status:  models.AccrualStatusProcessed,
+		Accrual: order.Accrual,
+	}, nil
+}
+
+func validateGoods(goods []models.OrderGood) error {
+	if len(goods) == 0 {
+		return fmt.Errorf("%w: at least one good is required", ErrInvalidOrderGoods)
+	}
+	if len(goods) > MaxOrderGoods {
+		return fmt.Errorf("%w: at most %d goods are allowed", ErrInvalidOrderGoods, MaxOrderGoods)
+	}
+	for i, good := range goods {
+		if strings.TrimSpace(good.Description) == "" {
+			return fmt.Errorf("%w: goods[%d]: description is required", ErrInvalidOrderGoods, i)
+		}
+		if good.Price < 0 || math.IsInf(good.Price, 0) || math.IsNaN(good.Price) {
+			return fmt.Errorf("%w: goods[%d]: price must be non-negative", ErrInvalidOrderGoods, i)
+		}
+	}
+	return nil
+}
+
+// CalculateAccrual считает начисление за заказ. К каждому товару
+// применяется первое по порядку создания правило, Match которого входит в
+// описание товара без учёта регистра: процент от цены или фиксированные
+// баллы. Товары без подходящего правила ничего не приносят. Результат
+// округляется до копеек.
+func CalculateAccrual(goods []models.OrderGood, rules []*models.RewardRule) float64 {
+	var total float64
+	for _, good := range goods {
+		description := strings.ToLower(good.Description)
+		for _, rule := range rules {
+			if !strings.Contains(description, strings.ToLower(rule.Match)) {
+				continue
+			}
+			switch rule.RewardType {
+			case models.RewardTypePercent:
+				total += good.Price * rule.Reward / 100
+			case models.RewardTypePoints:
+				total += rule.Reward
+			}
+			break
+		}
+	}
+	return math.Round(total*100) / 100
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+)
+
+func TestCalculateAccrual(t *testing.T) {
+	rules := []*models.RewardRule{
+		{ID: 1, Match: "Bork", Reward: 10, RewardType: models.RewardTypePercent},
+		{ID: 2, Match: "чайник", Reward: 50, RewardType: models.RewardTypePoints},
+		{ID: 3, Match: "LG", Reward: 12.5, RewardType: models.RewardTypePoints},
+	}
+
+	tests := []struct {
+		name  string
+		goods []models.OrderGood
+		want  float64
+	}{
+		{name: "percent", goods: []models.OrderGood{{Description: "Пылесос Bork V7", Price: 7000}}, want: 700},
+		{name: "case insensitive", goods: []models.OrderGood{{Description: "ЧАЙНИК электрический", Price: 1000}}, want: 50},
+		{name: "first rule wins", goods: []models.OrderGood{{Description: "Чайник Bork K810", Price: 900}}, want: 90},
+		{name: "no rule", goods: []models.OrderGood{{Description: "Кружка", Price: 300}}, want: 0},
+		{
+			name: "sum over goods",
+			goods: []models.OrderGood{
+				{Description: "Телевизор LG", Price: 50000},
+				{Description: "Утюг Bork", Price: 333.33},
+				{Description: "Кружка", Price: 300},
+			},
+			want: 45.83,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CalculateAccrual(tt.goods, rules); got != tt.want {
+				t.Errorf("CalculateAccrual = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewardService_Rules(t *testing.T) {
+	ctx := context.Background()
+	svc := NewRewardService(repository.NewMemoryRewardRepository(repository.NewMemoryStore()))
+
+	invalid := []struct {
+		match      string
+		reward     float64
+		rewardType string
+	}{
+		{match: " ", reward: 10, rewardType: models.RewardTypePercent},
+		{match: "Bork", reward: 0, rewardType: models.RewardTypePercent},
+		{match: "Bork", reward: 101, rewardType: models.RewardTypePercent},
+		{match: "Bork", reward: 10, rewardType: "rub"},
+	}
+	for _, tt := range invalid {
+		if _, err := svc.CreateRule(ctx, tt.match, tt.reward, tt.rewardType); !errors.Is(err, ErrInvalidRewardRule) {
+			t.Errorf("CreateRule(%q, %v, %q) error = %v, want ErrInvalidRewardRule", tt.match, tt.reward, tt.rewardType, err)
+		}
+	}
+
+	rule, err := svc.CreateRule(ctx, " Bork ", 10, models.RewardTypePercent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Match != "Bork" {
+		t.Errorf("Match = %q, want trimmed", rule.Match)
+	}
+	if _, err := svc.CreateRule(ctx, "Bork", 5, models.RewardTypePoints); !errors.Is(err, ErrRewardRuleExists) {
+		t.Errorf("duplicate error = %v, want ErrRewardRuleExists", err)
+	}
+
+	if err := svc.DeleteRule(ctx, rule.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.DeleteRule(ctx, rule.ID); !errors.Is(err, ErrRewardRuleNotFound) {
+		t.Errorf("second delete error = %v, want ErrRewardRuleNotFound", err)
+	}
+}
+
+func TestRewardService_RegisterOrder(t *testing.T) {
+	ctx := context.Background()
+	svc := NewRewardService(repository.NewMemoryRewardRepository(repository.NewMemoryStore()))
+	if _, err := svc.CreateRule(ctx, "Bork", 10, models.RewardTypePercent); err != nil {
+		t.Fatal(err)
+	}
+
+	goods := []models.OrderGood{{Description: "Чайник Bork", Price: 7000}}
+
+	if _, err := svc.RegisterOrder(ctx, "12345678900", goods); !errors.Is(err, ErrInvalidOrderNumber) {
+		t.Errorf("invalid number error = %v", err)
+	}
+	for _, bad := range [][]models.OrderGood{nil, {{Description: "", Price: 1}}, {{Description: "Bork", Price: -1}}} {
+		if _, err := svc.RegisterOrder(ctx, "12345678903", bad); !errors.Is(err, ErrInvalidOrderGoods) {
+			t.Errorf("RegisterOrder(%+v) error = %v, want ErrInvalidOrderGoods", bad, err)
+		}
+	}
+
+	result, err := svc.GetAccrual(ctx, "12345678903")
+	if err != nil || result != nil {
+		t.Fatalf("GetAccrual before registration = %+v, %v", result, err)
+	}
+
+	order, err := svc.RegisterOrder(ctx, "12345678903", goods)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Accrual != 700 {
+		t.Errorf("Accrual = %v, want 700", order.Accrual)
+	}
+	if _, err := svc.RegisterOrder(ctx, "12345678903", goods); !errors.Is(err, ErrOrderAlreadyRegistered) {
+		t.Errorf("duplicate error = %v, want ErrOrderAlreadyRegistered", err)
+	}
+
+	result, err = svc.GetAccrual(ctx, "12345678903")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result == nil || result.Status != models.AccrualStatusProcessed || result.Accrual != 700 {
+		t.Errorf("GetAccrual = %+v", result)
+	}
+}
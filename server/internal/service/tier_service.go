@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+)
+
+// TierService — уровни лояльности. Уровень определяется по сумме начислений
+// за всё время или по сумме списаний за последние 12 месяцев и задаёт
+// множитель для будущих начислений.
+type TierService interface {
+	// GetStatus возвращает сохранённый уровень пользователя — тот же, по
+	// которому выбираются множитель и кампании, — и текущую сумму накоплений.
+	GetStatus(ctx context.Context, userID int) (*models.TierStatus, error)
+	// Recalculate рассчитывает уровень и сохраняет его у пользователя.
+	// Второй результат — изменился ли сохранённый уровень.
+	Recalculate(ctx context.Context, userID int) (*models.TierStatus, bool, error)
+}
+
+type tierService struct {
+	cfg            models.TiersConfig
+	userRepo       repository.UserRepository
+	orderRepo      repository.OrderRepository
+	withdrawalRepo repository.WithdrawalRepository
+	now            func() time.Time
+}
+
+// NewTierService создаёт сервис уровней. cfg.Levels должны пройти проверку
+// конфигурации: непусты, упорядочены по порогу, первый порог — 0.
+func NewTierService(
+	cfg models.TiersConfig,
+	userRepo repository.UserRepository,
+	orderRepo repository.OrderRepository,
+	withdrawalRepo repository.WithdrawalRepository,
+) TierService {
+	return &tierService{
+		cfg:            cfg,
+		userRepo:       userRepo,
+		orderRepo:      orderRepo,
+		withdrawalRepo: withdrawalRepo,
+		now:            time.Now,
+	}
+}
+
+func (s *tierService) GetStatus(ctx context.Context, userID int) (*models.TierStatus, error) {
+	amount, err := s.amount(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	// Уровень, которого нет в настройках, заменяется рассчитанным до
+	// следующего пересчёта.
+	if user != nil {
+		for i, level := range s.cfg.Levels {
+			if level.Name == user.Tier {
+				return s.levelStatus(i, amount), nil
+			}
+		}
+	}
+	return s.status(amount), nil
+}
+
+func (s *tierService) Recalculate(ctx context.Context, userID int) (*models.TierStatus, bool, error) {
+	amount, err := s.amount(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	status := s.status(amount)
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || user.Tier == status.Name {
+		return status, false, nil
+	}
+
+	if err := s.userRepo.SetTier(ctx, userID, status.Name); err != nil {
+		return nil, false, fmt.Errorf("failed to save tier: %w", err)
+	}
+	return status, true, nil
+}
+
+// amount возвращает сумму, по которой определяется уровень.
+func (s *tierService) amount(ctx context.Context, userID int) (float64, error) {
+	switch s.cfg.Basis {
+	case models.TierBasisRollingSpend:
+		_, sum, err := s.withdrawalRepo.GetTotals(ctx, userID, s.now().AddDate(-1, 0, 0))
+		if err != nil {
+			return 0, fmt.Errorf("failed to get withdrawal totals: %w", err)
+		}
+		return math.Round(sum*100) / 100, nil
+	default:
+		sum, err := s.orderRepo.GetAccrualTotal(ctx, userID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get accrual total: %w", err)
+		}
+		return math.Round(sum*100) / 100, nil
+	}
+}
+
+// status выбирает старший уровень, порог которого не превышает amount.
+func (s *tierService) status(amount float64) *models.TierStatus {
+	levels := s.cfg.Levels
+	i := 0
+	for i+1 < len(levels) && levels[i+1].Threshold <= amount {
+		i++
+	}
+	return s.levelStatus(i, amount)
+}
+
+// levelStatus описывает уровень с индексом i при накоплениях amount.
+func (s *tierService) levelStatus(i int, amount float64) *models.TierStatus {
+	levels := s.cfg.Levels
+	status := &models.TierStatus{
+		Name:       levels[i].Name,
+		Multiplier: levels[i].Multiplier,
+		Amount:     amount,
+	}
+	if i+1 < len(levels) {
+		status.Next = &models.TierNext{Name: levels[i+1].Name, Threshold: levels[i+1].Threshold}
+	}
+	return status
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+)
+
+var testTiers = []models.TierLevel{
+	{Name: "bronze", Threshold: 0, Multiplier: 1},
+	{Name: "silver", Threshold: 100, Multiplier: 1.1},
+	{Name: "gold", Threshold: 500, Multiplier: 1.5},
+}
+
+func newTestTierService(t *testing.T, basis string) (*tierService, *repository.MemoryStore, *models.User) {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	users := repository.NewMemoryUserRepository(store)
+	user, err := users.Create(context.Background(), &models.User{Login: "alice", PasswordHash: "hash", ReferralCode: "ALICE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewTierService(
+		models.TiersConfig{Basis: basis, Levels: testTiers},
+		users,
+		repository.NewMemoryOrderRepository(store),
+		repository.NewMemoryWithdrawalRepository(store),
+	).(*tierService)
+	return svc, store, user
+}
+
+func TestTierService_LifetimeAccrual(t *testing.T) {
+	ctx := context.Background()
+	svc, store, user := newTestTierService(t, models.TierBasisLifetimeAccrual)
+	orders := repository.NewMemoryOrderRepository(store)
+
+	status, changed, err := svc.Recalculate(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || status.Name != "bronze" || status.Multiplier != 1 || status.Next == nil || status.Next.Name != "silver" {
+		t.Errorf("initial status = %+v, changed %v", status, changed)
+	}
+
+	for _, o := range []struct {
+		number, status string
+		accrual        float64
+	}{
+		{"12345678903", models.OrderStatusProcessed, 80},
+		{"2377225624", models.OrderStatusProcessed, 40.5},
+		{"79927398713", models.OrderStatusInvalid, 0},
+	} {
+		order, _, err := orders.CreateOrGet(ctx, o.number, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := orders.UpdateStatusByID(ctx, order.ID, o.status, o.accrual); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status, changed, err = svc.Recalculate(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || status.Name != "silver" || status.Amount != 120.5 || status.Next.Name != "gold" || status.Next.Threshold != 500 {
+		t.Errorf("status = %+v, changed %v; want silver at 120.5", status, changed)
+	}
+	if _, changed, _ := svc.Recalculate(ctx, user.ID); changed {
+		t.Error("unchanged tier reported as changed")
+	}
+
+	saved, err := repository.NewMemoryUserRepository(store).GetByID(ctx, user.ID)
+	if err != nil || saved.Tier != "silver" {
+		t.Errorf("saved user = %+v, %v", saved, err)
+	}
+}
+
+func TestTierService_RollingSpend(t *testing.T) {
+	ctx := context.Background()
+	svc, store, user := newTestTierService(t, models.TierBasisRollingSpend)
+	withdrawals := repository.NewMemoryWithdrawalRepository(store)
+
+	if _, err := withdrawals.Create(ctx, user.ID, "2377225624", 600); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := svc.GetStatus(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Name != "gold" || status.Multiplier != 1.5 || status.Next != nil {
+		t.Errorf("status = %+v, want gold without next tier", status)
+	}
+
+	// Через 13 месяцев списание выпадает из окна.
+	svc.now = func() time.Time { return time.Now().AddDate(1, 1, 0) }
+	status, err = svc.GetStatus(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Name != "bronze" || status.Amount != 0 {
+		t.Errorf("status after a year = %+v, want bronze", status)
+	}
+}
+
+// TestTierService_GetStatusReportsStoredTier проверяет, что в статусе
+// выводится сохранённый уровень, по которому считаются начисления, пока он
+// не пересчитан.
+func TestTierService_GetStatusReportsStoredTier(t *testing.T) {
+	ctx := context.Background()
+	svc, store, user := newTestTierService(t, models.TierBasisLifetimeAccrual)
+	orders := repository.NewMemoryOrderRepository(store)
+
+	if _, _, err := svc.Recalculate(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	order, _, err := orders.CreateOrGet(ctx, "12345678903", user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := orders.UpdateStatusByID(ctx, order.ID, models.OrderStatusProcessed, 120.5); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := svc.GetStatus(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Name != "bronze" || status.Multiplier != 1 || status.Amount != 120.5 || status.Next == nil || status.Next.Name != "silver" {
+		t.Errorf("status before recalculation = %+v, want stored bronze at 120.5", status)
+	}
+
+	if _, _, err := svc.Recalculate(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := svc.GetStatus(ctx, user.ID); status.Name != "silver" || status.Multiplier != 1.1 {
+		t.Errorf("status after recalculation = %+v, want silver", status)
+	}
+}
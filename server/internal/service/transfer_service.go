@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+)
+
+var (
+	ErrInvalidTransferSum = errors.New("invalid transfer sum")
+	ErrUnknownRecipient   = errors.New("unknown transfer recipient")
+	ErrSelfTransfer       = errors.New("cannot transfer points to yourself")
+
+	// ErrTransferLimitExceeded — перевод превысил бы суточный лимит. Лимиты
+	// проверяются в транзакции перевода, поэтому ошибка общая с репозиторием.
+	ErrTransferLimitExceeded = repository.ErrTransferLimitExceeded
+)
+
+// TransferService — переводы баллов между пользователями.
+type TransferService interface {
+	// Transfer переводит req.Sum баллов пользователю с логином req.To.
+	Transfer(ctx context.Context, userID int, req *models.TransferRequest) (*models.Transfer, error)
+	// GetTransfers возвращает входящие и исходящие переводы пользователя.
+	GetTransfers(ctx context.Context, userID int) ([]*models.Transfer, error)
+	// SetLimits заменяет лимиты для следующих переводов.
+	SetLimits(cfg models.TransfersConfig)
+}
+
+type transferService struct {
+	mu           sync.RWMutex
+	cfg          models.TransfersConfig
+	transferRepo repository.TransferRepository
+	userRepo     repository.UserRepository
+}
+
+// NewTransferService создаёт сервис переводов с суточными лимитами cfg.
+func NewTransferService(
+	cfg models.TransfersConfig,
+	transferRepo repository.TransferRepository,
+	userRepo repository.UserRepository,
+) TransferService {
+	return &transferService{
+		cfg:          cfg,
+		transferRepo: transferRepo,
+		userRepo:     userRepo,
+	}
+}
+
+func (s *transferService) Transfer(ctx context.Context, userID int, req *models.TransferRequest) (*models.Transfer, error) {
+	if req.Sum <= 0 {
+		return nil, ErrInvalidTransferSum
+	}
+
+	sender, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if sender == nil {
+		return nil, fmt.Errorf("user %d not found", userID)
+	}
+	recipient, err := s.userRepo.GetByLogin(ctx, req.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recipient: %w", err)
+	}
+	if recipient == nil {
+		return nil, ErrUnknownRecipient
+	}
+	if recipient.ID == userID {
+		return nil, ErrSelfTransfer
+	}
+
+	transfer, err := s.transferRepo.Transfer(ctx, userID, recipient.ID, req.Sum, s.limits())
+	if errors.Is(err, repository.ErrInsufficientFunds) {
+		return nil, ErrInsufficientFunds
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to transfer points: %w", err)
+	}
+	transfer.From = sender.Login
+	transfer.To = recipient.Login
+
+	return transfer, nil
+}
+
+func (s *transferService) SetLimits(cfg models.TransfersConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+}
+
+func (s *transferService) limits() models.TransfersConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+func (s *transferService) GetTransfers(ctx context.Context, userID int) ([]*models.Transfer, error) {
+	transfers, err := s.transferRepo.GetTransfers(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfers: %w", err)
+	}
+
+	return transfers, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+)
+
+func TestTransferService_Transfer(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	users := repository.NewMemoryUserRepository(store)
+	balances := repository.NewMemoryBalanceRepository(store)
+	auth := NewAuthService(users, models.TiersConfig{})
+	svc := NewTransferService(
+		models.TransfersConfig{MaxDailySum: 100},
+		repository.NewMemoryTransferRepository(store),
+		users,
+	)
+
+	alice, err := auth.Register(ctx, &models.UserCredentials{Login: "alice", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := auth.Register(ctx, &models.UserCredentials{Login: "bob", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := balances.AddAccrual(ctx, alice.ID, 200, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		userID  int
+		req     models.TransferRequest
+		wantErr error
+	}{
+		{name: "zero sum", userID: alice.ID, req: models.TransferRequest{To: "bob"}, wantErr: ErrInvalidTransferSum},
+		{name: "unknown recipient", userID: alice.ID, req: models.TransferRequest{To: "carol", Sum: 10}, wantErr: ErrUnknownRecipient},
+		{name: "self", userID: alice.ID, req: models.TransferRequest{To: "alice", Sum: 10}, wantErr: ErrSelfTransfer},
+		{name: "insufficient funds", userID: bob.ID, req: models.TransferRequest{To: "alice", Sum: 10}, wantErr: ErrInsufficientFunds},
+		{name: "ok", userID: alice.ID, req: models.TransferRequest{To: "bob", Sum: 60}},
+		{name: "daily limit", userID: alice.ID, req: models.TransferRequest{To: "bob", Sum: 41}, wantErr: ErrTransferLimitExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transfer, err := svc.Transfer(ctx, tt.userID, &tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (transfer.From != "alice" || transfer.To != "bob" || transfer.Sum != 60) {
+				t.Errorf("transfer = %+v", transfer)
+			}
+		})
+	}
+
+	history, err := svc.GetTransfers(ctx, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Direction != models.TransferIn || history[0].From != "alice" {
+		t.Errorf("bob history = %+v", history)
+	}
+	if balance, _ := balances.GetByUserID(ctx, bob.ID); balance.Current != 60 {
+		t.Errorf("bob balance = %+v, want 60", balance)
+	}
+
+	// Новые лимиты действуют со следующего перевода.
+	svc.SetLimits(models.TransfersConfig{MaxDailySum: 200})
+	if _, err := svc.Transfer(ctx, alice.ID, &models.TransferRequest{To: "bob", Sum: 41}); err != nil {
+		t.Errorf("transfer after raising the limit: %v", err)
+	}
+}
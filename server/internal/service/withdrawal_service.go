@@ -4,30 +4,53 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/RoGogDBD/loyalty_service/server/internal/models"
 	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
 )
 
-var ErrInvalidWithdrawalOrder = errors.New("invalid withdrawal order number")
+var (
+	ErrInvalidWithdrawalOrder = errors.New("invalid withdrawal order number")
+	ErrInvalidWithdrawalSum   = errors.New("invalid withdrawal sum")
+
+	// Ограничения списаний проверяются в транзакции списания, поэтому их
+	// ошибки общие с репозиторием.
+	ErrWithdrawalSumLimit     = repository.ErrWithdrawalSumLimit
+	ErrWithdrawalDailyLimit   = repository.ErrWithdrawalDailyLimit
+	ErrWithdrawalMinBalance   = repository.ErrWithdrawalMinBalance
+	ErrWithdrawalRateExceeded = repository.ErrWithdrawalRateExceeded
+
+	// ErrTierNotRecalculated возвращается Withdraw вместе с проведённым
+	// списанием, если после него не удалось пересчитать уровень.
+	ErrTierNotRecalculated = errors.New("loyalty tier was not recalculated after withdrawal")
+)
 
 type WithdrawalService interface {
 	Withdraw(ctx context.Context, userID int, req *models.WithdrawalRequest) (*models.Withdrawal, error)
 	GetWithdrawals(ctx context.Context, userID int) ([]*models.Withdrawal, error)
+	// SetLimits заменяет ограничения для следующих списаний.
+	SetLimits(cfg models.WithdrawalsConfig)
 }
 
 type withdrawalService struct {
+	mu             sync.RWMutex
+	cfg            models.WithdrawalsConfig
 	withdrawalRepo repository.WithdrawalRepository
-	balanceRepo    repository.BalanceRepository
+	tierService    TierService
+	now            func() time.Time
 }
 
-func NewWithdrawalService(
-	withdrawalRepo repository.WithdrawalRepository,
-	balanceRepo repository.BalanceRepository,
-) WithdrawalService {
+// NewWithdrawalService создаёт сервис списаний с ограничениями cfg. Если
+// задан tierService, после каждого списания пересчитывается уровень
+// пользователя.
+func NewWithdrawalService(cfg models.WithdrawalsConfig, withdrawalRepo repository.WithdrawalRepository, tierService TierService) WithdrawalService {
 	return &withdrawalService{
+		cfg:            cfg,
 		withdrawalRepo: withdrawalRepo,
-		balanceRepo:    balanceRepo,
+		tierService:    tierService,
+		now:            time.Now,
 	}
 }
 
@@ -37,24 +60,42 @@ func (s *withdrawalService) Withdraw(ctx context.Context, userID int, req *model
 	}
 
 	if req.Sum <= 0 {
-		return nil, fmt.Errorf("invalid withdrawal sum")
+		return nil, ErrInvalidWithdrawalSum
 	}
 
-	if err := s.balanceRepo.Withdraw(ctx, userID, req.Sum); err != nil {
-		if err.Error() == "insufficient funds" {
-			return nil, ErrInsufficientFunds
-		}
-		return nil, fmt.Errorf("failed to withdraw from balance: %w", err)
+	// Ограничения проверяются в той же транзакции, что и списание, иначе
+	// параллельные списания могли бы их обойти.
+	withdrawal, err := s.withdrawalRepo.Withdraw(ctx, userID, req.OrderNumber, req.Sum, s.limits(), s.now())
+	if errors.Is(err, repository.ErrInsufficientFunds) {
+		return nil, ErrInsufficientFunds
 	}
-
-	withdrawal, err := s.withdrawalRepo.Create(ctx, userID, req.OrderNumber, req.Sum)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create withdrawal: %w", err)
+		return nil, fmt.Errorf("failed to withdraw: %w", err)
+	}
+
+	// Списание уже проведено, поэтому при ошибке пересчёта оно всё равно
+	// возвращается: уровень пересчитается при следующем начислении.
+	if s.tierService != nil {
+		if _, _, err := s.tierService.Recalculate(ctx, userID); err != nil {
+			return withdrawal, fmt.Errorf("%w: %w", ErrTierNotRecalculated, err)
+		}
 	}
 
 	return withdrawal, nil
 }
 
+func (s *withdrawalService) SetLimits(cfg models.WithdrawalsConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+}
+
+func (s *withdrawalService) limits() models.WithdrawalsConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
 func (s *withdrawalService) GetWithdrawals(ctx context.Context, userID int) ([]*models.Withdrawal, error) {
 	withdrawals, err := s.withdrawalRepo.GetByUserID(ctx, userID)
 	if err != nil {
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/repository"
+)
+
+func TestWithdrawalService_Withdraw(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           models.WithdrawalRequest
+		wantErr       error
+		wantCurrent   float64
+		wantWithdrawn float64
+	}{
+		{name: "ok", req: models.WithdrawalRequest{OrderNumber: "2377225624", Sum: 60}, wantCurrent: 40, wantWithdrawn: 60},
+		{name: "whole balance", req: models.WithdrawalRequest{OrderNumber: "2377225624", Sum: 100}, wantCurrent: 0, wantWithdrawn: 100},
+		{name: "insufficient funds", req: models.WithdrawalRequest{OrderNumber: "2377225624", Sum: 100.01}, wantErr: ErrInsufficientFunds, wantCurrent: 100},
+		{name: "invalid order", req: models.WithdrawalRequest{OrderNumber: "2377225625", Sum: 10}, wantErr: ErrInvalidWithdrawalOrder, wantCurrent: 100},
+		{name: "zero sum", req: models.WithdrawalRequest{OrderNumber: "2377225624", Sum: 0}, wantErr: ErrInvalidWithdrawalSum, wantCurrent: 100},
+		{name: "negative sum", req: models.WithdrawalRequest{OrderNumber: "2377225624", Sum: -5}, wantErr: ErrInvalidWithdrawalSum, wantCurrent: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := repository.NewMemoryStore()
+			balances := repository.NewMemoryBalanceRepository(store)
+			withdrawals := repository.NewMemoryWithdrawalRepository(store)
+			if err := balances.AddAccrual(ctx, 1, 100, nil); err != nil {
+				t.Fatal(err)
+			}
+			svc := NewWithdrawalService(models.WithdrawalsConfig{}, withdrawals, nil)
+
+			w, err := svc.Withdraw(ctx, 1, &tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			history, err := svc.GetWithdrawals(ctx, 1)
+			if err != nil {
+				t.Fatalf("GetWithdrawals: %v", err)
+			}
+			if tt.wantErr == nil {
+				if w.OrderNumber != tt.req.OrderNumber || w.Sum != tt.req.Sum {
+					t.Errorf("withdrawal = %+v", w)
+				}
+				if len(history) != 1 {
+					t.Errorf("history length = %d, want 1", len(history))
+				}
+			} else if len(history) != 0 {
+				t.Errorf("history length = %d, want 0", len(history))
+			}
+
+			balance, _ := balances.GetByUserID(ctx, 1)
+			if balance.Current != tt.wantCurrent || balance.Withdrawn != tt.wantWithdrawn {
+				t.Errorf("balance = %+v, want current %v withdrawn %v", balance, tt.wantCurrent, tt.wantWithdrawn)
+			}
+		})
+	}
+}
+
+func TestWithdrawalService_Limits(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     models.WithdrawalsConfig
+		history []float64
+		later   time.Duration
+		sum     float64
+		wantErr error
+	}{
+		{name: "max sum", cfg: models.WithdrawalsConfig{MaxSum: 50}, sum: 50.01, wantErr: ErrWithdrawalSumLimit},
+		{name: "max sum reached exactly", cfg: models.WithdrawalsConfig{MaxSum: 50}, sum: 50},
+		{name: "daily sum", cfg: models.WithdrawalsConfig{MaxDailySum: 100}, history: []float64{60.1}, sum: 39.91, wantErr: ErrWithdrawalDailyLimit},
+		{name: "daily sum reached exactly", cfg: models.WithdrawalsConfig{MaxDailySum: 100}, history: []float64{60.1}, sum: 39.9},
+		{name: "daily sum after a day", cfg: models.WithdrawalsConfig{MaxDailySum: 100}, history: []float64{60}, later: 25 * time.Hour, sum: 100},
+		{name: "min balance", cfg: models.WithdrawalsConfig{MinBalance: 30}, sum: 170.01, wantErr: ErrWithdrawalMinBalance},
+		{name: "min balance kept", cfg: models.WithdrawalsConfig{MinBalance: 30}, sum: 170},
+		{name: "min balance with insufficient funds", cfg: models.WithdrawalsConfig{MinBalance: 30}, sum: 250, wantErr: ErrInsufficientFunds},
+		{name: "per hour", cfg: models.WithdrawalsConfig{MaxPerHour: 2}, history: []float64{1, 1}, sum: 1, wantErr: ErrWithdrawalRateExceeded},
+		{name: "per hour after an hour", cfg: models.WithdrawalsConfig{MaxPerHour: 2}, history: []float64{1, 1}, later: 61 * time.Minute, sum: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := repository.NewMemoryStore()
+			balances := repository.NewMemoryBalanceRepository(store)
+			withdrawals := repository.NewMemoryWithdrawalRepository(store)
+			if err := balances.AddAccrual(ctx, 1, 200, nil); err != nil {
+				t.Fatal(err)
+			}
+			svc := NewWithdrawalService(tt.cfg, withdrawals, nil).(*withdrawalService)
+			for _, sum := range tt.history {
+				if _, err := svc.Withdraw(ctx, 1, &models.WithdrawalRequest{OrderNumber: "2377225624", Sum: sum}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			svc.now = func() time.Time { return time.Now().Add(tt.later) }
+
+			_, err := svc.Withdraw(ctx, 1, &models.WithdrawalRequest{OrderNumber: "2377225624", Sum: tt.sum})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithdrawalService_SetLimits(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	if err := repository.NewMemoryBalanceRepository(store).AddAccrual(ctx, 1, 200, nil); err != nil {
+		t.Fatal(err)
+	}
+	svc := NewWithdrawalService(models.WithdrawalsConfig{MaxSum: 50}, repository.NewMemoryWithdrawalRepository(store), nil)
+
+	req := &models.WithdrawalRequest{OrderNumber: "2377225624", Sum: 60}
+	if _, err := svc.Withdraw(ctx, 1, req); !errors.Is(err, ErrWithdrawalSumLimit) {
+		t.Fatalf("error = %v, want ErrWithdrawalSumLimit", err)
+	}
+
+	// Новые ограничения действуют со следующего списания.
+	svc.SetLimits(models.WithdrawalsConfig{MaxSum: 100})
+	if _, err := svc.Withdraw(ctx, 1, req); err != nil {
+		t.Errorf("withdraw after raising the limit: %v", err)
+	}
+}
+
+// failingTierService не может пересчитать уровень.
+type failingTierService struct {
+	TierService
+}
+
+func (failingTierService) Recalculate(context.Context, int) (*models.TierStatus, bool, error) {
+	return nil, false, errors.New("db down")
+}
+
+func TestWithdrawalService_RecalculatesTier(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	users := repository.NewMemoryUserRepository(store)
+	user, err := users.Create(ctx, &models.User{Login: "alice", PasswordHash: "hash", ReferralCode: "ALICE", Tier: "bronze"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.NewMemoryBalanceRepository(store).AddAccrual(ctx, user.ID, 1000, nil); err != nil {
+		t.Fatal(err)
+	}
+	withdrawals := repository.NewMemoryWithdrawalRepository(store)
+	tiers := NewTierService(
+		models.TiersConfig{Basis: models.TierBasisRollingSpend, Levels: testTiers},
+		users,
+		repository.NewMemoryOrderRepository(store),
+		withdrawals,
+	)
+	svc := NewWithdrawalService(models.WithdrawalsConfig{}, withdrawals, tiers)
+
+	if _, err := svc.Withdraw(ctx, user.ID, &models.WithdrawalRequest{OrderNumber: "2377225624", Sum: 600}); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := users.GetByID(ctx, user.ID); saved.Tier != "gold" {
+		t.Errorf("tier after withdrawal = %q, want gold", saved.Tier)
+	}
+
+	// Ошибка пересчёта не отменяет проведённое списание.
+	svc = NewWithdrawalService(models.WithdrawalsConfig{}, withdrawals, failingTierService{})
+	w, err := svc.Withdraw(ctx, user.ID, &models.WithdrawalRequest{OrderNumber: "2377225624", Sum: 100})
+	if !errors.Is(err, ErrTierNotRecalculated) || w == nil || w.Sum != 100 {
+		t.Errorf("Withdraw = %+v, %v; want withdrawal with ErrTierNotRecalculated", w, err)
+	}
+}
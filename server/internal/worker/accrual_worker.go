@@ -2,11 +2,11 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"net/http"
-	"strconv"
+	"math"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/RoGogDBD/loyalty_service/server/internal/models"
@@ -15,31 +15,79 @@ import (
 )
 
 type AccrualWorker struct {
-	accrualURL     string
-	orderService   service.OrderService
-	balanceService service.BalanceService
-	logger         *logrus.Logger
-	client         *http.Client
+	mu              sync.RWMutex
+	cfg             models.AccrualConfig
+	client          AccrualClient
+	inFlight        atomic.Pointer[models.Order]
+	orderService    service.OrderService
+	balanceService  service.BalanceService
+	tierService     service.TierService
+	campaignService service.CampaignService
+	referralService service.ReferralService
+	logger          *logrus.Logger
+
+	// cursor — ID последнего заказа предыдущей пачки. Следующий проход
+	// продолжает очередь после него, поэтому заказы, которые система
+	// начислений долго не рассчитывает, не занимают каждую пачку.
+	cursor int
 }
 
+// NewAccrualWorker создаёт воркер, опрашивающий client. Без клиента воркер
+// только применяет присланные уведомления через ApplyAccrual. Если задан
+// tierService, уровень пользователя пересчитывается до и после каждого
+// зачисления, а начисление умножается на множитель уровня. Если задан
+// campaignService, за обработанные заказы начисляются бонусы кампаний, если
+// referralService — вознаграждения реферальной программы.
 func NewAccrualWorker(
-	accrualURL string,
+	cfg models.AccrualConfig,
+	client AccrualClient,
 	orderService service.OrderService,
 	balanceService service.BalanceService,
+	tierService service.TierService,
+	campaignService service.CampaignService,
+	referralService service.ReferralService,
 	logger *logrus.Logger,
 ) *AccrualWorker {
 	return &AccrualWorker{
-		accrualURL:     accrualURL,
-		orderService:   orderService,
-		balanceService: balanceService,
-		logger:         logger,
-		client:         &http.Client{Timeout: 10 * time.Second},
+		cfg:             cfg,
+		client:          client,
+		orderService:    orderService,
+		balanceService:  balanceService,
+		tierService:     tierService,
+		campaignService: campaignService,
+		referralService: referralService,
+		logger:          logger,
 	}
 }
 
+// SetConfig применяет новые настройки опроса, начиная со следующего
+// прохода. Системы начислений, к которым обращается воркер, не меняются.
+func (w *AccrualWorker) SetConfig(cfg models.AccrualConfig) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.cfg = cfg
+}
+
+func (w *AccrualWorker) config() models.AccrualConfig {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.cfg
+}
+
+// InFlight возвращает заказ, который воркер обрабатывает в данный момент.
+func (w *AccrualWorker) InFlight() (*models.Order, bool) {
+	order := w.inFlight.Load()
+	return order, order != nil
+}
+
+// Start опрашивает систему начислений до отмены ctx. Начатый заказ
+// обрабатывается до конца и после отмены, поэтому, чтобы не закрыть базу
+// под воркером, дождитесь возврата из Start.
 func (w *AccrualWorker) Start(ctx context.Context) {
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
+	cfg := w.config()
+	interval := basePollInterval(cfg)
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
 
 	w.logger.Info("Accrual worker started")
 
@@ -48,105 +96,285 @@ func (w *AccrualWorker) Start(ctx context.Context) {
 		case <-ctx.Done():
 			w.logger.Info("Accrual worker stopped")
 			return
-		case <-ticker.C:
-			if err := w.processOrders(ctx); err != nil {
+		case <-timer.C:
+			fetched, err := w.processOrders(ctx)
+			if err != nil {
 				w.logger.WithError(err).Error("Failed to process orders")
+			} else if next := w.nextInterval(interval, fetched); next != interval {
+				w.logger.WithFields(logrus.Fields{
+					"interval": next,
+					"fetched":  fetched,
+				}).Debug("Accrual poll interval changed")
+				interval = next
 			}
+			timer.Reset(interval)
 		}
 	}
 }
 
-func (w *AccrualWorker) processOrders(ctx context.Context) error {
-	orders, err := w.orderService.GetPendingOrders(ctx)
+// basePollInterval — интервал опроса без подстройки. Когда включены
+// уведомления, опрос только подстраховывает их и идёт редко.
+func basePollInterval(cfg models.AccrualConfig) time.Duration {
+	if cfg.WebhookSecret != "" {
+		return cfg.FallbackPollInterval
+	}
+	return cfg.PollInterval
+}
+
+// nextInterval подбирает паузу до следующего прохода. В динамическом режиме
+// полная пачка означает, что очередь больше пачки, и интервал сокращается
+// вдвое; пустая очередь удваивает интервал; иначе используется базовый.
+// При включённых уведомлениях интервал не подстраивается.
+func (w *AccrualWorker) nextInterval(current time.Duration, fetched int) time.Duration {
+	cfg := w.config()
+	if !cfg.DynamicInterval || cfg.WebhookSecret != "" {
+		return basePollInterval(cfg)
+	}
+
+	switch {
+	case fetched >= cfg.BatchSize:
+		return min(max(current/2, cfg.MinPollInterval), cfg.MaxPollInterval)
+	case fetched == 0:
+		return max(min(current*2, cfg.MaxPollInterval), cfg.MinPollInterval)
+	default:
+		return cfg.PollInterval
+	}
+}
+
+// processOrders обрабатывает одну пачку ожидающих заказов и возвращает её размер.
+// Пачки идут по очереди друг за другом; после неполной пачки очередь снова
+// читается с начала.
+func (w *AccrualWorker) processOrders(ctx context.Context) (int, error) {
+	cfg := w.config()
+	orders, err := w.orderService.GetPendingOrders(ctx, w.cursor, cfg.BatchSize)
+	if err == nil && len(orders) == 0 && w.cursor > 0 {
+		w.cursor = 0
+		orders, err = w.orderService.GetPendingOrders(ctx, w.cursor, cfg.BatchSize)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get pending orders: %w", err)
+		return 0, fmt.Errorf("failed to get pending orders: %w", err)
+	}
+	if cfg.BatchSize > 0 && len(orders) >= cfg.BatchSize {
+		w.cursor = orders[len(orders)-1].ID
+	} else {
+		w.cursor = 0
 	}
 
-	for _, order := range orders {
-		if err := w.processOrder(ctx, order); err != nil {
+	for i, order := range orders {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				w.logger.WithFields(logrus.Fields{
+					"processed": i,
+					"remaining": len(orders) - i,
+				}).Warn("Accrual worker stopped before finishing batch, remaining orders will be polled after restart")
+				return len(orders), nil
+			case <-time.After(cfg.OrderDelay):
+			}
+		}
+
+		w.inFlight.Store(order)
+		err := w.ProcessOrder(ctx, order)
+		w.inFlight.Store(nil)
+		if err != nil {
 			w.logger.WithError(err).WithField("orderNumber", order.Number).Error("Failed to process order")
 		}
-		time.Sleep(100 * time.Millisecond)
 	}
 
-	return nil
+	return len(orders), nil
 }
 
-func (w *AccrualWorker) processOrder(ctx context.Context, order *models.Order) error {
-	url := fmt.Sprintf("%s/api/orders/%s", w.accrualURL, order.Number)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+// ProcessOrder один раз опрашивает систему начислений по заказу, обновляет
+// его статус и зачисляет начисление на баланс. Отмена ctx не прерывает
+// начатый запрос и запись в базу, чтобы статус заказа и баланс не
+// разошлись; она прерывает только ожидание после отказа по лимиту запросов.
+func (w *AccrualWorker) ProcessOrder(ctx context.Context, order *models.Order) error {
+	cfg := w.config()
+	stop := ctx
+	ctx = context.WithoutCancel(ctx)
+
+	reqCtx, cancel := context.WithTimeout(ctx, cfg.RequestTimeout)
+	defer cancel()
+	result, err := w.client.GetAccrual(reqCtx, order.Number)
+
+	var rateErr *RateLimitError
+	switch {
+	case errors.As(err, &rateErr):
+		w.logger.WithField("retryAfter", rateErr.RetryAfter).Warn("Rate limited by accrual system")
+		select {
+		case <-stop.Done():
+		case <-time.After(rateErr.RetryAfter):
+		}
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to get accrual: %w", err)
+	case result == nil:
+		w.logger.WithField("orderNumber", order.Number).Debug("Order not registered in accrual system yet")
+		return nil
 	}
 
-	resp, err := w.client.Do(req)
+	return w.applyResult(ctx, order, result)
+}
+
+// ApplyAccrual применяет результат расчёта, присланный системой начислений,
+// так же, как ответ на опрос. Если заказ не найден, возвращает
+// service.ErrOrderNotFound.
+func (w *AccrualWorker) ApplyAccrual(ctx context.Context, result *models.AccrualResult) error {
+	order, err := w.orderService.GetOrder(ctx, result.Order)
 	if err != nil {
-		return fmt.Errorf("failed to call accrual system: %w", err)
+		return err
 	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Printf("failed to close response body: %v", err)
-		}
-	}()
-
-	switch resp.StatusCode {
-	case http.StatusOK:
-		var accrualResp struct {
-			Order   string  `json:"order"`
-			Status  string  `json:"status"`
-			Accrual float64 `json:"accrual,omitempty"`
+	return w.applyResult(context.WithoutCancel(ctx), order, result)
+}
+
+// applyResult обновляет статус заказа и зачисляет начисление на баланс.
+// Повторный результат для уже обработанного заказа пропускается.
+func (w *AccrualWorker) applyResult(ctx context.Context, order *models.Order, result *models.AccrualResult) error {
+	if order.Status == models.OrderStatusProcessed || order.Status == models.OrderStatusInvalid {
+		w.logger.WithField("orderNumber", order.Number).Debug("Order already finalized, accrual result ignored")
+		return nil
+	}
+
+	var orderStatus string
+	switch result.Status {
+	case models.AccrualStatusRegistered:
+		orderStatus = models.OrderStatusNew
+	case models.AccrualStatusProcessing:
+		orderStatus = models.OrderStatusProcessing
+	case models.AccrualStatusInvalid:
+		orderStatus = models.OrderStatusInvalid
+	case models.AccrualStatusProcessed:
+		orderStatus = models.OrderStatusProcessed
+	default:
+		// Схема допускает только известные статусы заказа, поэтому
+		// неизвестный статус не записывается: заказ остаётся в очереди и
+		// будет опрошен снова.
+		w.logger.WithFields(logrus.Fields{
+			"orderNumber": order.Number,
+			"status":      result.Status,
+		}).Warn("Unknown status from accrual system, order left pending")
+		return nil
+	}
+
+	accrual := result.Accrual
+	if orderStatus == models.OrderStatusProcessed && accrual > 0 && w.tierService != nil {
+		// Множитель применяется до записи в заказ, чтобы начисление в
+		// истории заказов совпадало с зачисленным на баланс. Уровень
+		// сохраняется у пользователя: по нему же кампании проверяют, кому
+		// положен бонус.
+		tier, err := w.recalculateTier(ctx, order.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to get loyalty tier: %w", err)
 		}
-		if err := json.NewDecoder(resp.Body).Decode(&accrualResp); err != nil {
-			return fmt.Errorf("failed to decode response: %w", err)
+		accrual = math.Round(accrual*tier.Multiplier*100) / 100
+	}
+
+	// Бонусы кампаний начисляются до записи результата: заказ остаётся в
+	// очереди, пока они не начислены, а повторный опрос уже начисленные
+	// бонусы не дублирует.
+	if orderStatus == models.OrderStatusProcessed && accrual > 0 {
+		if err := w.applyBonuses(ctx, order, accrual); err != nil {
+			return err
 		}
+	}
 
-		var orderStatus string
-		switch accrualResp.Status {
-		case "REGISTERED":
-			orderStatus = models.OrderStatusNew
-		case "PROCESSING":
-			orderStatus = models.OrderStatusProcessing
-		case "INVALID":
-			orderStatus = models.OrderStatusInvalid
-		case "PROCESSED":
-			orderStatus = models.OrderStatusProcessed
-		default:
-			w.logger.WithField("status", accrualResp.Status).Warn("Unknown status from accrual system")
-			orderStatus = accrualResp.Status
+	if err := w.orderService.UpdateStatus(ctx, order.ID, orderStatus, accrual); err != nil {
+		if errors.Is(err, service.ErrOrderProcessed) {
+			w.logger.WithField("orderNumber", order.Number).Debug("Order already finalized, accrual result ignored")
+			return nil
 		}
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
 
-		if err := w.orderService.UpdateStatus(ctx, order.ID, orderStatus, accrualResp.Accrual); err != nil {
-			return fmt.Errorf("failed to update order status: %w", err)
+	if orderStatus == models.OrderStatusProcessed && accrual > 0 {
+		if err := w.balanceService.AddAccrual(ctx, order.UserID, accrual); err != nil {
+			return fmt.Errorf("failed to add accrual: %w", err)
 		}
+		w.logger.WithFields(logrus.Fields{
+			"orderNumber": order.Number,
+			"accrual":     accrual,
+			"userID":      order.UserID,
+		}).Info("Accrual added")
+	}
 
-		if orderStatus == models.OrderStatusProcessed && accrualResp.Accrual > 0 {
-			if err := w.balanceService.AddAccrual(ctx, order.UserID, accrualResp.Accrual); err != nil {
-				return fmt.Errorf("failed to add accrual: %w", err)
+	if orderStatus == models.OrderStatusProcessed {
+		w.rewardReferral(ctx, order)
+		if accrual > 0 && w.tierService != nil {
+			// Ошибка только логируется: начисление уже зачислено, а уровень
+			// пересчитается при следующем.
+			if _, err := w.recalculateTier(ctx, order.UserID); err != nil {
+				w.logger.WithError(err).WithField("userID", order.UserID).Warn("Failed to recalculate loyalty tier")
 			}
-			w.logger.WithFields(logrus.Fields{
-				"orderNumber": order.Number,
-				"accrual":     accrualResp.Accrual,
-				"userID":      order.UserID,
-			}).Info("Accrual added")
 		}
+	}
 
-		return nil
+	return nil
+}
 
-	case http.StatusNoContent:
-		w.logger.WithField("orderNumber", order.Number).Debug("Order not registered in accrual system yet")
+// applyBonuses начисляет бонусы кампаний за обработанный заказ.
+func (w *AccrualWorker) applyBonuses(ctx context.Context, order *models.Order, accrual float64) error {
+	if w.campaignService == nil {
 		return nil
+	}
 
-	case http.StatusTooManyRequests:
-		retryAfter := resp.Header.Get("Retry-After")
-		if retryAfter != "" {
-			if seconds, err := strconv.Atoi(retryAfter); err == nil {
-				w.logger.WithField("retryAfter", seconds).Warn("Rate limited by accrual system")
-				time.Sleep(time.Duration(seconds) * time.Second)
-			}
-		}
-		return nil
+	bonuses, err := w.campaignService.ApplyBonuses(ctx, order, accrual)
+	for _, bonus := range bonuses {
+		w.logger.WithFields(logrus.Fields{
+			"orderNumber": order.Number,
+			"campaign":    bonus.Campaign,
+			"bonus":       bonus.Amount,
+			"userID":      order.UserID,
+		}).Info("Campaign bonus added")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply campaign bonuses: %w", err)
+	}
+	return nil
+}
 
-	default:
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+// rewardReferral начисляет реферальное вознаграждение за первый обработанный
+// заказ приглашённого. Если начислить не удалось, попытка повторится при
+// обработке следующего заказа.
+func (w *AccrualWorker) rewardReferral(ctx context.Context, order *models.Order) {
+	if w.referralService == nil {
+		return
+	}
+
+	reward, err := w.referralService.RewardReferral(ctx, order)
+	if err != nil {
+		w.logger.WithError(err).WithField("orderNumber", order.Number).Error("Failed to reward referral")
+		return
+	}
+	if reward == nil {
+		return
+	}
+
+	entry := w.logger.WithFields(logrus.Fields{
+		"orderNumber": order.Number,
+		"referrerID":  reward.ReferrerID,
+		"refereeID":   reward.RefereeID,
+	})
+	if reward.Limited {
+		entry.Warn("Referral reward skipped, referrer limit reached")
+		return
+	}
+	entry.WithFields(logrus.Fields{
+		"referrerBonus": reward.ReferrerAmount,
+		"refereeBonus":  reward.RefereeAmount,
+	}).Info("Referral reward added")
+}
+
+// recalculateTier пересчитывает и сохраняет уровень пользователя.
+func (w *AccrualWorker) recalculateTier(ctx context.Context, userID int) (*models.TierStatus, error) {
+	tier, changed, err := w.tierService.Recalculate(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		w.logger.WithFields(logrus.Fields{
+			"userID": userID,
+			"tier":   tier.Name,
+		}).Info("Loyalty tier changed")
 	}
+	return tier, nil
 }
@@ -0,0 +1,646 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/accrualmock"
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+	"github.com/sirupsen/logrus"
+)
+
+type statusUpdate struct {
+	orderID int
+	status  string
+	accrual float64
+}
+
+type fakeOrderService struct {
+	service.OrderService
+
+	mu        sync.Mutex
+	pending   []*models.Order
+	afters    []int
+	limits    []int
+	updates   []statusUpdate
+	finalized map[int]bool
+	done      []*models.Order
+}
+
+func (f *fakeOrderService) GetOrder(_ context.Context, number string) (*models.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, o := range slices.Concat(f.pending, f.done) {
+		if o.Number == number {
+			return o, nil
+		}
+	}
+	return nil, service.ErrOrderNotFound
+}
+
+func (f *fakeOrderService) UpdateStatus(_ context.Context, orderID int, status string, accrual float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.finalized[orderID] {
+		return service.ErrOrderProcessed
+	}
+	f.updates = append(f.updates, statusUpdate{orderID, status, accrual})
+
+	// Как и репозиторий, очередь отдаёт только необработанные заказы.
+	if status == models.OrderStatusProcessed || status == models.OrderStatusInvalid {
+		if f.finalized == nil {
+			f.finalized = make(map[int]bool)
+		}
+		f.finalized[orderID] = true
+		pending := f.pending[:0:0]
+		for _, o := range f.pending {
+			if o.ID != orderID {
+				pending = append(pending, o)
+			} else {
+				f.done = append(f.done, o)
+			}
+		}
+		f.pending = pending
+	}
+	return nil
+}
+
+func (f *fakeOrderService) GetPendingOrders(_ context.Context, afterID, limit int) ([]*models.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.afters = append(f.afters, afterID)
+	f.limits = append(f.limits, limit)
+	var pending []*models.Order
+	for _, o := range f.pending {
+		if o.ID > afterID {
+			pending = append(pending, o)
+		}
+	}
+	if limit > 0 && len(pending) > limit {
+		return pending[:limit], nil
+	}
+	return pending, nil
+}
+
+type fakeBalanceService struct {
+	service.BalanceService
+
+	mu       sync.Mutex
+	accruals map[int]float64
+}
+
+func (f *fakeBalanceService) AddAccrual(_ context.Context, userID int, amount float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.accruals == nil {
+		f.accruals = make(map[int]float64)
+	}
+	f.accruals[userID] += amount
+	return nil
+}
+
+// testConfig — настройки воркера с короткими интервалами для тестов.
+func testConfig() models.AccrualConfig {
+	return models.AccrualConfig{
+		PollInterval:    20 * time.Millisecond,
+		RequestTimeout:  time.Second,
+		OrderDelay:      time.Millisecond,
+		BatchSize:       10,
+		MinPollInterval: 5 * time.Millisecond,
+		MaxPollInterval: 80 * time.Millisecond,
+	}
+}
+
+func newTestWorker(t *testing.T, mock *accrualmock.Server) (*AccrualWorker, *fakeOrderService, *fakeBalanceService) {
+	t.Helper()
+
+	srv := httptest.NewServer(mock)
+	t.Cleanup(srv.Close)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	orders := &fakeOrderService{}
+	balances := &fakeBalanceService{}
+	return NewAccrualWorker(testConfig(), NewHTTPAccrualClient(srv.URL), orders, balances, nil, nil, nil, logger), orders, balances
+}
+
+func TestAccrualWorker_ProcessOrder(t *testing.T) {
+	order := &models.Order{ID: 1, Number: "12345678903", UserID: 7, Status: models.OrderStatusNew}
+
+	tests := []struct {
+		name        string
+		step        accrualmock.Step
+		wantErr     bool
+		wantUpdate  *statusUpdate
+		wantAccrual float64
+	}{
+		{
+			name:        "processed with accrual",
+			step:        accrualmock.Processed(500),
+			wantUpdate:  &statusUpdate{1, models.OrderStatusProcessed, 500},
+			wantAccrual: 500,
+		},
+		{
+			name:       "processed without accrual",
+			step:       accrualmock.Step{Status: accrualmock.StatusProcessed},
+			wantUpdate: &statusUpdate{1, models.OrderStatusProcessed, 0},
+		},
+		{
+			name:       "registered",
+			step:       accrualmock.Step{Status: accrualmock.StatusRegistered},
+			wantUpdate: &statusUpdate{1, models.OrderStatusNew, 0},
+		},
+		{
+			name:       "processing",
+			step:       accrualmock.Step{Status: accrualmock.StatusProcessing},
+			wantUpdate: &statusUpdate{1, models.OrderStatusProcessing, 0},
+		},
+		{
+			name:       "invalid",
+			step:       accrualmock.Step{Status: accrualmock.StatusInvalid},
+			wantUpdate: &statusUpdate{1, models.OrderStatusInvalid, 0},
+		},
+		{
+			name: "not registered",
+			step: accrualmock.Step{NoContent: true},
+		},
+		{
+			name: "unknown status",
+			step: accrualmock.Step{Status: "ON_HOLD"},
+		},
+		{
+			name:    "server error",
+			step:    accrualmock.Step{HTTPStatus: http.StatusInternalServerError},
+			wantErr: true,
+		},
+		{
+			name:    "bad gateway",
+			step:    accrualmock.Step{HTTPStatus: http.StatusBadGateway},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := accrualmock.New(accrualmock.Config{})
+			mock.Script(order.Number, tt.step)
+			w, orders, balances := newTestWorker(t, mock)
+
+			err := w.ProcessOrder(context.Background(), order)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("processOrder error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			switch {
+			case tt.wantUpdate == nil && len(orders.updates) != 0:
+				t.Errorf("updates = %+v, want none", orders.updates)
+			case tt.wantUpdate != nil && (len(orders.updates) != 1 || orders.updates[0] != *tt.wantUpdate):
+				t.Errorf("updates = %+v, want [%+v]", orders.updates, *tt.wantUpdate)
+			}
+			if got := balances.accruals[order.UserID]; got != tt.wantAccrual {
+				t.Errorf("accrual = %v, want %v", got, tt.wantAccrual)
+			}
+		})
+	}
+}
+
+func TestAccrualWorker_RateLimited(t *testing.T) {
+	mock := accrualmock.New(accrualmock.Config{
+		RateLimit:  1,
+		RateWindow: time.Hour,
+		RetryAfter: time.Millisecond,
+		Rules:      []accrualmock.RewardRule{{Accrual: 10}},
+	})
+	w, orders, _ := newTestWorker(t, mock)
+
+	order := &models.Order{ID: 1, Number: "12345678903", UserID: 7}
+	if err := w.ProcessOrder(context.Background(), order); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	if err := w.ProcessOrder(context.Background(), order); err != nil {
+		t.Fatalf("rate limited request: %v", err)
+	}
+
+	if len(orders.updates) != 1 {
+		t.Errorf("updates = %+v, want only the first request applied", orders.updates)
+	}
+	if got := mock.Requests(order.Number); got != 1 {
+		t.Errorf("requests served = %d, want 1", got)
+	}
+}
+
+// TestAccrualWorker_Start проверяет, что запущенный воркер сам опрашивает
+// систему начислений и зачисляет баллы за ожидающий заказ.
+func TestAccrualWorker_Start(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for worker ticks")
+	}
+
+	mock := accrualmock.New(accrualmock.Config{})
+	mock.Script("12345678903", accrualmock.Processed(42))
+	w, orders, balances := newTestWorker(t, mock)
+	orders.pending = []*models.Order{{ID: 1, Number: "12345678903", UserID: 7}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Start(ctx)
+		close(done)
+	}()
+
+	deadline := time.After(15 * time.Second)
+	for {
+		balances.mu.Lock()
+		got := balances.accruals[7]
+		balances.mu.Unlock()
+		if got > 0 {
+			if got != 42 {
+				t.Errorf("accrual = %v, want 42", got)
+			}
+			break
+		}
+
+		select {
+		case <-deadline:
+			t.Fatal("accrual was not added in time")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop after cancel")
+	}
+}
+
+func TestAccrualWorker_ProcessOrdersBatch(t *testing.T) {
+	mock := accrualmock.New(accrualmock.Config{Rules: []accrualmock.RewardRule{{Accrual: 1}}})
+	w, orders, _ := newTestWorker(t, mock)
+	w.cfg.BatchSize = 2
+	orders.pending = []*models.Order{
+		{ID: 1, Number: "12345678903", UserID: 7},
+		{ID: 2, Number: "9278923470", UserID: 7},
+		{ID: 3, Number: "346436439", UserID: 7},
+	}
+
+	fetched, err := w.processOrders(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetched != 2 || len(orders.limits) != 1 || orders.limits[0] != 2 {
+		t.Errorf("fetched %d with limits %v, want 2 with limit 2", fetched, orders.limits)
+	}
+	if len(orders.updates) != 2 {
+		t.Errorf("updates = %+v, want 2", orders.updates)
+	}
+}
+
+// TestAccrualWorker_RotatesStuckOrders проверяет, что полная пачка заказов,
+// которые система начислений не рассчитывает, не мешает опросить более
+// новый заказ, а после конца очереди проход начинается сначала.
+func TestAccrualWorker_RotatesStuckOrders(t *testing.T) {
+	var polled []string
+	client := accrualClientFunc(func(_ context.Context, number string) (*models.AccrualResult, error) {
+		polled = append(polled, number)
+		if number != "346436439" {
+			return nil, nil
+		}
+		return &models.AccrualResult{Order: number, Status: models.AccrualStatusProcessed, Accrual: 1}, nil
+	})
+
+	orders := &fakeOrderService{pending: []*models.Order{
+		{ID: 1, Number: "12345678903", UserID: 7},
+		{ID: 2, Number: "9278923470", UserID: 7},
+		{ID: 3, Number: "346436439", UserID: 7},
+	}}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	cfg := testConfig()
+	cfg.BatchSize = 2
+	w := NewAccrualWorker(cfg, client, orders, &fakeBalanceService{}, nil, nil, nil, logger)
+
+	for range 3 {
+		if _, err := w.processOrders(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(orders.updates) != 1 || orders.updates[0].orderID != 3 {
+		t.Errorf("updates = %+v, want the newer order processed", orders.updates)
+	}
+	want := []string{"12345678903", "9278923470", "346436439", "12345678903", "9278923470"}
+	if !slices.Equal(polled, want) {
+		t.Errorf("polled = %v, want %v", polled, want)
+	}
+	if !slices.Equal(orders.afters, []int{0, 2, 0}) {
+		t.Errorf("cursors = %v, want [0 2 0]", orders.afters)
+	}
+}
+
+func TestAccrualWorker_NextInterval(t *testing.T) {
+	cfg := testConfig()
+	cfg.DynamicInterval = true
+
+	tests := []struct {
+		name    string
+		dynamic bool
+		current time.Duration
+		fetched int
+		want    time.Duration
+	}{
+		{"static ignores queue", false, 40 * time.Millisecond, 10, 20 * time.Millisecond},
+		{"full batch halves", true, 20 * time.Millisecond, 10, 10 * time.Millisecond},
+		{"full batch stops at min", true, 8 * time.Millisecond, 10, 5 * time.Millisecond},
+		{"idle doubles", true, 20 * time.Millisecond, 0, 40 * time.Millisecond},
+		{"idle stops at max", true, 60 * time.Millisecond, 0, 80 * time.Millisecond},
+		{"partial batch resets", true, 80 * time.Millisecond, 3, 20 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cfg
+			c.DynamicInterval = tt.dynamic
+			w := NewAccrualWorker(c, nil, nil, nil, nil, nil, nil, logrus.New())
+			if got := w.nextInterval(tt.current, tt.fetched); got != tt.want {
+				t.Errorf("nextInterval(%s, %d) = %s, want %s", tt.current, tt.fetched, got, tt.want)
+			}
+		})
+	}
+}
+
+// accrualClientFunc позволяет задать AccrualClient функцией.
+type accrualClientFunc func(ctx context.Context, number string) (*models.AccrualResult, error)
+
+func (f accrualClientFunc) GetAccrual(ctx context.Context, number string) (*models.AccrualResult, error) {
+	return f(ctx, number)
+}
+
+func TestAccrualWorker_SetConfig(t *testing.T) {
+	var timeouts []time.Duration
+	client := accrualClientFunc(func(ctx context.Context, number string) (*models.AccrualResult, error) {
+		deadline, _ := ctx.Deadline()
+		timeouts = append(timeouts, time.Until(deadline).Round(time.Second))
+		return &models.AccrualResult{Order: number, Status: models.AccrualStatusProcessed, Accrual: 1}, nil
+	})
+
+	orders := &fakeOrderService{pending: []*models.Order{
+		{ID: 1, Number: "12345678903", UserID: 7},
+		{ID: 2, Number: "9278923470", UserID: 7},
+	}}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	w := NewAccrualWorker(testConfig(), client, orders, &fakeBalanceService{}, nil, nil, nil, logger)
+
+	next := testConfig()
+	next.BatchSize = 1
+	next.RequestTimeout = 3 * time.Second
+	w.SetConfig(next)
+
+	fetched, err := w.processOrders(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetched != 1 || len(orders.updates) != 1 {
+		t.Errorf("fetched %d with updates %+v, want one order from the reloaded batch size", fetched, orders.updates)
+	}
+	if len(timeouts) != 1 || timeouts[0] != 3*time.Second {
+		t.Errorf("request timeouts = %v, want [3s]", timeouts)
+	}
+}
+
+// TestAccrualWorker_FinishesInFlightOrder проверяет, что отмена контекста не
+// обрывает начатый заказ, а остаток пачки откладывается.
+func TestAccrualWorker_FinishesInFlightOrder(t *testing.T) {
+	mock := accrualmock.New(accrualmock.Config{})
+	mock.Script("12345678903", accrualmock.Processed(5))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { close(started) })
+		<-release
+		mock.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	orders := &fakeOrderService{pending: []*models.Order{
+		{ID: 1, Number: "12345678903", UserID: 7},
+		{ID: 2, Number: "9278923470", UserID: 7},
+	}}
+	balances := &fakeBalanceService{}
+	w := NewAccrualWorker(testConfig(), NewHTTPAccrualClient(srv.URL), orders, balances, nil, nil, nil, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		if _, err := w.processOrders(ctx); err != nil {
+			t.Error(err)
+		}
+		close(done)
+	}()
+
+	<-started
+	if order, ok := w.InFlight(); !ok || order.ID != 1 {
+		t.Errorf("InFlight() = %v, %v, want order 1", order, ok)
+	}
+	cancel()
+	close(release)
+	<-done
+
+	if _, ok := w.InFlight(); ok {
+		t.Error("order still in flight after processOrders returned")
+	}
+	if len(orders.updates) != 1 || orders.updates[0].orderID != 1 {
+		t.Errorf("updates = %+v, want only order 1", orders.updates)
+	}
+	if balances.accruals[7] != 5 {
+		t.Errorf("accrual = %v, want 5", balances.accruals[7])
+	}
+}
+
+func TestAccrualWorker_RateLimitWaitStopsOnCancel(t *testing.T) {
+	mock := accrualmock.New(accrualmock.Config{
+		RateLimit:  1,
+		RateWindow: time.Hour,
+		RetryAfter: time.Hour,
+	})
+	w, _, _ := newTestWorker(t, mock)
+	order := &models.Order{ID: 1, Number: "12345678903", UserID: 7}
+	if err := w.ProcessOrder(context.Background(), order); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := w.ProcessOrder(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("rate limit wait took %s after cancel", elapsed)
+	}
+}
+
+func TestAccrualWorker_ApplyAccrual(t *testing.T) {
+	w, orders, balances := newTestWorker(t, accrualmock.New(accrualmock.Config{}))
+	orders.pending = []*models.Order{{ID: 1, Number: "12345678903", UserID: 7}}
+	ctx := context.Background()
+
+	result := &models.AccrualResult{Order: "12345678903", Status: models.AccrualStatusProcessed, Accrual: 500}
+	if err := w.ApplyAccrual(ctx, result); err != nil {
+		t.Fatal(err)
+	}
+
+	// Повтор уведомления и опрос после него не должны зачислить повторно.
+	if err := w.ApplyAccrual(ctx, result); err != nil {
+		t.Errorf("repeated notification: %v", err)
+	}
+	if err := w.applyResult(ctx, &models.Order{ID: 1, Number: "12345678903", UserID: 7}, result); err != nil {
+		t.Errorf("late poll result: %v", err)
+	}
+
+	if balances.accruals[7] != 500 {
+		t.Errorf("accrual = %v, want 500 credited once", balances.accruals[7])
+	}
+	if len(orders.updates) != 1 {
+		t.Errorf("updates = %+v, want 1", orders.updates)
+	}
+
+	err := w.ApplyAccrual(ctx, &models.AccrualResult{Order: "9278923470", Status: models.AccrualStatusProcessed})
+	if !errors.Is(err, service.ErrOrderNotFound) {
+		t.Errorf("unknown order: error = %v, want ErrOrderNotFound", err)
+	}
+}
+
+type fakeTierService struct {
+	service.TierService
+
+	multiplier   float64
+	recalculated []int
+}
+
+func (f *fakeTierService) Recalculate(_ context.Context, userID int) (*models.TierStatus, bool, error) {
+	f.recalculated = append(f.recalculated, userID)
+	return &models.TierStatus{Name: "gold", Multiplier: f.multiplier}, true, nil
+}
+
+type fakeCampaignService struct {
+	service.CampaignService
+
+	// applied — начисления, от которых считались бонусы, по номерам заказов.
+	applied map[string]float64
+	// err возвращается вместо начисления бонусов.
+	err error
+}
+
+func (f *fakeCampaignService) ApplyBonuses(_ context.Context, order *models.Order, accrual float64) ([]*models.CampaignBonus, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.applied == nil {
+		f.applied = make(map[string]float64)
+	}
+	f.applied[order.Number] = accrual
+	return []*models.CampaignBonus{{Campaign: "weekend", Amount: accrual}}, nil
+}
+
+type fakeReferralService struct {
+	service.ReferralService
+
+	rewarded []string
+}
+
+func (f *fakeReferralService) RewardReferral(_ context.Context, order *models.Order) (*models.ReferralReward, error) {
+	f.rewarded = append(f.rewarded, order.Number)
+	return &models.ReferralReward{RefereeID: order.UserID, ReferrerID: 1, ReferrerAmount: 100}, nil
+}
+
+func TestAccrualWorker_TierMultiplierAndCampaigns(t *testing.T) {
+	mock := accrualmock.New(accrualmock.Config{})
+	mock.Script("12345678903", accrualmock.Processed(100.01))
+	mock.Script("9278923470", accrualmock.Step{Status: accrualmock.StatusInvalid})
+	w, orders, balances := newTestWorker(t, mock)
+	tiers := &fakeTierService{multiplier: 1.5}
+	campaigns := &fakeCampaignService{}
+	referrals := &fakeReferralService{}
+	w.tierService = tiers
+	w.campaignService = campaigns
+	w.referralService = referrals
+
+	ctx := context.Background()
+	for _, order := range []*models.Order{
+		{ID: 1, Number: "12345678903", UserID: 7},
+		{ID: 2, Number: "9278923470", UserID: 8},
+	} {
+		if err := w.ProcessOrder(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Заказ хранит начисление с учётом множителя, округлённое до копеек.
+	want := []statusUpdate{
+		{1, models.OrderStatusProcessed, 150.02},
+		{2, models.OrderStatusInvalid, 0},
+	}
+	if !slices.Equal(orders.updates, want) {
+		t.Errorf("updates = %+v, want %+v", orders.updates, want)
+	}
+	if balances.accruals[7] != 150.02 {
+		t.Errorf("accrual = %v, want 150.02", balances.accruals[7])
+	}
+	// Уровень пересчитывается перед множителем и после зачисления.
+	if !slices.Equal(tiers.recalculated, []int{7, 7}) {
+		t.Errorf("recalculated = %v, want user 7 twice", tiers.recalculated)
+	}
+	// Бонусы кампаний считаются от начисления с учётом уровня и только для
+	// обработанных заказов.
+	if want := map[string]float64{"12345678903": 150.02}; !maps.Equal(campaigns.applied, want) {
+		t.Errorf("campaign bonuses applied for %+v, want %+v", campaigns.applied, want)
+	}
+	if !slices.Equal(referrals.rewarded, []string{"12345678903"}) {
+		t.Errorf("referral rewards checked for %v, want only the processed order", referrals.rewarded)
+	}
+}
+
+func TestAccrualWorker_CampaignBonusFailure(t *testing.T) {
+	mock := accrualmock.New(accrualmock.Config{})
+	mock.Script("12345678903", accrualmock.Processed(100))
+	w, orders, balances := newTestWorker(t, mock)
+	campaigns := &fakeCampaignService{err: errors.New("db down")}
+	w.campaignService = campaigns
+
+	// Пока бонусы не начислены, заказ не обработан и будет опрошен снова.
+	ctx := context.Background()
+	order := &models.Order{ID: 1, Number: "12345678903", UserID: 7, Status: models.OrderStatusNew}
+	if err := w.ProcessOrder(ctx, order); err == nil {
+		t.Fatal("ProcessOrder succeeded despite campaign bonus failure")
+	}
+	if len(orders.updates) != 0 || balances.accruals[7] != 0 {
+		t.Errorf("updates = %+v, accrual = %v; want order left pending", orders.updates, balances.accruals[7])
+	}
+
+	campaigns.err = nil
+	if err := w.ProcessOrder(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	if want := []statusUpdate{{1, models.OrderStatusProcessed, 100}}; !slices.Equal(orders.updates, want) {
+		t.Errorf("updates = %+v, want %+v", orders.updates, want)
+	}
+	if campaigns.applied["12345678903"] != 100 || balances.accruals[7] != 100 {
+		t.Errorf("bonus accrual = %v, balance accrual = %v; want 100", campaigns.applied["12345678903"], balances.accruals[7])
+	}
+}
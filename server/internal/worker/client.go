@@ -0,0 +1,149 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+// AccrualClient получает результат расчёта начисления по заказу из системы
+// начислений.
+type AccrualClient interface {
+	// GetAccrual возвращает nil без ошибки, если заказ ещё не
+	// зарегистрирован в системе начислений. При превышении лимита запросов
+	// возвращает *RateLimitError.
+	GetAccrual(ctx context.Context, number string) (*models.AccrualResult, error)
+}
+
+// RateLimitError — система начислений просит повторить запрос не раньше,
+// чем через RetryAfter.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited by accrual system, retry after %s", e.RetryAfter)
+}
+
+// ErrNoAccrualProvider возвращается, если номер заказа не подходит ни под один
+// префикс настроенных систем начислений.
+var ErrNoAccrualProvider = errors.New("no accrual provider for order")
+
+// NewAccrualClient собирает клиента по настройкам: адрес Address обслуживает
+// все заказы, Providers — заказы со своими префиксами номера, engine —
+// встроенный расчёт для заказов с префиксом EnginePrefix, если он включён.
+// Если не настроено ни одной системы начислений, возвращает nil.
+func NewAccrualClient(cfg models.AccrualConfig, engine AccrualClient) AccrualClient {
+	var routes []AccrualRoute
+	if cfg.Address != "" {
+		routes = append(routes, AccrualRoute{Client: NewHTTPAccrualClient(cfg.Address)})
+	}
+	for _, p := range cfg.Providers {
+		routes = append(routes, AccrualRoute{Prefix: p.Prefix, Client: NewHTTPAccrualClient(p.Address)})
+	}
+	if cfg.EngineEnabled && engine != nil {
+		routes = append(routes, AccrualRoute{Prefix: cfg.EnginePrefix, Client: engine})
+	}
+
+	switch len(routes) {
+	case 0:
+		return nil
+	case 1:
+		if routes[0].Prefix == "" {
+			return routes[0].Client
+		}
+	}
+	return NewPrefixRouter(routes...)
+}
+
+type httpAccrualClient struct {
+	address string
+	client  *http.Client
+}
+
+// NewHTTPAccrualClient обращается к системе начислений по протоколу
+// GET /api/orders/{number}. Таймаут запроса задаётся контекстом.
+func NewHTTPAccrualClient(address string) AccrualClient {
+	return &httpAccrualClient{
+		address: strings.TrimSuffix(address, "/"),
+		client:  &http.Client{},
+	}
+}
+
+func (c *httpAccrualClient) GetAccrual(ctx context.Context, number string) (*models.AccrualResult, error) {
+	endpoint := fmt.Sprintf("%s/api/orders/%s", c.address, url.PathEscape(number))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call accrual system: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("failed to close response body: %v", err)
+		}
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var result models.AccrualResult
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &result, nil
+
+	case http.StatusNoContent:
+		return nil, nil
+
+	case http.StatusTooManyRequests:
+		rateErr := &RateLimitError{}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			rateErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return nil, rateErr
+
+	default:
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+// AccrualRoute направляет заказы с номером, начинающимся на Prefix, в Client.
+// Пустой префикс подходит для любого номера.
+type AccrualRoute struct {
+	Prefix string
+	Client AccrualClient
+}
+
+type prefixRouter struct {
+	routes []AccrualRoute
+}
+
+// NewPrefixRouter выбирает систему начислений по самому длинному подходящему
+// префиксу номера заказа.
+func NewPrefixRouter(routes ...AccrualRoute) AccrualClient {
+	sorted := append([]AccrualRoute(nil), routes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i].Prefix) > len(sorted[j].Prefix) })
+	return &prefixRouter{routes: sorted}
+}
+
+func (r *prefixRouter) GetAccrual(ctx context.Context, number string) (*models.AccrualResult, error) {
+	for _, route := range r.routes {
+		if strings.HasPrefix(number, route.Prefix) {
+			return route.Client.GetAccrual(ctx, number)
+		}
+	}
+	return nil, fmt.Errorf("%w %s", ErrNoAccrualProvider, number)
+}
@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/accrualmock"
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+func TestHTTPAccrualClient(t *testing.T) {
+	mock := accrualmock.New(accrualmock.Config{})
+	srv := httptest.NewServer(mock)
+	t.Cleanup(srv.Close)
+	client := NewHTTPAccrualClient(srv.URL + "/")
+	ctx := context.Background()
+
+	mock.Script("12345678903", accrualmock.Processed(729.98))
+	result, err := client.GetAccrual(ctx, "12345678903")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result == nil || result.Status != models.AccrualStatusProcessed || result.Accrual != 729.98 {
+		t.Errorf("processed: result = %+v", result)
+	}
+
+	mock.Script("9278923470", accrualmock.Step{NoContent: true})
+	if result, err := client.GetAccrual(ctx, "9278923470"); result != nil || err != nil {
+		t.Errorf("not registered: GetAccrual = %+v, %v; want nil, nil", result, err)
+	}
+
+	mock.Script("346436439", accrualmock.Step{HTTPStatus: http.StatusInternalServerError})
+	if _, err := client.GetAccrual(ctx, "346436439"); err == nil {
+		t.Error("server error: want error")
+	}
+}
+
+func TestHTTPAccrualClient_RateLimited(t *testing.T) {
+	srv := httptest.NewServer(accrualmock.New(accrualmock.Config{
+		RateLimit:  1,
+		RateWindow: time.Hour,
+		RetryAfter: 2 * time.Second,
+	}))
+	t.Cleanup(srv.Close)
+	client := NewHTTPAccrualClient(srv.URL)
+
+	if _, err := client.GetAccrual(context.Background(), "12345678903"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.GetAccrual(context.Background(), "12345678903")
+
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) || rateErr.RetryAfter != 2*time.Second {
+		t.Errorf("error = %v, want RateLimitError with 2s", err)
+	}
+}
+
+func TestPrefixRouter(t *testing.T) {
+	fallback := NewMockAccrualClient()
+	partner := NewMockAccrualClient()
+	partnerVIP := NewMockAccrualClient()
+
+	router := NewPrefixRouter(
+		AccrualRoute{Client: fallback},
+		AccrualRoute{Prefix: "9", Client: partner},
+		AccrualRoute{Prefix: "92", Client: partnerVIP},
+	)
+
+	for number, want := range map[string]*MockAccrualClient{
+		"12345678903": fallback,
+		"9000000003":  partner,
+		"9278923470":  partnerVIP,
+	} {
+		if _, err := router.GetAccrual(context.Background(), number); err != nil {
+			t.Fatal(err)
+		}
+		if want.Calls(number) != 1 {
+			t.Errorf("%s was not routed to the expected provider", number)
+		}
+	}
+
+	partnerOnly := NewPrefixRouter(AccrualRoute{Prefix: "9", Client: partner})
+	if _, err := partnerOnly.GetAccrual(context.Background(), "12345678903"); !errors.Is(err, ErrNoAccrualProvider) {
+		t.Errorf("unmatched number: error = %v, want ErrNoAccrualProvider", err)
+	}
+}
+
+func TestNewAccrualClient(t *testing.T) {
+	if client := NewAccrualClient(models.AccrualConfig{}, nil); client != nil {
+		t.Errorf("no providers: client = %T, want nil", client)
+	}
+	if _, ok := NewAccrualClient(models.AccrualConfig{Address: "http://accrual"}, nil).(*httpAccrualClient); !ok {
+		t.Error("single address: want plain HTTP client")
+	}
+
+	client := NewAccrualClient(models.AccrualConfig{
+		Providers: []models.AccrualProvider{{Prefix: "9", Address: "http://partner"}},
+	}, nil)
+	router, ok := client.(*prefixRouter)
+	if !ok || len(router.routes) != 1 || router.routes[0].Prefix != "9" {
+		t.Errorf("providers: client = %#v, want router with prefix 9", client)
+	}
+
+	engine := NewMockAccrualClient()
+	if client := NewAccrualClient(models.AccrualConfig{}, engine); client != nil {
+		t.Errorf("engine disabled: client = %T, want nil", client)
+	}
+	if client := NewAccrualClient(models.AccrualConfig{EngineEnabled: true}, engine); client != engine {
+		t.Errorf("engine only: client = %T, want the engine itself", client)
+	}
+
+	engine.Set("77", models.AccrualStatusProcessed, 5)
+	client = NewAccrualClient(models.AccrualConfig{
+		Providers:     []models.AccrualProvider{{Prefix: "9", Address: "http://partner"}},
+		EngineEnabled: true,
+		EnginePrefix:  "7",
+	}, engine)
+	if result, err := client.GetAccrual(context.Background(), "77"); err != nil || result == nil || result.Accrual != 5 {
+		t.Errorf("engine prefix: GetAccrual = %+v, %v", result, err)
+	}
+}
+
+func TestMockAccrualClient(t *testing.T) {
+	client := NewMockAccrualClient()
+	ctx := context.Background()
+
+	if result, err := client.GetAccrual(ctx, "12345678903"); result != nil || err != nil {
+		t.Errorf("unknown order: GetAccrual = %+v, %v; want nil, nil", result, err)
+	}
+
+	client.Set("12345678903", models.AccrualStatusProcessed, 10)
+	if result, _ := client.GetAccrual(ctx, "12345678903"); result == nil || result.Accrual != 10 {
+		t.Errorf("result = %+v, want accrual 10", result)
+	}
+
+	boom := errors.New("boom")
+	client.Fail("12345678903", boom)
+	if _, err := client.GetAccrual(ctx, "12345678903"); !errors.Is(err, boom) {
+		t.Errorf("error = %v, want %v", err, boom)
+	}
+	if got := client.Calls("12345678903"); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+}
@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+	"github.com/sirupsen/logrus"
+)
+
+// ExpiryWorker периодически сжигает баллы с истёкшим сроком действия.
+type ExpiryWorker struct {
+	interval       time.Duration
+	balanceService service.BalanceService
+	logger         *logrus.Logger
+}
+
+func NewExpiryWorker(interval time.Duration, balanceService service.BalanceService, logger *logrus.Logger) *ExpiryWorker {
+	return &ExpiryWorker{
+		interval:       interval,
+		balanceService: balanceService,
+		logger:         logger,
+	}
+}
+
+// Start сжигает просроченные баллы сразу и затем каждые interval до отмены
+// ctx: баллы, истёкшие, пока сервис не работал, сгорают при запуске.
+func (w *ExpiryWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.logger.Info("Points expiry worker started")
+
+	for {
+		w.ExpirePoints(ctx)
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Points expiry worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExpirePoints выполняет один проход сжигания.
+func (w *ExpiryWorker) ExpirePoints(ctx context.Context) {
+	expired, err := w.balanceService.ExpirePoints(ctx)
+	if err != nil {
+		w.logger.WithError(err).WithField("expired", expired).Error("Failed to expire points")
+		return
+	}
+	if expired > 0 {
+		w.logger.WithField("expired", expired).Info("Expired points")
+	}
+}
@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/service"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeExpiringBalanceService struct {
+	service.BalanceService
+	calls atomic.Int32
+	err   error
+}
+
+func (f *fakeExpiringBalanceService) ExpirePoints(context.Context) (float64, error) {
+	f.calls.Add(1)
+	return 10, f.err
+}
+
+func TestExpiryWorker_Start(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	balances := &fakeExpiringBalanceService{err: errors.New("boom")}
+	w := NewExpiryWorker(10*time.Millisecond, balances, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Start(ctx)
+		close(done)
+	}()
+
+	// Ошибка прохода не останавливает воркер.
+	deadline := time.After(time.Second)
+	for balances.calls.Load() < 3 {
+		select {
+		case <-deadline:
+			t.Fatalf("calls = %d, want at least 3", balances.calls.Load())
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop")
+	}
+}
@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"context"
+	"sync"
+
+	"github.com/RoGogDBD/loyalty_service/server/internal/models"
+)
+
+// MockAccrualClient отдаёт заранее заданные результаты расчёта без обращения
+// к сети. Заказы без результата считаются незарегистрированными.
+type MockAccrualClient struct {
+	mu      sync.Mutex
+	results map[string]models.AccrualResult
+	errs    map[string]error
+	calls   map[string]int
+}
+
+func NewMockAccrualClient() *MockAccrualClient {
+	return &MockAccrualClient{
+		results: make(map[string]models.AccrualResult),
+		errs:    make(map[string]error),
+		calls:   make(map[string]int),
+	}
+}
+
+// Set задаёт результат расчёта по заказу.
+func (c *MockAccrualClient) Set(number, status string, accrual float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results[number] = models.AccrualResult{Order: number, Status: status, Accrual: accrual}
+	delete(c.errs, number)
+}
+
+// Fail задаёт ошибку, которую вернёт запрос по заказу.
+func (c *MockAccrualClient) Fail(number string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs[number] = err
+}
+
+// Calls возвращает число запросов по заказу.
+func (c *MockAccrualClient) Calls(number string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[number]
+}
+
+func (c *MockAccrualClient) GetAccrual(_ context.Context, number string) (*models.AccrualResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls[number]++
+
+	if err, ok := c.errs[number]; ok {
+		return nil, err
+	}
+	result, ok := c.results[number]
+	if !ok {
+		return nil, nil
+	}
+	return &result, nil
+}
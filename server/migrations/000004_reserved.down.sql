@@ -0,0 +1,4 @@
+-- Номер 000004 был пропущен в исходной истории миграций. Миграция ничего не
+-- меняет и только закрывает пропуск в нумерации; базы, уже находящиеся на
+-- версии 5 и выше, её не применяют.
+SELECT 1;
@@ -0,0 +1,13 @@
+ALTER TABLE withdrawals
+    DROP CONSTRAINT IF EXISTS withdrawals_sum_check;
+
+ALTER TABLE balance
+    DROP CONSTRAINT IF EXISTS balance_withdrawn_check,
+    DROP CONSTRAINT IF EXISTS balance_current_check;
+
+ALTER TABLE orders
+    DROP CONSTRAINT IF EXISTS orders_accrual_check,
+    DROP CONSTRAINT IF EXISTS orders_status_check;
+
+CREATE INDEX IF NOT EXISTS idx_orders_number ON orders(number);
+CREATE INDEX IF NOT EXISTS idx_users_login ON users(login);
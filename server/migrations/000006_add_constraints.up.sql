@@ -0,0 +1,14 @@
+-- Ограничения UNIQUE уже создают индексы по users.login и orders.number.
+DROP INDEX IF EXISTS idx_users_login;
+DROP INDEX IF EXISTS idx_orders_number;
+
+ALTER TABLE orders
+    ADD CONSTRAINT orders_status_check CHECK (status IN ('NEW', 'PROCESSING', 'INVALID', 'PROCESSED')),
+    ADD CONSTRAINT orders_accrual_check CHECK (accrual IS NULL OR accrual >= 0);
+
+ALTER TABLE balance
+    ADD CONSTRAINT balance_current_check CHECK (current >= 0),
+    ADD CONSTRAINT balance_withdrawn_check CHECK (withdrawn >= 0);
+
+ALTER TABLE withdrawals
+    ADD CONSTRAINT withdrawals_sum_check CHECK (sum > 0);
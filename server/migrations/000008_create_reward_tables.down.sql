@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS registered_orders;
+DROP TABLE IF EXISTS reward_rules;
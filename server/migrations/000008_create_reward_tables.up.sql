@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS reward_rules (
+    id SERIAL PRIMARY KEY,
+    match TEXT NOT NULL UNIQUE CHECK (match <> ''),
+    reward DECIMAL(10,2) NOT NULL CHECK (reward > 0),
+    reward_type VARCHAR(2) NOT NULL CHECK (reward_type IN ('%', 'pt')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS registered_orders (
+    number VARCHAR(255) PRIMARY KEY,
+    goods JSONB NOT NULL,
+    accrual DECIMAL(10,2) NOT NULL CHECK (accrual >= 0),
+    registered_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS point_lots (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
+    remaining DECIMAL(10,2) NOT NULL CHECK (remaining >= 0),
+    expired DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (expired >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_point_lots_user_id ON point_lots(user_id) WHERE remaining > 0;
+CREATE INDEX idx_point_lots_expires_at ON point_lots(expires_at) WHERE remaining > 0;
+
+-- Баллы, накопленные до появления сроков действия, не сгорают.
+INSERT INTO point_lots (user_id, amount, remaining)
+SELECT user_id, current, current FROM balance WHERE current > 0;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS campaigns (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL CHECK (name <> ''),
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL CHECK (ends_at >= starts_at),
+    multiplier DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (multiplier >= 0),
+    bonus DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (bonus >= 0),
+    min_accrual DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (min_accrual >= 0),
+    tier VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_campaigns_period ON campaigns(starts_at, ends_at);
+
+CREATE TABLE IF NOT EXISTS campaign_bonuses (
+    id SERIAL PRIMARY KEY,
+    campaign_id INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    order_number VARCHAR(255) NOT NULL REFERENCES orders(number) ON DELETE CASCADE,
+    amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    reversed_at TIMESTAMPTZ,
+    UNIQUE (campaign_id, order_number)
+);
+
+CREATE INDEX idx_campaign_bonuses_user_id ON campaign_bonuses(user_id);
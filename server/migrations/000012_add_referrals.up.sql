@@ -0,0 +1,24 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS referral_code VARCHAR(16);
+
+UPDATE users
+SET referral_code = UPPER(SUBSTR(MD5(RANDOM()::TEXT || id::TEXT), 1, 8))
+WHERE referral_code IS NULL;
+
+ALTER TABLE users ALTER COLUMN referral_code SET NOT NULL;
+ALTER TABLE users ADD CONSTRAINT users_referral_code_key UNIQUE (referral_code);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS referred_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_users_referred_by ON users(referred_by);
+
+CREATE TABLE IF NOT EXISTS referral_rewards (
+    referee_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    referrer_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    order_number VARCHAR(255) NOT NULL REFERENCES orders(number) ON DELETE CASCADE,
+    referrer_amount DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (referrer_amount >= 0),
+    referee_amount DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (referee_amount >= 0),
+    limited BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_referral_rewards_referrer_id ON referral_rewards(referrer_id, created_at);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS transfers (
+    id SERIAL PRIMARY KEY,
+    from_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sum DECIMAL(10,2) NOT NULL CHECK (sum > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (from_user_id <> to_user_id)
+);
+
+CREATE INDEX idx_transfers_from_user_id ON transfers(from_user_id, created_at);
+CREATE INDEX idx_transfers_to_user_id ON transfers(to_user_id);
@@ -0,0 +1,3 @@
+ALTER TABLE withdrawals ALTER COLUMN processed_at TYPE TIMESTAMP;
+ALTER TABLE orders ALTER COLUMN uploaded_at TYPE TIMESTAMP;
+ALTER TABLE users ALTER COLUMN created_at TYPE TIMESTAMP;
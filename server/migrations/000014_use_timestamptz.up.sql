@@ -0,0 +1,7 @@
+-- Окна лимитов списаний и уровней передаются из приложения с часовым поясом,
+-- поэтому столбцы, по которым они считаются, хранят момент времени.
+-- Значения TIMESTAMP записывались в часовом поясе сессии, поэтому при смене
+-- типа они интерпретируются в нём же.
+ALTER TABLE users ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+ALTER TABLE orders ALTER COLUMN uploaded_at TYPE TIMESTAMPTZ;
+ALTER TABLE withdrawals ALTER COLUMN processed_at TYPE TIMESTAMPTZ;
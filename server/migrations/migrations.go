@@ -0,0 +1,10 @@
+// Package migrations содержит SQL-миграции схемы базы данных. Файлы
+// встраиваются в бинарник, поэтому сервис не зависит от рабочего каталога.
+package migrations
+
+import "embed"
+
+// FS — файлы миграций в формате golang-migrate (NNNNNN_name.up.sql / .down.sql).
+//
+//go:embed *.sql
+var FS embed.FS